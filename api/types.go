@@ -13,20 +13,42 @@ type Response struct {
 
 // FileInfo 文件信息结构
 type FileInfo struct {
-	Name          string    `json:"name"`          // 文件名
-	IsDir         bool      `json:"isDir"`         // 是否为目录
-	Size          int64     `json:"size"`          // 文件大小（字节）
-	SizeHuman     string    `json:"sizeHuman"`     // 人类可读的文件大小
-	Path          string    `json:"path"`          // 完整路径
-	Ext           string    `json:"ext"`           // 文件扩展名
-	MimeType      string    `json:"mimeType"`      // MIME类型
-	CreateTime    time.Time `json:"createTime"`    // 创建时间
-	ModTime       time.Time `json:"modTime"`       // 修改时间
-	AccessTime    time.Time `json:"accessTime"`    // 访问时间
-	Mode          string    `json:"mode"`          // 文件权限
-	IsHidden      bool      `json:"isHidden"`      // 是否为隐藏文件
-	IsSymlink     bool      `json:"isSymlink"`     // 是否为符号链接
-	SymlinkTarget string    `json:"symlinkTarget"` // 符号链接目标
+	Name          string     `json:"name"`                 // 文件名
+	IsDir         bool       `json:"isDir"`                // 是否为目录
+	Size          int64      `json:"size"`                 // 文件大小（字节）
+	SizeHuman     string     `json:"sizeHuman"`            // 人类可读的文件大小
+	Path          string     `json:"path"`                 // 完整路径
+	Ext           string     `json:"ext"`                  // 文件扩展名
+	MimeType      string     `json:"mimeType"`             // MIME类型
+	CreateTime    *time.Time `json:"createTime,omitempty"` // 创建时间（birth time），平台或文件系统不支持时省略
+	ModTime       time.Time  `json:"modTime"`              // 修改时间
+	AccessTime    *time.Time `json:"accessTime,omitempty"` // 访问时间，平台不支持时省略
+	ChangeTime    *time.Time `json:"changeTime,omitempty"` // 状态改变时间（ctime），平台不支持时省略
+	Mode          string     `json:"mode"`                 // 文件权限
+	IsHidden      bool       `json:"isHidden"`             // 是否为隐藏文件
+	IsSymlink     bool       `json:"isSymlink"`            // 是否为符号链接
+	SymlinkTarget string     `json:"symlinkTarget"`        // 符号链接目标
+	Broken        bool       `json:"broken,omitempty"`     // 符号链接的目标是否不存在
+	Target        *FileInfo  `json:"target,omitempty"`     // 符号链接最终指向的文件信息，目标在根目录之外时省略
+
+	// 以下字段由平台提供，不支持的平台上省略
+	UID    *uint32 `json:"uid,omitempty"`    // 所有者用户ID
+	GID    *uint32 `json:"gid,omitempty"`    // 所属组ID
+	Owner  string  `json:"owner,omitempty"`  // 所有者用户名，无法解析时省略
+	Group  string  `json:"group,omitempty"`  // 所属组名，无法解析时省略
+	Inode  uint64  `json:"inode,omitempty"`  // inode 编号
+	Device uint64  `json:"device,omitempty"` // 所在设备编号
+	Nlink  uint64  `json:"nlink,omitempty"`  // 硬链接数
+	Blocks *int64  `json:"blocks,omitempty"` // 占用的 512 字节块数
+
+	Xattrs []Xattr `json:"xattrs,omitempty"` // 扩展属性，只在字段投影中显式请求 xattrs 时返回
+}
+
+// Xattr 扩展属性
+type Xattr struct {
+	Name     string `json:"name"`               // 属性名，包含命名空间，如 user.tag
+	Value    string `json:"value"`              // 属性值
+	Encoding string `json:"encoding,omitempty"` // 值不是合法的 UTF-8 文本时为 base64
 }
 
 // CreateDocumentRequest 创建文档请求
@@ -43,4 +65,4 @@ const (
 	CodeMethodNotAllow = 1002 // 方法不允许
 	CodePathNotExist   = 1003 // 路径不存在
 	CodeOperationFail  = 1004 // 操作失败
-) 
\ No newline at end of file
+)
@@ -1,14 +1,21 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"jia-file/internal/config"
 	"jia-file/internal/file"
 	"jia-file/internal/handler"
+	"jia-file/internal/index"
+	"jia-file/internal/job"
 	"jia-file/internal/logger"
 	"jia-file/internal/middleware"
+	"jia-file/internal/tus"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 )
 
 func main() {
@@ -26,8 +33,44 @@ func main() {
 	// 创建文件服务实例
 	fileService := file.NewService()
 
+	// 创建后台任务管理器
+	jobManager, err := job.NewManager(cfg.Job.Workers, cfg.Job.QueueSize, cfg.Job.HistorySize, cfg.Job.HistoryFile)
+	if err != nil {
+		log.Fatalf("Failed to init job manager: %v", err)
+	}
+
+	// 创建全文索引，通过文件服务的变更事件增量更新
+	var fullTextIndex *index.Index
+	if cfg.FullText.Enabled {
+		if cfg.File.RootPath == "" {
+			logger.Error("Full-text index requires ROOT_PATH, disabled")
+		} else {
+			fullTextIndex, err = index.New(index.Options{
+				Root:           cfg.File.RootPath,
+				Dir:            cfg.FullText.IndexDir,
+				Extensions:     cfg.FullText.Extensions,
+				MaxFileSize:    cfg.FullText.MaxFileSize,
+				RescanInterval: cfg.FullText.RescanInterval,
+				Exclude:        []string{cfg.Upload.TempDir},
+			})
+			if err != nil {
+				log.Fatalf("Failed to init full-text index: %v", err)
+			}
+			fileService.Subscribe(fullTextIndex.OnChange)
+		}
+	}
+
 	// 创建HTTP处理器实例
-	h := handler.NewHandler(fileService)
+	h := handler.NewHandler(cfg, fileService, jobManager, fullTextIndex)
+
+	// 创建断点续传处理器，分片暂存在根目录下的保留目录中，未完成的上传不会被列出、下载或删除
+	pathProcessor := file.NewPathProcessor(cfg.File.RootPath)
+	pathProcessor.Reserve(cfg.Upload.TempDir)
+	uploadStore, err := tus.NewStore(cfg.Upload.TempDir, cfg.Upload.Expiration)
+	if err != nil {
+		log.Fatalf("Failed to init upload store: %v", err)
+	}
+	tusHandler := tus.NewHandler("/uploads/", uploadStore, fileService, pathProcessor, cfg.File.MaxUploadSize)
 
 	// 创建路由
 	mux := http.NewServeMux()
@@ -43,14 +86,47 @@ func main() {
 
 	// 文件操作路由
 	mux.HandleFunc("/list", h.List)
+	mux.HandleFunc("/tree", h.Tree)
+	mux.HandleFunc("/search", h.Search)
+	mux.HandleFunc("/grep", h.Grep)
+	mux.HandleFunc("/search/fulltext", h.FullTextSearch)
 	mux.HandleFunc("/mkdir", h.CreateDir)
 	mux.HandleFunc("/touch", h.CreateFile)
+	mux.HandleFunc("/upload", h.Upload)
+	mux.Handle("/uploads", tusHandler)
+	mux.Handle("/uploads/", tusHandler)
 	mux.HandleFunc("/delete", h.Delete)
 	mux.HandleFunc("/move", h.Move)
 	mux.HandleFunc("/copy", h.Copy)
 	mux.HandleFunc("/info", h.GetInfo)
+	mux.HandleFunc("/symlink", h.CreateSymlink)
+	mux.HandleFunc("/link", h.CreateHardLink)
+	mux.HandleFunc("/chmod", h.Chmod)
+	mux.HandleFunc("/chown", h.Chown)
+	mux.HandleFunc("/chtimes", h.Chtimes)
+	mux.HandleFunc("/xattrs", h.ListXattrs)
+	mux.HandleFunc("/xattr", h.Xattr)
+	mux.HandleFunc("/checksum", h.Checksum)
+	mux.HandleFunc("/duplicates", h.Duplicates)
+	mux.HandleFunc("/du", h.DiskUsage)
+	mux.HandleFunc("/archive", h.Archive)
+	mux.HandleFunc("/extract", h.Extract)
+	mux.HandleFunc("/compress", h.Compress)
+	mux.HandleFunc("/trash", h.ListTrash)
+	mux.HandleFunc("/trash/restore", h.RestoreTrash)
+	mux.HandleFunc("/trash/purge", h.PurgeTrash)
+	mux.HandleFunc("/versions", h.ListVersions)
+	mux.HandleFunc("/versions/download", h.DownloadVersion)
+	mux.HandleFunc("/versions/diff", h.DiffVersions)
+	mux.HandleFunc("/versions/restore", h.RestoreVersion)
+	mux.HandleFunc("/download", h.Download)
 	mux.HandleFunc("/document", h.CreateDocument)
 
+	// 后台任务路由
+	mux.HandleFunc("/jobs", h.ListJobs)
+	mux.HandleFunc("/jobs/info", h.GetJob)
+	mux.HandleFunc("/jobs/cancel", h.CancelJob)
+
 	// 应用中间件
 	handler := middleware.LoggingMiddleware(
 		middleware.RecoveryMiddleware(
@@ -60,11 +136,54 @@ func main() {
 		),
 	)
 
+	// 收到 SIGINT 或 SIGTERM 时关闭 ctx，后台协程随之退出
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// 所有可能失败的初始化完成后再启动后台服务，保证退出时都能被停止
+	jobManager.Start()
+	if fullTextIndex != nil {
+		fullTextIndex.Start()
+	}
+	// 按间隔清除超过保留时间或超出总大小限制的回收站条目
+	fileService.StartTrashPurge(cfg.Trash.PurgeInterval, ctx.Done())
+	// 按间隔应用版本保留策略并删除不再被引用的版本内容
+	fileService.StartVersionPrune(cfg.Version.PruneInterval, ctx.Done())
+	uploadStore.StartCleanup(cfg.Upload.CleanupInterval, ctx.Done())
+
 	// 启动服务器
 	port := ":" + cfg.Server.Port
-	logger.Info("Server starting on %s...", port)
-	if err := http.ListenAndServe(port, handler); err != nil {
+	server := &http.Server{Addr: port, Handler: handler}
+	serverErr := make(chan error, 1)
+	go func() {
+		logger.Info("Server starting on %s...", port)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	exitCode := 0
+	select {
+	case err := <-serverErr:
 		logger.Error("Server error: %v", err)
-		log.Fatal(err)
+		log.Print(err)
+		exitCode = 1
+	case <-ctx.Done():
+		logger.Info("Shutting down...")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			// 超时后强制关闭仍未结束的连接
+			logger.Error("Server shutdown error: %v", err)
+			server.Close()
+		}
+		cancel()
+	}
+
+	// 取消未结束的后台任务并持久化任务历史和全文索引
+	jobManager.Stop()
+	if fullTextIndex != nil {
+		fullTextIndex.Stop()
+	}
+	logger.Info("Server stopped")
+	if exitCode != 0 {
+		os.Exit(exitCode)
 	}
-} 
\ No newline at end of file
+}
@@ -2,4 +2,9 @@ module jia-file
 
 go 1.24
 
-require github.com/joho/godotenv v1.5.1 // indirect
+require (
+	github.com/joho/godotenv v1.5.1
+	github.com/klauspost/compress v1.18.0
+	golang.org/x/crypto v0.38.0
+	golang.org/x/sys v0.33.0
+)
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -12,14 +13,81 @@ import (
 // Config 应用配置结构
 type Config struct {
 	Server struct {
-		Port string
+		Port            string
+		ShutdownTimeout time.Duration // 收到退出信号后等待进行中的请求完成的最长时间
 	}
 	Log struct {
 		Level string
 		Dir   string
 	}
 	File struct {
-		RootPath string // 文件操作的根目录
+		RootPath      string // 文件操作的根目录
+		MaxUploadSize int64  // 单个上传文件的最大字节数，0 表示不限制
+		IgnoreConfig  string // 忽略配置文件路径，为空时使用 internal/config/ignore.json
+	}
+	Upload struct {
+		TempDir         string        // 断点续传分片的暂存目录，为空时使用根目录下的 .uploads
+		Expiration      time.Duration // 未完成上传的过期时间
+		CleanupInterval time.Duration // 清理过期上传的间隔
+	}
+	Job struct {
+		Workers     int    // 并发执行的后台任务数
+		QueueSize   int    // 等待执行的任务队列长度
+		HistorySize int    // 保留的已结束任务数
+		HistoryFile string // 任务历史持久化文件
+	}
+	Tree struct {
+		MaxDepth int // 目录树允许展开的最大层数
+		MaxNodes int // 目录树单次请求最多返回的节点数
+	}
+	Grep struct {
+		Workers int // 内容搜索并发扫描的文件数
+	}
+	FullText struct {
+		Enabled        bool          // 是否启用全文索引，需要设置 RootPath
+		IndexDir       string        // 索引存储目录
+		Extensions     []string      // 建立索引的文件扩展名
+		MaxFileSize    int64         // 大于此大小的文件不建立索引
+		RescanInterval time.Duration // 全量校对的间隔
+	}
+	Usage struct {
+		CacheTTL  time.Duration // 磁盘用量统计结果的有效期，0 表示不缓存
+		CacheSize int           // 最多缓存的目录数
+	}
+	Archive struct {
+		MaxEntries int   // 解压时允许的最大条目数
+		MaxSize    int64 // 解压后允许的最大总字节数
+		MaxRatio   int   // 解压后总大小与归档文件大小的最大比例
+	}
+	Trash struct {
+		Enabled        bool          // 删除时是否移入回收站
+		AllowPermanent bool          // 是否允许通过 permanent=true 跳过回收站永久删除
+		Dir            string        // 回收站目录，为空时使用根目录下的 .Trash-<uid>
+		MaxAge         time.Duration // 回收站中条目的保留时间，0 表示不限制
+		MaxSize        int64         // 回收站的最大总字节数，超出时清除最早删除的条目，0 表示不限制
+		PurgeInterval  time.Duration // 自动清理回收站的间隔
+	}
+	Version struct {
+		Enabled         bool          // 覆盖和删除文件时是否保存历史版本
+		Dir             string        // 版本存储目录
+		MaxCount        int           // 每个文件保留的最大版本数，0 表示不限制
+		MaxAge          time.Duration // 版本的保留时间，0 表示不限制
+		MaxFileSize     int64         // 大于此大小的文件不保存版本，0 表示不限制
+		RetentionConfig string        // 按路径前缀设置保留策略的配置文件路径，为空时使用 internal/config/versions.json
+		PruneInterval   time.Duration // 清理过期版本的间隔
+	}
+	Timeout struct {
+		List     time.Duration // 列出目录的超时时间
+		Info     time.Duration // 获取信息、创建目录等轻量操作的超时时间
+		Download time.Duration // 下载的超时时间，包括传输文件内容
+		Write    time.Duration // 上传和创建文件的超时时间
+		Copy     time.Duration // 复制的超时时间
+		Move     time.Duration // 移动的超时时间
+		Delete   time.Duration // 删除的超时时间
+		Search   time.Duration // 搜索的超时时间
+		Checksum time.Duration // 计算校验和的超时时间
+		Usage    time.Duration // 统计磁盘用量的超时时间
+		Archive  time.Duration // 解压和压缩的超时时间
 	}
 }
 
@@ -27,9 +95,11 @@ var (
 	// 默认配置
 	defaultConfig = Config{
 		Server: struct {
-			Port string
+			Port            string
+			ShutdownTimeout time.Duration
 		}{
-			Port: "8190",
+			Port:            "8190",
+			ShutdownTimeout: 30 * time.Second,
 		},
 		Log: struct {
 			Level string
@@ -39,9 +109,134 @@ var (
 			Dir:   "logs",
 		},
 		File: struct {
-			RootPath string
+			RootPath      string
+			MaxUploadSize int64
+			IgnoreConfig  string
+		}{
+			RootPath:      "",      // 默认为空，表示不限制根目录
+			MaxUploadSize: 1 << 30, // 默认 1GB
+		},
+		Upload: struct {
+			TempDir         string
+			Expiration      time.Duration
+			CleanupInterval time.Duration
+		}{
+			TempDir:         "",
+			Expiration:      24 * time.Hour,
+			CleanupInterval: time.Hour,
+		},
+		Job: struct {
+			Workers     int
+			QueueSize   int
+			HistorySize int
+			HistoryFile string
+		}{
+			Workers:     4,
+			QueueSize:   100,
+			HistorySize: 1000,
+			HistoryFile: filepath.Join("data", "jobs.json"),
+		},
+		Tree: struct {
+			MaxDepth int
+			MaxNodes int
+		}{
+			MaxDepth: 10,
+			MaxNodes: 10000,
+		},
+		Grep: struct {
+			Workers int
+		}{
+			Workers: 4,
+		},
+		FullText: struct {
+			Enabled        bool
+			IndexDir       string
+			Extensions     []string
+			MaxFileSize    int64
+			RescanInterval time.Duration
+		}{
+			Enabled:  false,
+			IndexDir: filepath.Join("data", "index"),
+			Extensions: []string{
+				".txt", ".md", ".markdown", ".rst", ".csv", ".log",
+				".json", ".yaml", ".yml", ".toml", ".ini", ".conf", ".xml", ".html", ".htm", ".css",
+				".go", ".py", ".js", ".ts", ".java", ".c", ".h", ".cpp", ".rs", ".sh", ".sql",
+			},
+			MaxFileSize:    10 << 20, // 默认 10MB
+			RescanInterval: 6 * time.Hour,
+		},
+		Usage: struct {
+			CacheTTL  time.Duration
+			CacheSize int
+		}{
+			CacheTTL:  10 * time.Minute,
+			CacheSize: 100,
+		},
+		Archive: struct {
+			MaxEntries int
+			MaxSize    int64
+			MaxRatio   int
+		}{
+			MaxEntries: 100000,
+			MaxSize:    10 << 30, // 默认 10GB
+			MaxRatio:   100,
+		},
+		Trash: struct {
+			Enabled        bool
+			AllowPermanent bool
+			Dir            string
+			MaxAge         time.Duration
+			MaxSize        int64
+			PurgeInterval  time.Duration
+		}{
+			Enabled:        true,
+			AllowPermanent: false,
+			Dir:            "",
+			MaxAge:         30 * 24 * time.Hour,
+			MaxSize:        0,
+			PurgeInterval:  time.Hour,
+		},
+		Version: struct {
+			Enabled         bool
+			Dir             string
+			MaxCount        int
+			MaxAge          time.Duration
+			MaxFileSize     int64
+			RetentionConfig string
+			PruneInterval   time.Duration
+		}{
+			Enabled:         true,
+			Dir:             filepath.Join("data", "versions"),
+			MaxCount:        20,
+			MaxAge:          30 * 24 * time.Hour,
+			MaxFileSize:     100 << 20, // 默认 100MB
+			RetentionConfig: "",
+			PruneInterval:   time.Hour,
+		},
+		Timeout: struct {
+			List     time.Duration
+			Info     time.Duration
+			Download time.Duration
+			Write    time.Duration
+			Copy     time.Duration
+			Move     time.Duration
+			Delete   time.Duration
+			Search   time.Duration
+			Checksum time.Duration
+			Usage    time.Duration
+			Archive  time.Duration
 		}{
-			RootPath: "", // 默认为空，表示不限制根目录
+			List:     time.Minute,
+			Info:     10 * time.Second,
+			Download: 0, // 0 表示不限制，下载大文件可能耗时很长
+			Write:    0, // 0 表示不限制，上传大文件可能耗时很长
+			Copy:     30 * time.Minute,
+			Move:     30 * time.Minute,
+			Delete:   10 * time.Minute,
+			Search:   5 * time.Minute,
+			Checksum: 30 * time.Minute,
+			Usage:    30 * time.Minute,
+			Archive:  30 * time.Minute,
 		},
 	}
 )
@@ -61,6 +256,7 @@ func LoadConfig(envPath string) (*Config, error) {
 	if port := os.Getenv("PORT"); port != "" {
 		config.Server.Port = port
 	}
+	config.Server.ShutdownTimeout = GetEnvDuration("SHUTDOWN_TIMEOUT", config.Server.ShutdownTimeout)
 	if level := os.Getenv("LOG_LEVEL"); level != "" {
 		config.Log.Level = level
 	}
@@ -70,6 +266,60 @@ func LoadConfig(envPath string) (*Config, error) {
 	if rootPath := os.Getenv("ROOT_PATH"); rootPath != "" {
 		config.File.RootPath = rootPath
 	}
+	config.File.MaxUploadSize = GetEnvInt64("MAX_UPLOAD_SIZE", config.File.MaxUploadSize)
+	config.File.IgnoreConfig = GetEnv("IGNORE_CONFIG", config.File.IgnoreConfig)
+	config.Upload.TempDir = GetEnv("UPLOAD_TEMP_DIR", config.Upload.TempDir)
+	if config.Upload.TempDir == "" {
+		// 暂存在根目录下，完成时可以在同一文件系统内原子地重命名到目标
+		if config.File.RootPath != "" {
+			config.Upload.TempDir = filepath.Join(config.File.RootPath, ".uploads")
+		} else {
+			config.Upload.TempDir = filepath.Join(os.TempDir(), "jia-file-uploads")
+		}
+	}
+	config.Upload.Expiration = GetEnvDuration("UPLOAD_EXPIRATION", config.Upload.Expiration)
+	config.Upload.CleanupInterval = GetEnvDuration("UPLOAD_CLEANUP_INTERVAL", config.Upload.CleanupInterval)
+	config.Job.Workers = GetEnvInt("JOB_WORKERS", config.Job.Workers)
+	config.Job.QueueSize = GetEnvInt("JOB_QUEUE_SIZE", config.Job.QueueSize)
+	config.Job.HistorySize = GetEnvInt("JOB_HISTORY_SIZE", config.Job.HistorySize)
+	config.Job.HistoryFile = GetEnv("JOB_HISTORY_FILE", config.Job.HistoryFile)
+	config.Tree.MaxDepth = GetEnvInt("TREE_MAX_DEPTH", config.Tree.MaxDepth)
+	config.Tree.MaxNodes = GetEnvInt("TREE_MAX_NODES", config.Tree.MaxNodes)
+	config.Grep.Workers = GetEnvInt("GREP_WORKERS", config.Grep.Workers)
+	config.FullText.Enabled = GetEnvBool("FULLTEXT_ENABLED", config.FullText.Enabled)
+	config.FullText.IndexDir = GetEnv("FULLTEXT_INDEX_DIR", config.FullText.IndexDir)
+	config.FullText.Extensions = GetEnvStringSlice("FULLTEXT_EXTENSIONS", config.FullText.Extensions)
+	config.FullText.MaxFileSize = GetEnvInt64("FULLTEXT_MAX_FILE_SIZE", config.FullText.MaxFileSize)
+	config.FullText.RescanInterval = GetEnvDuration("FULLTEXT_RESCAN_INTERVAL", config.FullText.RescanInterval)
+	config.Usage.CacheTTL = GetEnvDuration("DU_CACHE_TTL", config.Usage.CacheTTL)
+	config.Usage.CacheSize = GetEnvInt("DU_CACHE_SIZE", config.Usage.CacheSize)
+	config.Archive.MaxEntries = GetEnvInt("ARCHIVE_MAX_ENTRIES", config.Archive.MaxEntries)
+	config.Archive.MaxSize = GetEnvInt64("ARCHIVE_MAX_SIZE", config.Archive.MaxSize)
+	config.Archive.MaxRatio = GetEnvInt("ARCHIVE_MAX_RATIO", config.Archive.MaxRatio)
+	config.Trash.Enabled = GetEnvBool("TRASH_ENABLED", config.Trash.Enabled)
+	config.Trash.AllowPermanent = GetEnvBool("TRASH_ALLOW_PERMANENT", config.Trash.AllowPermanent)
+	config.Trash.Dir = GetEnv("TRASH_DIR", config.Trash.Dir)
+	config.Trash.MaxAge = GetEnvDuration("TRASH_MAX_AGE", config.Trash.MaxAge)
+	config.Trash.MaxSize = GetEnvInt64("TRASH_MAX_SIZE", config.Trash.MaxSize)
+	config.Trash.PurgeInterval = GetEnvDuration("TRASH_PURGE_INTERVAL", config.Trash.PurgeInterval)
+	config.Version.Enabled = GetEnvBool("VERSION_ENABLED", config.Version.Enabled)
+	config.Version.Dir = GetEnv("VERSION_DIR", config.Version.Dir)
+	config.Version.MaxCount = GetEnvInt("VERSION_MAX_COUNT", config.Version.MaxCount)
+	config.Version.MaxAge = GetEnvDuration("VERSION_MAX_AGE", config.Version.MaxAge)
+	config.Version.MaxFileSize = GetEnvInt64("VERSION_MAX_FILE_SIZE", config.Version.MaxFileSize)
+	config.Version.RetentionConfig = GetEnv("VERSION_RETENTION_CONFIG", config.Version.RetentionConfig)
+	config.Version.PruneInterval = GetEnvDuration("VERSION_PRUNE_INTERVAL", config.Version.PruneInterval)
+	config.Timeout.List = GetEnvDuration("TIMEOUT_LIST", config.Timeout.List)
+	config.Timeout.Info = GetEnvDuration("TIMEOUT_INFO", config.Timeout.Info)
+	config.Timeout.Download = GetEnvDuration("TIMEOUT_DOWNLOAD", config.Timeout.Download)
+	config.Timeout.Write = GetEnvDuration("TIMEOUT_WRITE", config.Timeout.Write)
+	config.Timeout.Copy = GetEnvDuration("TIMEOUT_COPY", config.Timeout.Copy)
+	config.Timeout.Move = GetEnvDuration("TIMEOUT_MOVE", config.Timeout.Move)
+	config.Timeout.Delete = GetEnvDuration("TIMEOUT_DELETE", config.Timeout.Delete)
+	config.Timeout.Search = GetEnvDuration("TIMEOUT_SEARCH", config.Timeout.Search)
+	config.Timeout.Checksum = GetEnvDuration("TIMEOUT_CHECKSUM", config.Timeout.Checksum)
+	config.Timeout.Usage = GetEnvDuration("TIMEOUT_DU", config.Timeout.Usage)
+	config.Timeout.Archive = GetEnvDuration("TIMEOUT_ARCHIVE", config.Timeout.Archive)
 	return &config, nil
 }
 
@@ -100,4 +350,45 @@ func LoadIgnoreConfig(configPath string) (*IgnoreConfig, error) {
 	}
 
 	return &config, nil
-} 
\ No newline at end of file
+}
+
+// VersionRetentionConfig 按路径前缀设置的版本保留策略
+type VersionRetentionConfig struct {
+	Rules []VersionRetentionRule `json:"rules"`
+}
+
+// VersionRetentionRule 单个路径前缀的保留策略，省略的字段使用 VERSION_MAX_COUNT 和 VERSION_MAX_AGE
+type VersionRetentionRule struct {
+	Path     string  `json:"path"`     // 路径前缀，绝对路径或相对于根目录的路径
+	MaxCount *int    `json:"maxCount"` // 最大版本数，0 表示不限制
+	MaxAge   *string `json:"maxAge"`   // 保留时间，如 "2160h"，"0" 表示不限制
+}
+
+// LoadVersionRetentionConfig 加载版本保留策略配置
+func LoadVersionRetentionConfig(configPath string) (*VersionRetentionConfig, error) {
+	if configPath == "" {
+		configPath = filepath.Join("internal", "config", "versions.json")
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var config VersionRetentionConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+	for _, rule := range config.Rules {
+		if rule.Path == "" {
+			return nil, fmt.Errorf("version retention rule without path")
+		}
+		if rule.MaxAge != nil {
+			if _, err := time.ParseDuration(*rule.MaxAge); err != nil {
+				return nil, fmt.Errorf("invalid maxAge for %s: %v", rule.Path, err)
+			}
+		}
+	}
+
+	return &config, nil
+}
\ No newline at end of file
@@ -5,6 +5,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -60,6 +61,20 @@ func GetEnvBool(key string, defaultValue bool) bool {
 	return boolValue
 }
 
+// GetEnvDuration 获取时间间隔类型环境变量（如 "30s"、"24h"），如果不存在或转换失败则返回默认值
+func GetEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return defaultValue
+	}
+	return duration
+}
+
 // GetEnvStringSlice 获取字符串切片类型环境变量，如果不存在则返回默认值
 func GetEnvStringSlice(key string, defaultValue []string) []string {
 	value := os.Getenv(key)
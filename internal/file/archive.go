@@ -0,0 +1,490 @@
+package file
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// ArchiveFormat 归档格式
+type ArchiveFormat string
+
+const (
+	ArchiveZip    ArchiveFormat = "zip"
+	ArchiveTar    ArchiveFormat = "tar"
+	ArchiveTarGz  ArchiveFormat = "tar.gz"
+	ArchiveTarZst ArchiveFormat = "tar.zst"
+	ArchiveTarBz2 ArchiveFormat = "tar.bz2" // 只支持解压
+)
+
+// ParseArchiveFormat 解析归档格式，空字符串表示 zip，tgz、tbz2、tzst 分别等同于 tar.gz、tar.bz2、tar.zst
+func ParseArchiveFormat(value string) (ArchiveFormat, error) {
+	switch format := ArchiveFormat(strings.ToLower(value)); format {
+	case "":
+		return ArchiveZip, nil
+	case "tgz":
+		return ArchiveTarGz, nil
+	case "tbz2", "tbz":
+		return ArchiveTarBz2, nil
+	case "tzst":
+		return ArchiveTarZst, nil
+	case ArchiveZip, ArchiveTar, ArchiveTarGz, ArchiveTarZst, ArchiveTarBz2:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unsupported archive format: %s", value)
+	}
+}
+
+// DetectArchiveFormat 根据文件扩展名判断归档格式，无法识别时返回 false
+func DetectArchiveFormat(name string) (ArchiveFormat, bool) {
+	lower := strings.ToLower(name)
+	for _, suffix := range []string{".tar.gz", ".tar.bz2", ".tar.zst", ".tgz", ".tbz2", ".tbz", ".tzst", ".zip", ".tar"} {
+		if strings.HasSuffix(lower, suffix) {
+			format, err := ParseArchiveFormat(strings.TrimPrefix(suffix, "."))
+			return format, err == nil
+		}
+	}
+	return "", false
+}
+
+// Extension 返回归档格式的文件扩展名
+func (f ArchiveFormat) Extension() string {
+	return "." + string(f)
+}
+
+// ContentType 返回归档格式的 MIME 类型
+func (f ArchiveFormat) ContentType() string {
+	switch f {
+	case ArchiveZip:
+		return "application/zip"
+	case ArchiveTarGz:
+		return "application/gzip"
+	case ArchiveTarBz2:
+		return "application/x-bzip2"
+	case ArchiveTarZst:
+		return "application/zstd"
+	default:
+		return "application/x-tar"
+	}
+}
+
+// ArchiveOptions 归档选项
+type ArchiveOptions struct {
+	Format  ArchiveFormat // 归档格式
+	Include []string      // 只打包目录下匹配这些通配符的文件，直接指定的文件总是被打包
+	Exclude []string      // 排除目录下匹配这些通配符的条目，被排除的目录不会继续遍历
+}
+
+// ArchiveSummary 归档统计
+type ArchiveSummary struct {
+	Files   int   `json:"files"`   // 打包的文件数（含符号链接）
+	Dirs    int   `json:"dirs"`    // 打包的目录数
+	Bytes   int64 `json:"bytes"`   // 打包的文件内容字节数
+	Skipped int   `json:"skipped"` // 无法读取或类型不支持（设备、管道等）而跳过的条目数
+}
+
+// archiveWriter 一种归档格式的写入器，name 为使用 / 分隔的相对路径
+type archiveWriter interface {
+	writeDir(name string, info os.FileInfo) error
+	writeSymlink(name string, info os.FileInfo, target string) error
+	// writeFile 写入文件头并返回用于写入 info.Size() 字节内容的写入器
+	writeFile(name string, info os.FileInfo) (io.Writer, error)
+	Close() error
+}
+
+// newArchiveWriter 创建写入 w 的归档写入器
+func newArchiveWriter(w io.Writer, format ArchiveFormat) (archiveWriter, error) {
+	switch format {
+	case ArchiveZip:
+		return &zipArchive{zw: zip.NewWriter(w)}, nil
+	case ArchiveTar:
+		return &tarArchive{tw: tar.NewWriter(w)}, nil
+	case ArchiveTarGz:
+		gw := gzip.NewWriter(w)
+		return &tarArchive{tw: tar.NewWriter(gw), compressor: gw}, nil
+	case ArchiveTarZst:
+		zw, err := zstd.NewWriter(w)
+		if err != nil {
+			return nil, err
+		}
+		return &tarArchive{tw: tar.NewWriter(zw), compressor: zw}, nil
+	default:
+		return nil, fmt.Errorf("creating %s archives is not supported", format)
+	}
+}
+
+// zipArchive zip 格式写入器，文件使用 Deflate 压缩
+type zipArchive struct {
+	zw *zip.Writer
+}
+
+func (a *zipArchive) header(name string, info os.FileInfo, method uint16) (*zip.FileHeader, error) {
+	hdr, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return nil, err
+	}
+	hdr.Name = name
+	hdr.Method = method
+	return hdr, nil
+}
+
+func (a *zipArchive) writeDir(name string, info os.FileInfo) error {
+	hdr, err := a.header(name+"/", info, zip.Store)
+	if err != nil {
+		return err
+	}
+	_, err = a.zw.CreateHeader(hdr)
+	return err
+}
+
+func (a *zipArchive) writeSymlink(name string, info os.FileInfo, target string) error {
+	hdr, err := a.header(name, info, zip.Store)
+	if err != nil {
+		return err
+	}
+	w, err := a.zw.CreateHeader(hdr)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, target)
+	return err
+}
+
+func (a *zipArchive) writeFile(name string, info os.FileInfo) (io.Writer, error) {
+	hdr, err := a.header(name, info, zip.Deflate)
+	if err != nil {
+		return nil, err
+	}
+	return a.zw.CreateHeader(hdr)
+}
+
+func (a *zipArchive) Close() error {
+	return a.zw.Close()
+}
+
+// tarArchive tar 格式写入器，可选地经过 gzip 或 zstd 压缩
+type tarArchive struct {
+	tw         *tar.Writer
+	compressor io.WriteCloser
+}
+
+func (a *tarArchive) write(name string, info os.FileInfo, link string) error {
+	hdr, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+	return a.tw.WriteHeader(hdr)
+}
+
+func (a *tarArchive) writeDir(name string, info os.FileInfo) error {
+	return a.write(name+"/", info, "")
+}
+
+func (a *tarArchive) writeSymlink(name string, info os.FileInfo, target string) error {
+	return a.write(name, info, target)
+}
+
+func (a *tarArchive) writeFile(name string, info os.FileInfo) (io.Writer, error) {
+	if err := a.write(name, info, ""); err != nil {
+		return nil, err
+	}
+	return a.tw, nil
+}
+
+func (a *tarArchive) Close() error {
+	err := a.tw.Close()
+	if a.compressor != nil {
+		if closeErr := a.compressor.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// archiver 一次归档操作
+type archiver struct {
+	ctx      context.Context
+	aw       archiveWriter
+	ignore   *ignoreRules
+	skip     []string // 不打包的路径：压缩到源目录之内时为正在写入的临时文件和将被覆盖的旧归档
+	reserved func(path string) bool
+	progress *Progress
+	summary  ArchiveSummary
+}
+
+// add 打包一个条目；符号链接保存为链接本身，设备、管道等特殊文件被跳过
+func (a *archiver) add(path, name string, info os.FileInfo) error {
+	a.progress.AddEntries(1)
+	switch {
+	case info.IsDir():
+		if err := a.aw.writeDir(name, info); err != nil {
+			return err
+		}
+		a.summary.Dirs++
+		return nil
+	case info.Mode()&os.ModeSymlink != 0:
+		target, err := os.Readlink(path)
+		if err != nil {
+			a.summary.Skipped++
+			return nil
+		}
+		if err := a.aw.writeSymlink(name, info, target); err != nil {
+			return err
+		}
+		a.summary.Files++
+		return nil
+	case !info.Mode().IsRegular():
+		a.summary.Skipped++
+		return nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		a.summary.Skipped++
+		return nil
+	}
+	defer f.Close()
+	// 以打开后的状态为准，避免文件在遍历和打开之间被替换
+	if info, err = f.Stat(); err != nil || !info.Mode().IsRegular() {
+		a.summary.Skipped++
+		return nil
+	}
+
+	w, err := a.aw.writeFile(name, info)
+	if err != nil {
+		return err
+	}
+	n, err := copyContext(a.ctx, w, io.LimitReader(f, info.Size()), a.progress)
+	if err != nil {
+		return err
+	}
+	if n != info.Size() {
+		return fmt.Errorf("file changed during archiving: %s", path)
+	}
+	a.summary.Files++
+	a.summary.Bytes += n
+	return nil
+}
+
+// Archive 实现 Service 接口的 Archive 方法
+// 条目路径相对于所有路径的公共父目录，单个目录时以目录名为顶层；
+// 直接指定的路径跟随符号链接，目录中的符号链接保存为链接本身；IgnoreConfig 忽略的条目不会被打包
+func (s *service) Archive(ctx context.Context, paths []string, opts ArchiveOptions, w io.Writer) (ArchiveSummary, error) {
+	// 先校验所有路径，出错时还没有写入任何内容
+	processedPaths, err := s.processArchivePaths(paths)
+	if err != nil {
+		return ArchiveSummary{}, err
+	}
+	return s.writeArchive(ctx, processedPaths, opts, w, &archiver{ctx: ctx})
+}
+
+// processArchivePaths 校验要打包的路径，返回去掉重复和嵌套路径后的处理后路径
+func (s *service) processArchivePaths(paths []string) ([]string, error) {
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no path to archive")
+	}
+	processedPaths := make([]string, 0, len(paths))
+	for _, path := range paths {
+		processedPath, err := s.pathProcessor.ProcessPath(path)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := os.Stat(processedPath); err != nil {
+			return nil, err
+		}
+		processedPaths = append(processedPaths, processedPath)
+	}
+	return outermostPaths(processedPaths), nil
+}
+
+// writeArchive 使用 a 将已处理的路径打包写入 w
+func (s *service) writeArchive(ctx context.Context, processedPaths []string, opts ArchiveOptions, w io.Writer, a *archiver) (ArchiveSummary, error) {
+	filter, err := newPathFilter(opts.Include, opts.Exclude)
+	if err != nil {
+		return ArchiveSummary{}, err
+	}
+	aw, err := newArchiveWriter(w, opts.Format)
+	if err != nil {
+		return ArchiveSummary{}, err
+	}
+	a.aw, a.ignore, a.reserved = aw, s.ignore, s.pathProcessor.Reserved
+
+	base := commonParent(processedPaths)
+	for _, processedPath := range processedPaths {
+		if err := a.addTree(processedPath, base, filter); err != nil {
+			aw.Close()
+			return a.summary, err
+		}
+	}
+	return a.summary, aw.Close()
+}
+
+// CompressOptions 压缩选项
+type CompressOptions struct {
+	ArchiveOptions
+	Conflict ConflictPolicy // 归档文件已存在时的冲突策略
+	Progress *Progress      // 进度，可为 nil
+}
+
+// Compress 实现 Service 接口的 Compress 方法
+// 先写入归档文件旁的临时文件，完成后再按冲突策略重命名，失败时不会留下不完整的归档
+func (s *service) Compress(ctx context.Context, paths []string, dst string, opts CompressOptions) (FileInfo, error) {
+	processedPaths, err := s.processArchivePaths(paths)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	processedDst, err := s.pathProcessor.ProcessPath(dst)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	if opts.Format == "" {
+		if opts.Format, _ = DetectArchiveFormat(processedDst); opts.Format == "" {
+			opts.Format = ArchiveZip
+		}
+	}
+	for _, path := range processedPaths {
+		if isSubPath(processedDst, path) {
+			return FileInfo{}, fmt.Errorf("cannot compress into a path being archived: %s", dst)
+		}
+	}
+
+	target, exists, err := resolveConflict(processedDst, opts.Conflict)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	if exists && opts.Conflict == ConflictSkip {
+		return s.GetInfo(ctx, target)
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return FileInfo{}, fmt.Errorf("failed to create parent directory: %v", err)
+	}
+
+	tmp := tempSibling(target, "compress")
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	defer os.Remove(tmp)
+
+	// 目标位于被打包的目录中时，已存在的旧归档和正在写入的临时文件都不应被打包
+	a := &archiver{ctx: ctx, skip: []string{tmp, target}, progress: opts.Progress}
+	_, err = s.writeArchive(ctx, processedPaths, opts.ArchiveOptions, f, a)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return FileInfo{}, err
+	}
+	if exists {
+		if err := s.saveVersion(ctx, target, VersionOverwrite); err != nil {
+			return FileInfo{}, err
+		}
+	}
+	if err := os.Rename(tmp, target); err != nil {
+		return FileInfo{}, err
+	}
+	s.notify(ChangeEvent{Op: ChangeWrite, Path: target})
+	return s.GetInfo(ctx, target)
+}
+
+// addTree 打包直接指定的路径，路径为目录时递归打包其内容
+func (a *archiver) addTree(root, base string, filter *pathFilter) error {
+	info, err := os.Stat(root)
+	if err != nil {
+		return err
+	}
+	if a.ignore.ignored(root, info.IsDir()) {
+		return nil
+	}
+	name := archiveName(base, root)
+	if err := a.add(root, name, info); err != nil || !info.IsDir() {
+		return err
+	}
+
+	return walkTree(a.ctx, root, walkOptions{filter: filter, reserved: a.reserved}, func(dir string, entry os.DirEntry, rel string) error {
+		fullPath := filepath.Join(dir, entry.Name())
+		if a.skipped(fullPath) {
+			return nil
+		}
+		if a.ignore.ignored(fullPath, entry.IsDir()) {
+			if entry.IsDir() {
+				return errSkipDir
+			}
+			return nil
+		}
+		// include 只作用于文件，目录总是被遍历；指定了 include 时不单独打包目录，避免产生大量空目录
+		if entry.IsDir() && len(filter.include) > 0 {
+			return nil
+		}
+		if !entry.IsDir() && !filter.included(entry.Name(), rel) {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			a.summary.Skipped++
+			if entry.IsDir() {
+				return errSkipDir
+			}
+			return nil
+		}
+		return a.add(fullPath, archiveName(base, fullPath), info)
+	})
+}
+
+// skipped 判断路径是否不应被打包
+func (a *archiver) skipped(path string) bool {
+	for _, skip := range a.skip {
+		if path == skip {
+			return true
+		}
+	}
+	return false
+}
+
+// archiveName 返回条目在归档中的名称：相对于 base、使用 / 分隔
+func archiveName(base, path string) string {
+	rel, err := filepath.Rel(base, path)
+	if err != nil {
+		rel = filepath.Base(path)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// outermostPaths 排序去重，并去掉位于其他路径之下的路径，避免重复打包
+func outermostPaths(paths []string) []string {
+	sorted := append([]string{}, paths...)
+	sort.Strings(sorted)
+	var result []string
+	for _, path := range sorted {
+		if n := len(result); n > 0 && (path == result[n-1] || strings.HasPrefix(path, strings.TrimSuffix(result[n-1], string(filepath.Separator))+string(filepath.Separator))) {
+			continue
+		}
+		result = append(result, path)
+	}
+	return result
+}
+
+// commonParent 返回所有路径的公共父目录
+func commonParent(paths []string) string {
+	parent := filepath.Dir(paths[0])
+	for _, path := range paths[1:] {
+		for dir := filepath.Dir(path); parent != dir && !strings.HasPrefix(dir, strings.TrimSuffix(parent, string(filepath.Separator))+string(filepath.Separator)); {
+			next := filepath.Dir(parent)
+			if next == parent {
+				break
+			}
+			parent = next
+		}
+	}
+	return parent
+}
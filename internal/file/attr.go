@@ -0,0 +1,350 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Unix 权限位
+const (
+	modeSetuid = 04000
+	modeSetgid = 02000
+	modeSticky = 01000
+
+	// 各类用户对应的权限位，包括各自的特殊位
+	whoUser  = 04700
+	whoGroup = 02070
+	whoOther = 01007
+	whoAll   = whoUser | whoGroup | whoOther
+)
+
+// ModeSpec 权限修改说明，由 ParseModeSpec 解析
+type ModeSpec struct {
+	octal   bool
+	mode    uint32
+	clauses []modeClause
+}
+
+// modeClause 符号形式中逗号分隔的一个子句，如 "go-w"
+type modeClause struct {
+	who     uint32
+	actions []modeAction
+}
+
+// modeAction 子句中的一个操作，如 "+x"
+type modeAction struct {
+	op    byte   // '+'、'-' 或 '='
+	perms string // rwxXst 的组合，或者 u、g、o 之一表示复制该类用户的权限
+}
+
+// ParseModeSpec 解析权限说明，支持两种形式：
+//   - 八进制，如 755、0644、4755
+//   - 符号形式，与 chmod 相同，如 u+x、go-w、a=rX、u=rw,g=r,o=、g=u；省略用户类别时表示 a
+func ParseModeSpec(s string) (ModeSpec, error) {
+	if s == "" {
+		return ModeSpec{}, fmt.Errorf("mode is empty")
+	}
+	if s[0] >= '0' && s[0] <= '9' {
+		mode, err := strconv.ParseUint(s, 8, 32)
+		if err != nil || len(s) > 4 {
+			return ModeSpec{}, fmt.Errorf("invalid octal mode: %s", s)
+		}
+		return ModeSpec{octal: true, mode: uint32(mode)}, nil
+	}
+
+	var spec ModeSpec
+	for _, part := range strings.Split(s, ",") {
+		clause, err := parseModeClause(part)
+		if err != nil {
+			return ModeSpec{}, fmt.Errorf("invalid mode %q: %v", s, err)
+		}
+		spec.clauses = append(spec.clauses, clause)
+	}
+	return spec, nil
+}
+
+// parseModeClause 解析形如 [ugoa]*([-+=]([rwxXst]*|[ugo]))+ 的子句
+func parseModeClause(s string) (modeClause, error) {
+	var clause modeClause
+	i := 0
+	for ; i < len(s) && strings.IndexByte("ugoa", s[i]) >= 0; i++ {
+		switch s[i] {
+		case 'u':
+			clause.who |= whoUser
+		case 'g':
+			clause.who |= whoGroup
+		case 'o':
+			clause.who |= whoOther
+		case 'a':
+			clause.who |= whoAll
+		}
+	}
+	if clause.who == 0 {
+		clause.who = whoAll
+	}
+	if i == len(s) {
+		return modeClause{}, fmt.Errorf("missing operator in %q", s)
+	}
+
+	for i < len(s) {
+		op := s[i]
+		if op != '+' && op != '-' && op != '=' {
+			return modeClause{}, fmt.Errorf("unexpected %q in %q", op, s)
+		}
+		i++
+		start := i
+		if i < len(s) && strings.IndexByte("ugo", s[i]) >= 0 {
+			i++
+		} else {
+			for i < len(s) && strings.IndexByte("rwxXst", s[i]) >= 0 {
+				i++
+			}
+		}
+		clause.actions = append(clause.actions, modeAction{op: op, perms: s[start:i]})
+	}
+	return clause, nil
+}
+
+// Apply 计算对权限为 mode 的条目应用修改后的权限
+// X 只对目录或已有任意执行权限的文件添加执行权限
+func (m ModeSpec) Apply(mode os.FileMode, isDir bool) os.FileMode {
+	if m.octal {
+		return fromUnixMode(m.mode)
+	}
+
+	cur := toUnixMode(mode)
+	for _, clause := range m.clauses {
+		for _, action := range clause.actions {
+			bits := actionBits(cur, clause.who, action.perms, isDir)
+			switch action.op {
+			case '+':
+				cur |= bits
+			case '-':
+				cur &^= bits
+			case '=':
+				cur = cur&^clause.who | bits
+			}
+		}
+	}
+	return fromUnixMode(cur)
+}
+
+// actionBits 返回操作涉及的权限位
+func actionBits(cur, who uint32, perms string, isDir bool) uint32 {
+	switch perms {
+	case "u":
+		return spreadClass(cur>>6&7) & who & 0777
+	case "g":
+		return spreadClass(cur>>3&7) & who & 0777
+	case "o":
+		return spreadClass(cur&7) & who & 0777
+	}
+
+	var bits uint32
+	for i := 0; i < len(perms); i++ {
+		switch perms[i] {
+		case 'r':
+			bits |= 0444 & who
+		case 'w':
+			bits |= 0222 & who
+		case 'x':
+			bits |= 0111 & who
+		case 'X':
+			if isDir || cur&0111 != 0 {
+				bits |= 0111 & who
+			}
+		case 's':
+			bits |= (modeSetuid | modeSetgid) & who
+		case 't':
+			bits |= modeSticky & who
+		}
+	}
+	return bits
+}
+
+// spreadClass 将一类用户的 rwx 复制到所有类别
+func spreadClass(perm uint32) uint32 {
+	return perm<<6 | perm<<3 | perm
+}
+
+// toUnixMode 将 os.FileMode 的权限和特殊位转换为 Unix 权限位
+func toUnixMode(mode os.FileMode) uint32 {
+	bits := uint32(mode.Perm())
+	if mode&os.ModeSetuid != 0 {
+		bits |= modeSetuid
+	}
+	if mode&os.ModeSetgid != 0 {
+		bits |= modeSetgid
+	}
+	if mode&os.ModeSticky != 0 {
+		bits |= modeSticky
+	}
+	return bits
+}
+
+// fromUnixMode 将 Unix 权限位转换为可传给 os.Chmod 的 os.FileMode
+func fromUnixMode(bits uint32) os.FileMode {
+	mode := os.FileMode(bits & 0777)
+	if bits&modeSetuid != 0 {
+		mode |= os.ModeSetuid
+	}
+	if bits&modeSetgid != 0 {
+		mode |= os.ModeSetgid
+	}
+	if bits&modeSticky != 0 {
+		mode |= os.ModeSticky
+	}
+	return mode
+}
+
+// ChmodOptions 修改权限选项
+type ChmodOptions struct {
+	Mode      ModeSpec // 权限说明
+	Recursive bool     // 是否递归修改目录下的所有条目，符号链接本身没有权限，会被跳过
+}
+
+// ChownOptions 修改所有者选项
+type ChownOptions struct {
+	Owner     string // 用户名或用户ID，为空表示不修改
+	Group     string // 组名或组ID，为空表示不修改
+	Recursive bool   // 是否递归修改目录下的所有条目，符号链接修改其本身而不跟随
+}
+
+// ChtimesOptions 修改时间选项
+type ChtimesOptions struct {
+	AccessTime time.Time // 访问时间，零值表示不修改
+	ModTime    time.Time // 修改时间，零值表示不修改
+}
+
+// Chmod 实现 Service 接口的 Chmod 方法
+func (s *service) Chmod(ctx context.Context, path string, opts ChmodOptions) (FileInfo, error) {
+	processedPath, err := s.pathProcessor.ProcessPath(path)
+	if err != nil {
+		return FileInfo{}, err
+	}
+
+	info, err := os.Stat(processedPath)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	if err := os.Chmod(processedPath, opts.Mode.Apply(info.Mode(), info.IsDir())); err != nil {
+		return FileInfo{}, err
+	}
+
+	if opts.Recursive && info.IsDir() {
+		err = walkTree(ctx, processedPath, walkOptions{reserved: s.pathProcessor.Reserved}, func(dir string, entry os.DirEntry, rel string) error {
+			if entry.Type()&os.ModeSymlink != 0 {
+				return nil
+			}
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			fullPath := filepath.Join(dir, entry.Name())
+			return os.Chmod(fullPath, opts.Mode.Apply(info.Mode(), info.IsDir()))
+		})
+	}
+	s.notify(ChangeEvent{Op: ChangeWrite, Path: processedPath})
+	if err != nil {
+		return FileInfo{}, err
+	}
+	return s.GetInfo(ctx, path)
+}
+
+// Chown 实现 Service 接口的 Chown 方法
+func (s *service) Chown(ctx context.Context, path string, opts ChownOptions) (FileInfo, error) {
+	uid, gid, err := lookupOwner(opts.Owner, opts.Group)
+	if err != nil {
+		return FileInfo{}, err
+	}
+
+	processedPath, err := s.pathProcessor.ProcessPath(path)
+	if err != nil {
+		return FileInfo{}, err
+	}
+
+	info, err := os.Stat(processedPath)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	if err := os.Chown(processedPath, uid, gid); err != nil {
+		return FileInfo{}, chownError(err)
+	}
+
+	if opts.Recursive && info.IsDir() {
+		err = walkTree(ctx, processedPath, walkOptions{reserved: s.pathProcessor.Reserved}, func(dir string, entry os.DirEntry, rel string) error {
+			fullPath := filepath.Join(dir, entry.Name())
+			if err := os.Lchown(fullPath, uid, gid); err != nil {
+				return chownError(err)
+			}
+			return nil
+		})
+	}
+	s.notify(ChangeEvent{Op: ChangeWrite, Path: processedPath})
+	if err != nil {
+		return FileInfo{}, err
+	}
+	return s.GetInfo(ctx, path)
+}
+
+// Chtimes 实现 Service 接口的 Chtimes 方法
+func (s *service) Chtimes(ctx context.Context, path string, opts ChtimesOptions) (FileInfo, error) {
+	if opts.AccessTime.IsZero() && opts.ModTime.IsZero() {
+		return FileInfo{}, fmt.Errorf("no time to change")
+	}
+
+	processedPath, err := s.pathProcessor.ProcessPath(path)
+	if err != nil {
+		return FileInfo{}, err
+	}
+
+	// os.Chtimes 不修改零值对应的时间
+	if err := os.Chtimes(processedPath, opts.AccessTime, opts.ModTime); err != nil {
+		return FileInfo{}, err
+	}
+	s.notify(ChangeEvent{Op: ChangeWrite, Path: processedPath})
+	return s.GetInfo(ctx, path)
+}
+
+// lookupOwner 将用户名、组名或数字ID解析为 uid 和 gid，为空时返回 -1 表示不修改
+func lookupOwner(owner, group string) (uid, gid int, err error) {
+	if owner == "" && group == "" {
+		return 0, 0, fmt.Errorf("owner or group is required")
+	}
+
+	uid, gid = -1, -1
+	if owner != "" {
+		if uid, err = strconv.Atoi(owner); err != nil {
+			u, err := user.Lookup(owner)
+			if err != nil {
+				return 0, 0, fmt.Errorf("unknown user: %s", owner)
+			}
+			uid, _ = strconv.Atoi(u.Uid)
+		}
+	}
+	if group != "" {
+		if gid, err = strconv.Atoi(group); err != nil {
+			g, err := user.LookupGroup(group)
+			if err != nil {
+				return 0, 0, fmt.Errorf("unknown group: %s", group)
+			}
+			gid, _ = strconv.Atoi(g.Gid)
+		}
+	}
+	return uid, gid, nil
+}
+
+// chownError 为权限不足的错误补充说明：修改所有者通常需要服务以 root 或具有 CAP_CHOWN 的身份运行
+func chownError(err error) error {
+	if errors.Is(err, os.ErrPermission) {
+		return fmt.Errorf("%v (changing ownership requires the server to run with sufficient privilege)", err)
+	}
+	return err
+}
@@ -0,0 +1,75 @@
+package file
+
+import (
+	"os"
+	"testing"
+)
+
+func TestModeSpecApply(t *testing.T) {
+	// 期望值与 umask 为 0 时 GNU chmod 的结果一致
+	tests := []struct {
+		mode  uint32
+		spec  string
+		isDir bool
+		want  uint32
+	}{
+		{0644, "u+x", false, 0744},
+		{0644, "a=rX", false, 0444},
+		{0744, "a=rX", false, 0555},
+		{0644, "a=rX", true, 0555},
+		{0664, "+X", false, 0664},
+		{0640, "g=u", false, 0660},
+		{0754, "o=g", false, 0755},
+		{0750, "go=u", false, 0777},
+		{0777, "go-w", false, 0755},
+		{0644, "u=rw,g=r,o=", false, 0640},
+		{0644, "u+x,g+w,o-r", false, 0760},
+		{0600, "g+rw-w", false, 0640},
+		{0711, "u=rwx,go=x", false, 0711},
+		{0755, "a=", false, 0},
+		{0644, "=r", false, 0444},
+		{0755, "u+s", false, 04755},
+		{0755, "g+s", false, 02755},
+		{0755, "ug+s", false, 06755},
+		{04755, "o+s", false, 04755},
+		{04755, "u-s", false, 0755},
+		{06755, "a-s", false, 0755},
+		{0755, "+t", true, 01755},
+		{01777, "-t", true, 0777},
+		{04755, "644", false, 0644},
+		{0644, "0755", false, 0755},
+		{0644, "4755", false, 04755},
+		{0755, "1777", true, 01777},
+	}
+	for _, tt := range tests {
+		spec, err := ParseModeSpec(tt.spec)
+		if err != nil {
+			t.Errorf("ParseModeSpec(%q) error = %v", tt.spec, err)
+			continue
+		}
+		got := toUnixMode(spec.Apply(fromUnixMode(tt.mode), tt.isDir))
+		if got != tt.want {
+			t.Errorf("%04o %q (dir %v) = %04o, want %04o", tt.mode, tt.spec, tt.isDir, got, tt.want)
+		}
+	}
+}
+
+func TestParseModeSpecInvalid(t *testing.T) {
+	for _, s := range []string{"", "8", "0800", "04755", "12345", "u", "ug", "u+q", "z+x", "u+x,", "u+rw,go"} {
+		if _, err := ParseModeSpec(s); err == nil {
+			t.Errorf("ParseModeSpec(%q) error = nil, want error", s)
+		}
+	}
+}
+
+func TestUnixModeRoundTrip(t *testing.T) {
+	for _, bits := range []uint32{0, 0644, 04755, 02775, 01777, 07777} {
+		mode := fromUnixMode(bits)
+		if mode&^(os.ModePerm|os.ModeSetuid|os.ModeSetgid|os.ModeSticky) != 0 {
+			t.Errorf("fromUnixMode(%04o) = %v contains non-permission bits", bits, mode)
+		}
+		if got := toUnixMode(mode); got != bits {
+			t.Errorf("toUnixMode(fromUnixMode(%04o)) = %04o", bits, got)
+		}
+	}
+}
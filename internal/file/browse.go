@@ -0,0 +1,622 @@
+package file
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"container/list"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// ArchiveSeparator 归档文件路径与归档内成员路径之间的分隔符，如 /data/build.zip!/bin
+	ArchiveSeparator = "!/"
+	// archiveIndexCacheSize 最多缓存成员索引的归档数
+	archiveIndexCacheSize = 32
+)
+
+// archiveMember 归档中的一个成员，目录可能没有对应的条目而由成员路径推断
+type archiveMember struct {
+	name     string      // 归档内的路径，以 / 分隔，不含前导 /，归档根目录为空
+	info     os.FileInfo // 来自归档头的文件信息
+	link     string      // 符号链接目标
+	hardLink string      // tar 硬链接指向的成员路径
+	index    int         // 条目在归档中的序号，读取压缩的 tar 和 zip 时用于定位
+	offset   int64       // 未压缩内容在归档文件中的偏移，-1 表示需要解压读取
+	children map[string]*archiveMember
+}
+
+// archiveIndex 归档的成员索引
+type archiveIndex struct {
+	path    string // 处理后的归档文件路径
+	format  ArchiveFormat
+	modTime time.Time // 归档文件的修改时间，用作推断出的目录的修改时间
+	members map[string]*archiveMember
+}
+
+// memberInfo 使用规范化后的名称覆盖归档头中的名称
+type memberInfo struct {
+	os.FileInfo
+	name string
+}
+
+// Name 实现 os.FileInfo 接口
+func (i memberInfo) Name() string { return i.name }
+
+// impliedDirInfo 没有对应条目、由成员路径推断出的目录
+type impliedDirInfo struct {
+	name    string
+	modTime time.Time
+}
+
+func (i impliedDirInfo) Name() string       { return i.name }
+func (i impliedDirInfo) Size() int64        { return 0 }
+func (i impliedDirInfo) Mode() os.FileMode  { return os.ModeDir | 0755 }
+func (i impliedDirInfo) ModTime() time.Time { return i.modTime }
+func (i impliedDirInfo) IsDir() bool        { return true }
+func (i impliedDirInfo) Sys() interface{}   { return nil }
+
+// newArchiveIndex 创建只包含根目录的索引
+func newArchiveIndex(path string, format ArchiveFormat, modTime time.Time) *archiveIndex {
+	root := &archiveMember{info: impliedDirInfo{name: "/", modTime: modTime}, index: -1, offset: -1, children: make(map[string]*archiveMember)}
+	return &archiveIndex{path: path, format: format, modTime: modTime, members: map[string]*archiveMember{"": root}}
+}
+
+// dir 返回目录成员，不存在或同名的是文件时创建目录并推断其上级目录
+func (idx *archiveIndex) dir(name string) *archiveMember {
+	if m, ok := idx.members[name]; ok && m.children != nil {
+		return m
+	}
+	m := &archiveMember{name: name, info: impliedDirInfo{name: path.Base(name), modTime: idx.modTime}, index: -1, offset: -1, children: make(map[string]*archiveMember)}
+	idx.members[name] = m
+	parent := idx.dir(parentMember(name))
+	parent.children[path.Base(name)] = m
+	return m
+}
+
+// add 加入一个成员，名称不安全的条目被忽略；同名条目以后出现的为准，已有目录的子成员被保留
+func (idx *archiveIndex) add(name string, info os.FileInfo, m *archiveMember) {
+	clean, err := cleanMemberName(name)
+	if err != nil || clean == "" {
+		return
+	}
+	m.name = clean
+	m.info = memberInfo{FileInfo: info, name: path.Base(clean)}
+	if info.IsDir() {
+		existing := idx.dir(clean)
+		existing.info = m.info
+		return
+	}
+	if existing, ok := idx.members[clean]; ok && existing.children != nil {
+		// 目录与文件同名时保留目录，否则其子成员将无法访问
+		return
+	}
+	idx.members[clean] = m
+	idx.dir(parentMember(clean)).children[path.Base(clean)] = m
+}
+
+// parentMember 返回成员的上级目录路径
+func parentMember(name string) string {
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		return name[:i]
+	}
+	return ""
+}
+
+// splitArchivePath 在第一个以归档扩展名结尾并跟随 ! 的位置拆分路径，返回归档路径和成员路径
+func splitArchivePath(p string) (string, string, bool) {
+	for i := 0; i < len(p); i++ {
+		if p[i] != '!' || (i+1 < len(p) && p[i+1] != '/') {
+			continue
+		}
+		if _, ok := DetectArchiveFormat(p[:i]); ok {
+			return p[:i], strings.TrimLeft(p[i+1:], "/"), true
+		}
+	}
+	return "", "", false
+}
+
+// archiveMemberPath 判断路径是否指向归档内部，返回处理后的归档路径和规范化的成员路径
+// 只有 ! 之前的部分是已存在的普通文件时才视为归档路径，其他情况按普通路径处理
+func (s *service) archiveMemberPath(p string) (string, string, bool, error) {
+	archivePath, member, ok := splitArchivePath(p)
+	if !ok {
+		return "", "", false, nil
+	}
+	processedPath, err := s.pathProcessor.ProcessPath(archivePath)
+	if err != nil {
+		return "", "", false, err
+	}
+	if info, err := os.Stat(processedPath); err != nil || !info.Mode().IsRegular() {
+		return "", "", false, nil
+	}
+	member, err = cleanMemberName(member)
+	if err != nil {
+		return "", "", false, fmt.Errorf("invalid archive member path: %s", p)
+	}
+	return processedPath, member, true, nil
+}
+
+// archiveIndexCache 按 LRU 淘汰的归档成员索引缓存，归档文件变化后缓存失效
+type archiveIndexCache struct {
+	mu    sync.Mutex
+	max   int
+	order *list.List // 元素为 *archiveIndexCacheEntry，最近使用的在前
+	items map[string]*list.Element
+}
+
+// archiveIndexCacheEntry 缓存条目
+type archiveIndexCacheEntry struct {
+	key checksumKey
+	idx *archiveIndex
+}
+
+// newArchiveIndexCache 创建归档成员索引缓存
+func newArchiveIndexCache(max int) *archiveIndexCache {
+	return &archiveIndexCache{max: max, order: list.New(), items: make(map[string]*list.Element)}
+}
+
+// get 返回与归档当前状态一致的索引
+func (c *archiveIndexCache) get(key checksumKey) *archiveIndex {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key.path]
+	if !ok || elem.Value.(*archiveIndexCacheEntry).key != key {
+		return nil
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*archiveIndexCacheEntry).idx
+}
+
+// put 保存索引，同一归档旧状态的索引被替换
+func (c *archiveIndexCache) put(key checksumKey, idx *archiveIndex) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[key.path]; ok {
+		elem.Value = &archiveIndexCacheEntry{key: key, idx: idx}
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.items[key.path] = c.order.PushFront(&archiveIndexCacheEntry{key: key, idx: idx})
+	for c.order.Len() > c.max {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*archiveIndexCacheEntry).key.path)
+	}
+}
+
+// archiveIndex 返回归档的成员索引，只读取归档头，不解压文件内容（压缩的 tar 仍需顺序解压一遍）
+func (s *service) archiveIndex(ctx context.Context, archivePath string) (*archiveIndex, error) {
+	info, err := os.Stat(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	key := newChecksumKey(archivePath, info)
+	if idx := s.archives.get(key); idx != nil {
+		return idx, nil
+	}
+
+	format, ok := DetectArchiveFormat(archivePath)
+	if !ok {
+		return nil, fmt.Errorf("unknown archive format: %s", archivePath)
+	}
+	idx := newArchiveIndex(archivePath, format, info.ModTime())
+	if format == ArchiveZip {
+		err = s.indexZip(ctx, archivePath, idx)
+	} else {
+		err = s.indexTar(ctx, archivePath, idx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.archives.put(key, idx)
+	return idx, nil
+}
+
+// checkIndexSize 检查成员数限制，避免为条目过多的归档占用大量内存
+func (s *service) checkIndexSize(count int) error {
+	if max := s.config.Archive.MaxEntries; max > 0 && count > max {
+		return fmt.Errorf("%w: more than %d entries", ErrArchiveLimit, max)
+	}
+	return nil
+}
+
+// indexZip 根据 zip 的中央目录建立索引
+func (s *service) indexZip(ctx context.Context, archivePath string, idx *archiveIndex) error {
+	zr, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	if err := s.checkIndexSize(len(zr.File)); err != nil {
+		return err
+	}
+	for i, f := range zr.File {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		m := &archiveMember{index: i, offset: -1}
+		if f.Method == zip.Store {
+			if offset, err := f.DataOffset(); err == nil {
+				m.offset = offset
+			}
+		}
+		if f.Mode()&os.ModeSymlink != 0 {
+			if rc, err := f.Open(); err == nil {
+				link, _ := io.ReadAll(io.LimitReader(rc, maxSymlinkTargetSize))
+				rc.Close()
+				m.link = string(link)
+			}
+		}
+		idx.add(f.Name, f.FileInfo(), m)
+	}
+	return nil
+}
+
+// offsetReader 记录当前读取位置，用于得到未压缩 tar 中文件内容的偏移
+type offsetReader struct {
+	f   *os.File
+	pos int64
+}
+
+// Read 实现 io.Reader 接口
+func (r *offsetReader) Read(b []byte) (int, error) {
+	n, err := r.f.Read(b)
+	r.pos += int64(n)
+	return n, err
+}
+
+// Seek 实现 io.Seeker 接口，使 tar.Reader 跳过文件内容时不必读取
+func (r *offsetReader) Seek(offset int64, whence int) (int64, error) {
+	pos, err := r.f.Seek(offset, whence)
+	if err == nil {
+		r.pos = pos
+	}
+	return pos, err
+}
+
+// indexTar 顺序读取 tar 的条目头建立索引
+func (s *service) indexTar(ctx context.Context, archivePath string, idx *archiveIndex) error {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	or := &offsetReader{f: f}
+	var r io.Reader = or
+	if idx.format != ArchiveTar {
+		decompressed, closeReader, err := decompressor(f, idx.format)
+		if err != nil {
+			return err
+		}
+		defer closeReader()
+		r = decompressed
+	}
+
+	tr := tar.NewReader(r)
+	for i := 0; ; i++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := s.checkIndexSize(i + 1); err != nil {
+			return err
+		}
+
+		m := &archiveMember{index: i, offset: -1}
+		info := hdr.FileInfo()
+		switch hdr.Typeflag {
+		case tar.TypeSymlink:
+			m.link = hdr.Linkname
+		case tar.TypeLink:
+			// 硬链接条目不含内容，大小、权限和修改时间取自之前出现的目标条目
+			m.hardLink, _ = cleanMemberName(hdr.Linkname)
+			if target, ok := idx.members[m.hardLink]; ok && target.children == nil {
+				info = target.info
+			}
+		case tar.TypeReg, tar.TypeRegA:
+			if idx.format == ArchiveTar && !sparseHeader(hdr) {
+				m.offset = or.pos
+			}
+		}
+		idx.add(hdr.Name, info, m)
+	}
+}
+
+// sparseHeader 判断 tar 条目是否为稀疏文件，稀疏文件的内容在归档中不连续
+func sparseHeader(hdr *tar.Header) bool {
+	if hdr.Typeflag == tar.TypeGNUSparse {
+		return true
+	}
+	for key := range hdr.PAXRecords {
+		if strings.HasPrefix(key, "GNU.sparse.") {
+			return true
+		}
+	}
+	return false
+}
+
+// memberFileInfo 根据归档头构造成员的文件信息，路径为 归档路径!/成员路径
+func memberFileInfo(archivePath string, m *archiveMember, fields FieldSet) FileInfo {
+	info := m.info
+	isDir := m.children != nil
+	fileInfo := FileInfo{
+		Name:          info.Name(),
+		IsDir:         isDir,
+		Size:          info.Size(),
+		SizeHuman:     formatFileSize(info.Size()),
+		Path:          archivePath + ArchiveSeparator + m.name,
+		Ext:           path.Ext(info.Name()),
+		ModTime:       info.ModTime(),
+		Mode:          info.Mode().String(),
+		IsHidden:      strings.HasPrefix(info.Name(), "."),
+		IsSymlink:     info.Mode()&os.ModeSymlink != 0,
+		SymlinkTarget: m.link,
+	}
+	if isDir {
+		fileInfo.Size, fileInfo.SizeHuman = 0, formatFileSize(0)
+	}
+	if fields.Requested("mimeType") {
+		fileInfo.MimeType = memberMimeType(m)
+	}
+	if hdr, ok := info.Sys().(*tar.Header); ok {
+		uid, gid := uint32(hdr.Uid), uint32(hdr.Gid)
+		fileInfo.UID, fileInfo.GID = &uid, &gid
+		fileInfo.Owner, fileInfo.Group = hdr.Uname, hdr.Gname
+	}
+	return fileInfo
+}
+
+// memberMimeType 根据扩展名判断成员的 MIME 类型，不读取内容
+func memberMimeType(m *archiveMember) string {
+	if m.children != nil {
+		return "inode/directory"
+	}
+	if mimeType := mime.TypeByExtension(path.Ext(m.name)); mimeType != "" {
+		return mimeType
+	}
+	return "application/octet-stream"
+}
+
+// listArchive 列出归档中的目录
+func (s *service) listArchive(ctx context.Context, archivePath, member string, opts ListOptions) (ListResult, error) {
+	idx, err := s.archiveIndex(ctx, archivePath)
+	if err != nil {
+		return ListResult{}, err
+	}
+	dir, ok := idx.members[member]
+	if !ok {
+		return ListResult{}, fmt.Errorf("directory does not exist: %s", archivePath+ArchiveSeparator+member)
+	}
+	if dir.children == nil {
+		return ListResult{}, fmt.Errorf("not a directory: %s", archivePath+ArchiveSeparator+member)
+	}
+
+	dirEntries := make([]os.DirEntry, 0, len(dir.children))
+	for _, child := range dir.children {
+		dirEntries = append(dirEntries, fs.FileInfoToDirEntry(child.info))
+	}
+	return listPage(ctx, dirEntries, opts, func(entry os.DirEntry) (FileInfo, error) {
+		return memberFileInfo(archivePath, dir.children[entry.Name()], opts.Fields), nil
+	})
+}
+
+// resolveMember 跟随符号链接和硬链接，返回归档中的普通文件成员，链接目标必须位于归档之内
+func (idx *archiveIndex) resolveMember(name string) (*archiveMember, error) {
+	display := idx.path + ArchiveSeparator + name
+	m, ok := idx.members[name]
+	for i := 0; ok && i < maxSymlinks; i++ {
+		switch {
+		case m.hardLink != "":
+			m, ok = idx.members[m.hardLink]
+		case m.info.Mode()&os.ModeSymlink != 0:
+			if path.IsAbs(m.link) {
+				return nil, fmt.Errorf("symlink points outside archive: %s", display)
+			}
+			target, err := cleanMemberName(path.Join(parentMember(m.name), m.link))
+			if err != nil {
+				return nil, fmt.Errorf("symlink points outside archive: %s", display)
+			}
+			m, ok = idx.members[target]
+		case m.children != nil:
+			return nil, fmt.Errorf("path is a directory: %s", display)
+		case !m.info.Mode().IsRegular():
+			return nil, fmt.Errorf("not a regular file: %s", display)
+		default:
+			return m, nil
+		}
+	}
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: display, Err: os.ErrNotExist}
+	}
+	return nil, fmt.Errorf("too many levels of symbolic links: %s", display)
+}
+
+// openArchiveMember 打开归档中的文件成员用于读取
+// 未压缩的成员直接按偏移读取；压缩的成员在定位时从头解压，向后定位时跳过中间的内容
+func (s *service) openArchiveMember(ctx context.Context, archivePath, member string) (io.ReadSeekCloser, FileInfo, error) {
+	idx, err := s.archiveIndex(ctx, archivePath)
+	if err != nil {
+		return nil, FileInfo{}, err
+	}
+	m, err := idx.resolveMember(member)
+	if err != nil {
+		return nil, FileInfo{}, err
+	}
+	// 下载时需要 MIME 类型作为 Content-Type
+	info := memberFileInfo(archivePath, m, FieldSet{"mimeType": true})
+	if m.name != member {
+		// 经由链接打开时使用请求的名称和路径
+		info.Name, info.Ext, info.Path = path.Base(member), path.Ext(member), archivePath+ArchiveSeparator+member
+	}
+
+	size := m.info.Size()
+	if m.offset >= 0 {
+		f, err := os.Open(archivePath)
+		if err != nil {
+			return nil, FileInfo{}, err
+		}
+		return sectionReadCloser{SectionReader: io.NewSectionReader(f, m.offset, size), f: f}, info, nil
+	}
+	open := func() (io.ReadCloser, error) { return openZipMember(archivePath, m.index) }
+	if idx.format != ArchiveZip {
+		open = func() (io.ReadCloser, error) { return openTarMember(archivePath, idx.format, m.index) }
+	}
+	return &memberReader{open: open, size: size}, info, nil
+}
+
+// sectionReadCloser 读取归档文件中的一段
+type sectionReadCloser struct {
+	*io.SectionReader
+	f *os.File
+}
+
+// Close 实现 io.Closer 接口
+func (r sectionReadCloser) Close() error { return r.f.Close() }
+
+// readCloser 将读取器与关闭函数组合
+type readCloser struct {
+	io.Reader
+	close func() error
+}
+
+// Close 实现 io.Closer 接口
+func (r readCloser) Close() error { return r.close() }
+
+// openZipMember 打开 zip 中指定序号的条目并解压
+func openZipMember(archivePath string, index int) (io.ReadCloser, error) {
+	zr, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	if index >= len(zr.File) {
+		zr.Close()
+		return nil, fmt.Errorf("archive changed: %s", archivePath)
+	}
+	rc, err := zr.File[index].Open()
+	if err != nil {
+		zr.Close()
+		return nil, err
+	}
+	return readCloser{Reader: rc, close: func() error {
+		rc.Close()
+		return zr.Close()
+	}}, nil
+}
+
+// openTarMember 从头读取 tar 归档直到指定序号的条目
+func openTarMember(archivePath string, format ArchiveFormat, index int) (io.ReadCloser, error) {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	r, closeReader, err := decompressor(f, format)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	closeAll := func() error {
+		closeReader()
+		return f.Close()
+	}
+
+	tr := tar.NewReader(r)
+	for i := 0; i <= index; i++ {
+		if _, err := tr.Next(); err != nil {
+			closeAll()
+			if err == io.EOF {
+				err = fmt.Errorf("archive changed: %s", archivePath)
+			}
+			return nil, err
+		}
+	}
+	return readCloser{Reader: tr, close: closeAll}, nil
+}
+
+// memberReader 为需要解压的成员提供 io.ReadSeeker，使下载可以使用 Range 请求
+// 定位只记录位置，读取时如需向前回退则重新打开成员
+type memberReader struct {
+	open  func() (io.ReadCloser, error)
+	size  int64
+	pos   int64 // 下一次读取的位置
+	rc    io.ReadCloser
+	rcPos int64 // rc 的当前位置
+}
+
+// Read 实现 io.Reader 接口
+func (r *memberReader) Read(b []byte) (int, error) {
+	if r.pos >= r.size {
+		return 0, io.EOF
+	}
+	if r.rc != nil && r.rcPos > r.pos {
+		r.rc.Close()
+		r.rc = nil
+	}
+	if r.rc == nil {
+		rc, err := r.open()
+		if err != nil {
+			return 0, err
+		}
+		r.rc, r.rcPos = rc, 0
+	}
+	if r.rcPos < r.pos {
+		n, err := io.CopyN(io.Discard, r.rc, r.pos-r.rcPos)
+		r.rcPos += n
+		if err != nil {
+			return 0, err
+		}
+	}
+	if remaining := r.size - r.pos; int64(len(b)) > remaining {
+		b = b[:remaining]
+	}
+	n, err := r.rc.Read(b)
+	r.rcPos += int64(n)
+	r.pos += int64(n)
+	return n, err
+}
+
+// Seek 实现 io.Seeker 接口
+func (r *memberReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.pos
+	case io.SeekEnd:
+		offset += r.size
+	default:
+		return 0, fmt.Errorf("invalid whence: %d", whence)
+	}
+	if offset < 0 {
+		return 0, fmt.Errorf("negative position: %d", offset)
+	}
+	r.pos = offset
+	return offset, nil
+}
+
+// Close 实现 io.Closer 接口
+func (r *memberReader) Close() error {
+	if r.rc == nil {
+		return nil
+	}
+	err := r.rc.Close()
+	r.rc = nil
+	return err
+}
@@ -0,0 +1,218 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSplitArchivePath(t *testing.T) {
+	tests := []struct {
+		path        string
+		wantArchive string
+		wantMember  string
+		wantOK      bool
+	}{
+		{"/a/b.zip!/x/y", "/a/b.zip", "x/y", true},
+		{"/a/b.zip!", "/a/b.zip", "", true},
+		{"/a/b.zip!/", "/a/b.zip", "", true},
+		{"/a/b.tar.gz!//x", "/a/b.tar.gz", "x", true},
+		{"/a/B.ZIP!/x", "/a/B.ZIP", "x", true},
+		// ! 之后不是 / 时不是分隔符
+		{"/a/b!c.zip!/x", "/a/b!c.zip", "x", true},
+		// ! 之前不是归档扩展名时继续查找
+		{"/a/dir!/b.tgz!/x", "/a/dir!/b.tgz", "x", true},
+		// 只在第一个归档处拆分，嵌套归档作为成员路径的一部分
+		{"/a/outer.zip!/inner.tar!/f", "/a/outer.zip", "inner.tar!/f", true},
+		{"/a/b.zip", "", "", false},
+		{"/a/b.zip!x", "", "", false},
+		{"/a/file.txt!/x", "", "", false},
+		{"", "", "", false},
+	}
+	for _, tt := range tests {
+		archive, member, ok := splitArchivePath(tt.path)
+		if archive != tt.wantArchive || member != tt.wantMember || ok != tt.wantOK {
+			t.Errorf("splitArchivePath(%q) = %q, %q, %v; want %q, %q, %v",
+				tt.path, archive, member, ok, tt.wantArchive, tt.wantMember, tt.wantOK)
+		}
+	}
+}
+
+func TestResolveMember(t *testing.T) {
+	root := t.TempDir()
+	s := newTestService(t, root)
+	archive := filepath.Join(root, "links.tar")
+	writeTestTar(t, archive, false, []testEntry{
+		{name: "d/", dir: true},
+		{name: "d/f", body: "data"},
+		{name: "d/l1", link: "f"},
+		{name: "l2", link: "d/l1"},
+		{name: "d/up", link: "../d/f"},
+		{name: "h", hardLink: "d/f"},
+		{name: "hl", hardLink: "l2"},
+		{name: "dirlink", link: "d"},
+		{name: "esc", link: "../x"},
+		{name: "d/esc", link: "../../f"},
+		{name: "abs", link: "/etc/passwd"},
+		{name: "dangling", link: "missing"},
+		{name: "loop1", link: "loop2"},
+		{name: "loop2", link: "loop1"},
+		{name: "self", link: "self"},
+	})
+	idx, err := s.archiveIndex(context.Background(), archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		want    string // 解析到的成员路径
+		wantErr string
+	}{
+		{name: "d/f", want: "d/f"},
+		{name: "d/l1", want: "d/f"},
+		{name: "l2", want: "d/f"},
+		{name: "d/up", want: "d/f"},
+		{name: "h", want: "d/f"},
+		{name: "hl", want: "d/f"},
+		{name: "d", wantErr: "path is a directory"},
+		{name: "dirlink", wantErr: "path is a directory"},
+		{name: "esc", wantErr: "outside archive"},
+		{name: "d/esc", wantErr: "outside archive"},
+		{name: "abs", wantErr: "outside archive"},
+		{name: "dangling", wantErr: "does not exist"},
+		{name: "missing", wantErr: "does not exist"},
+		{name: "loop1", wantErr: "too many levels"},
+		{name: "self", wantErr: "too many levels"},
+	}
+	for _, tt := range tests {
+		m, err := idx.resolveMember(tt.name)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("resolveMember(%q) error = %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || m.name != tt.want {
+			t.Errorf("resolveMember(%q) = %v, %v; want %q", tt.name, m, err, tt.want)
+		}
+	}
+	if _, err := idx.resolveMember("missing"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("resolveMember(missing) error = %v, want os.ErrNotExist", err)
+	}
+}
+
+// seekStep memberReader 测试中的一步：定位后读取 n 个字节
+type seekStep struct {
+	offset  int64
+	whence  int
+	wantPos int64
+	n       int
+	want    string
+	seekErr bool
+}
+
+// runSeekSteps 依次执行定位和读取，检查读到的内容
+func runSeekSteps(t *testing.T, r io.ReadSeeker, steps []seekStep) {
+	t.Helper()
+	for i, step := range steps {
+		pos, err := r.Seek(step.offset, step.whence)
+		if step.seekErr {
+			if err == nil {
+				t.Errorf("step %d: Seek(%d, %d) error = nil", i, step.offset, step.whence)
+			}
+			continue
+		}
+		if err != nil || pos != step.wantPos {
+			t.Fatalf("step %d: Seek(%d, %d) = %d, %v; want %d", i, step.offset, step.whence, pos, err, step.wantPos)
+		}
+		buf := make([]byte, step.n)
+		n, err := io.ReadFull(r, buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			t.Fatalf("step %d: read error = %v", i, err)
+		}
+		if got := string(buf[:n]); got != step.want {
+			t.Errorf("step %d: read at %d = %q, want %q", i, pos, got, step.want)
+		}
+	}
+}
+
+const seekContent = "0123456789abcdefghij"
+
+var seekSteps = []seekStep{
+	{offset: 5, whence: io.SeekStart, wantPos: 5, n: 3, want: "567"},
+	{offset: 2, whence: io.SeekCurrent, wantPos: 10, n: 4, want: "abcd"},
+	{offset: -3, whence: io.SeekEnd, wantPos: 17, n: 10, want: "hij"},
+	// 向前回退需要重新打开成员
+	{offset: 1, whence: io.SeekStart, wantPos: 1, n: 2, want: "12"},
+	{offset: 0, whence: io.SeekCurrent, wantPos: 3, n: 1, want: "3"},
+	{offset: 25, whence: io.SeekStart, wantPos: 25, n: 1, want: ""},
+	{offset: -1, whence: io.SeekStart, seekErr: true},
+	{offset: 0, whence: 7, seekErr: true},
+}
+
+func TestMemberReader(t *testing.T) {
+	opens := 0
+	r := &memberReader{
+		open: func() (io.ReadCloser, error) {
+			opens++
+			return io.NopCloser(strings.NewReader(seekContent)), nil
+		},
+		size: int64(len(seekContent)),
+	}
+	runSeekSteps(t, r, seekSteps)
+	// 第一次读取和唯一一次回退各打开一次，越过末尾的读取不打开
+	if opens != 2 {
+		t.Errorf("opens = %d, want 2", opens)
+	}
+	if err := r.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+
+	// 内容比记录的大小长时只读取 size 个字节
+	r = &memberReader{
+		open: func() (io.ReadCloser, error) { return io.NopCloser(strings.NewReader(seekContent)), nil },
+		size: 4,
+	}
+	if data, err := io.ReadAll(r); err != nil || string(data) != "0123" {
+		t.Errorf("ReadAll() = %q, %v; want 0123", data, err)
+	}
+}
+
+func TestOpenArchiveMember(t *testing.T) {
+	root := t.TempDir()
+	s := newTestService(t, root)
+	entries := []testEntry{{name: "dir/", dir: true}, {name: "dir/data.txt", body: seekContent}, {name: "link", link: "dir/data.txt"}}
+
+	archives := map[string]func(path string){
+		"plain.tar":  func(path string) { writeTestTar(t, path, false, entries) },
+		"packed.tgz": func(path string) { writeTestTar(t, path, true, entries) },
+		"packed.zip": func(path string) { writeTestZip(t, path, entries) },
+	}
+	for name, write := range archives {
+		t.Run(name, func(t *testing.T) {
+			archive := filepath.Join(root, name)
+			write(archive)
+
+			r, info, err := s.Open(context.Background(), archive+ArchiveSeparator+"link")
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			defer r.Close()
+			// 经由链接打开时使用请求的路径
+			if info.Name != "link" || info.Path != archive+"!/link" || info.Size != int64(len(seekContent)) {
+				t.Errorf("Open() info = %+v", info)
+			}
+			data, err := io.ReadAll(r)
+			if err != nil || !bytes.Equal(data, []byte(seekContent)) {
+				t.Fatalf("ReadAll() = %q, %v", data, err)
+			}
+			runSeekSteps(t, r, seekSteps[:6])
+		})
+	}
+}
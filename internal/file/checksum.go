@@ -0,0 +1,331 @@
+package file
+
+import (
+	"container/list"
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// checksumCacheSize 校验和缓存最多保存的文件数
+const checksumCacheSize = 10000
+
+// HashAlgorithm 校验和算法
+type HashAlgorithm string
+
+const (
+	HashMD5     HashAlgorithm = "md5"
+	HashSHA1    HashAlgorithm = "sha1"
+	HashSHA256  HashAlgorithm = "sha256"
+	HashSHA512  HashAlgorithm = "sha512"
+	HashBLAKE2b HashAlgorithm = "blake2b" // BLAKE2b-512，与 b2sum 一致
+	HashCRC32   HashAlgorithm = "crc32"   // IEEE 多项式
+)
+
+// newHash 创建算法对应的哈希函数
+func newHash(algorithm HashAlgorithm) (hash.Hash, error) {
+	switch algorithm {
+	case HashMD5:
+		return md5.New(), nil
+	case HashSHA1:
+		return sha1.New(), nil
+	case HashSHA256:
+		return sha256.New(), nil
+	case HashSHA512:
+		return sha512.New(), nil
+	case HashBLAKE2b:
+		return blake2b.New512(nil)
+	case HashCRC32:
+		return crc32.NewIEEE(), nil
+	default:
+		return nil, fmt.Errorf("unsupported checksum algorithm: %s", algorithm)
+	}
+}
+
+// ParseHashAlgorithms 解析逗号分隔的算法列表，空字符串表示 sha256，重复的算法只保留一个
+func ParseHashAlgorithms(value string) ([]HashAlgorithm, error) {
+	if strings.TrimSpace(value) == "" {
+		return []HashAlgorithm{HashSHA256}, nil
+	}
+
+	var algorithms []HashAlgorithm
+	seen := make(map[HashAlgorithm]bool)
+	for _, name := range strings.Split(value, ",") {
+		algorithm := HashAlgorithm(strings.ToLower(strings.TrimSpace(name)))
+		if algorithm == "" || seen[algorithm] {
+			continue
+		}
+		if _, err := newHash(algorithm); err != nil {
+			return nil, err
+		}
+		seen[algorithm] = true
+		algorithms = append(algorithms, algorithm)
+	}
+	return algorithms, nil
+}
+
+// ChecksumResult 文件校验和
+type ChecksumResult struct {
+	Path      string                   `json:"path"`      // 文件路径
+	Size      int64                    `json:"size"`      // 文件大小
+	ModTime   time.Time                `json:"modTime"`   // 修改时间
+	Checksums map[HashAlgorithm]string `json:"checksums"` // 算法到十六进制校验和的映射
+	Cached    bool                     `json:"cached"`    // 是否全部来自缓存，未读取文件内容
+}
+
+// ManifestEntry 目录清单中的一个文件
+type ManifestEntry struct {
+	Path     string // 相对于目录的路径，使用 / 分隔
+	Checksum string // 十六进制校验和
+}
+
+// ManifestSummary 目录清单统计
+type ManifestSummary struct {
+	Files  int `json:"files"`  // 写入清单的文件数
+	Failed int `json:"failed"` // 无法读取而被跳过的文件数
+}
+
+// checksumKey 缓存键，文件被修改、替换或移动后键会变化
+type checksumKey struct {
+	path    string
+	size    int64
+	modTime int64
+	inode   uint64
+}
+
+// newChecksumKey 根据文件状态构造缓存键
+func newChecksumKey(path string, info os.FileInfo) checksumKey {
+	return checksumKey{path: path, size: info.Size(), modTime: info.ModTime().UnixNano(), inode: fileInode(info)}
+}
+
+// checksumCache 按 LRU 淘汰的校验和缓存，每个路径只保留最新状态的结果
+type checksumCache struct {
+	mu     sync.Mutex
+	max    int
+	order  *list.List // 元素为 *checksumCacheEntry，最近使用的在前
+	items  map[checksumKey]*list.Element
+	byPath map[string]checksumKey
+}
+
+// checksumCacheEntry 缓存条目
+type checksumCacheEntry struct {
+	key  checksumKey
+	sums map[HashAlgorithm]string
+}
+
+// newChecksumCache 创建校验和缓存
+func newChecksumCache(max int) *checksumCache {
+	return &checksumCache{
+		max:    max,
+		order:  list.New(),
+		items:  make(map[checksumKey]*list.Element),
+		byPath: make(map[string]checksumKey),
+	}
+}
+
+// get 返回已缓存的校验和副本
+func (c *checksumCache) get(key checksumKey) map[HashAlgorithm]string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return nil
+	}
+	c.order.MoveToFront(elem)
+	sums := make(map[HashAlgorithm]string, len(elem.Value.(*checksumCacheEntry).sums))
+	for algorithm, sum := range elem.Value.(*checksumCacheEntry).sums {
+		sums[algorithm] = sum
+	}
+	return sums
+}
+
+// put 合并保存校验和，同一路径旧状态的结果被丢弃
+func (c *checksumCache) put(key checksumKey, sums map[HashAlgorithm]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		entry := elem.Value.(*checksumCacheEntry)
+		for algorithm, sum := range sums {
+			entry.sums[algorithm] = sum
+		}
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	if old, ok := c.byPath[key.path]; ok {
+		c.order.Remove(c.items[old])
+		delete(c.items, old)
+	}
+	entry := &checksumCacheEntry{key: key, sums: make(map[HashAlgorithm]string, len(sums))}
+	for algorithm, sum := range sums {
+		entry.sums[algorithm] = sum
+	}
+	c.items[key] = c.order.PushFront(entry)
+	c.byPath[key.path] = key
+
+	for c.order.Len() > c.max {
+		oldest := c.order.Back()
+		evicted := oldest.Value.(*checksumCacheEntry).key
+		c.order.Remove(oldest)
+		delete(c.items, evicted)
+		delete(c.byPath, evicted.path)
+	}
+}
+
+// fileChecksums 计算已处理路径的校验和，缓存中已有的算法不再计算，缺少的算法在一次读取中同时计算
+// 读取期间文件发生变化时结果不写入缓存
+func (s *service) fileChecksums(ctx context.Context, processedPath string, algorithms []HashAlgorithm) (map[HashAlgorithm]string, os.FileInfo, bool, error) {
+	f, err := os.Open(processedPath)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, nil, false, err
+	}
+	if info.IsDir() {
+		return nil, nil, false, fmt.Errorf("path is a directory: %s", processedPath)
+	}
+
+	key := newChecksumKey(processedPath, info)
+	sums := s.checksums.get(key)
+	if sums == nil {
+		sums = make(map[HashAlgorithm]string)
+	}
+	hashes := make(map[HashAlgorithm]hash.Hash)
+	var writers []io.Writer
+	for _, algorithm := range algorithms {
+		if _, ok := sums[algorithm]; ok {
+			continue
+		}
+		h, err := newHash(algorithm)
+		if err != nil {
+			return nil, nil, false, err
+		}
+		hashes[algorithm] = h
+		writers = append(writers, h)
+	}
+
+	result := make(map[HashAlgorithm]string, len(algorithms))
+	if len(hashes) == 0 {
+		for _, algorithm := range algorithms {
+			result[algorithm] = sums[algorithm]
+		}
+		return result, info, true, nil
+	}
+
+	if _, err := copyContext(ctx, io.MultiWriter(writers...), f, nil); err != nil {
+		return nil, nil, false, err
+	}
+	computed := make(map[HashAlgorithm]string, len(hashes))
+	for algorithm, h := range hashes {
+		computed[algorithm] = hex.EncodeToString(h.Sum(nil))
+	}
+	if after, err := os.Stat(processedPath); err == nil && newChecksumKey(processedPath, after) == key {
+		s.checksums.put(key, computed)
+	}
+
+	for _, algorithm := range algorithms {
+		if sum, ok := computed[algorithm]; ok {
+			result[algorithm] = sum
+		} else {
+			result[algorithm] = sums[algorithm]
+		}
+	}
+	return result, info, false, nil
+}
+
+// Checksum 实现 Service 接口的 Checksum 方法
+func (s *service) Checksum(ctx context.Context, path string, algorithms []HashAlgorithm) (ChecksumResult, error) {
+	processedPath, err := s.pathProcessor.ProcessPath(path)
+	if err != nil {
+		return ChecksumResult{}, err
+	}
+
+	sums, info, cached, err := s.fileChecksums(ctx, processedPath, algorithms)
+	if err != nil {
+		return ChecksumResult{}, err
+	}
+	return ChecksumResult{
+		Path:      path,
+		Size:      info.Size(),
+		ModTime:   info.ModTime(),
+		Checksums: sums,
+		Cached:    cached,
+	}, nil
+}
+
+// ChecksumManifest 实现 Service 接口的 ChecksumManifest 方法
+// 按名称顺序深度优先遍历，只包含普通文件，不跟随符号链接；无法读取的文件被跳过并计入 Failed
+func (s *service) ChecksumManifest(ctx context.Context, path string, algorithm HashAlgorithm, fn func(ManifestEntry) error) (ManifestSummary, error) {
+	var summary ManifestSummary
+	if _, err := newHash(algorithm); err != nil {
+		return summary, err
+	}
+
+	processedPath, err := s.pathProcessor.ProcessPath(path)
+	if err != nil {
+		return summary, err
+	}
+	if info, err := os.Stat(processedPath); err != nil {
+		return summary, err
+	} else if !info.IsDir() {
+		return summary, fmt.Errorf("path is not a directory: %s", path)
+	}
+
+	err = walkTree(ctx, processedPath, walkOptions{reserved: s.pathProcessor.Reserved}, func(dir string, entry os.DirEntry, rel string) error {
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		sums, _, _, err := s.fileChecksums(ctx, filepath.Join(dir, entry.Name()), []HashAlgorithm{algorithm})
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			summary.Failed++
+			return nil
+		}
+		summary.Files++
+		return fn(ManifestEntry{Path: filepath.ToSlash(rel), Checksum: sums[algorithm]})
+	})
+	return summary, err
+}
+
+// CachedChecksum 实现 Service 接口的 CachedChecksum 方法
+func (s *service) CachedChecksum(ctx context.Context, path string, info FileInfo, algorithm HashAlgorithm) (string, bool) {
+	processedPath, err := s.pathProcessor.ProcessPath(path)
+	if err != nil {
+		return "", false
+	}
+	key := checksumKey{path: processedPath, size: info.Size, modTime: info.ModTime.UnixNano(), inode: info.Inode}
+	sum, ok := s.checksums.get(key)[algorithm]
+	return sum, ok
+}
+
+// FormatManifestLine 按 sha256sum 等工具的格式输出一行：校验和、两个空格、路径
+// 路径包含反斜杠、换行或回车时与 coreutils 一致：行首加反斜杠并对这些字符转义
+func FormatManifestLine(entry ManifestEntry) string {
+	if !strings.ContainsAny(entry.Path, "\\\n\r") {
+		return entry.Checksum + "  " + entry.Path + "\n"
+	}
+	escaped := strings.NewReplacer("\\", "\\\\", "\n", "\\n", "\r", "\\r").Replace(entry.Path)
+	return "\\" + entry.Checksum + "  " + escaped + "\n"
+}
@@ -0,0 +1,305 @@
+package file
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// maxReportedErrors CopyResult 中最多记录的错误条数
+const maxReportedErrors = 100
+
+// CopyOptions 复制选项
+type CopyOptions struct {
+	Conflict       ConflictPolicy // 冲突策略
+	FollowSymlinks bool           // 为 true 时复制符号链接指向的内容，否则复制链接本身
+	Progress       *Progress      // 可选，用于报告进度
+}
+
+// CopyResult 复制结果统计
+type CopyResult struct {
+	Copied  int      `json:"copied"`           // 成功复制的条目数
+	Skipped int      `json:"skipped"`          // 因冲突策略跳过的条目数
+	Failed  int      `json:"failed"`           // 复制失败的条目数
+	Errors  []string `json:"errors,omitempty"` // 失败原因
+}
+
+// addError 记录一个失败的条目
+func (r *CopyResult) addError(path string, err error) {
+	r.Failed++
+	if len(r.Errors) < maxReportedErrors {
+		r.Errors = append(r.Errors, fmt.Sprintf("%s: %v", path, err))
+	}
+}
+
+// copier 递归复制器
+type copier struct {
+	ctx    context.Context
+	opts   CopyOptions
+	result CopyResult
+	// target 顶层条目实际写入的路径，冲突策略为 rename 时可能与 dst 不同
+	target string
+	// visiting 记录跟随符号链接时当前递归路径上的目录，用于检测循环
+	visiting map[string]bool
+	// pathProcessor 跟随符号链接时用于检查链接目标是否位于根目录内
+	pathProcessor *PathProcessor
+	// saveVersion 可选，覆盖已存在的目标前调用，用于保存其历史版本
+	saveVersion func(path string) error
+	// hashes 可选，非 nil 时记录复制过程中读到的每个普通文件内容的 SHA-256，键为目标路径
+	hashes map[string]string
+}
+
+// newCopier 创建复制器
+func newCopier(ctx context.Context, opts CopyOptions, pathProcessor *PathProcessor) *copier {
+	return &copier{
+		ctx:           ctx,
+		opts:          opts,
+		visiting:      make(map[string]bool),
+		pathProcessor: pathProcessor,
+	}
+}
+
+// isSubPath 判断 path 是否等于 parent 或位于 parent 之下
+func isSubPath(parent, path string) bool {
+	rel, err := filepath.Rel(filepath.Clean(parent), filepath.Clean(path))
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
+
+// copyEntry 复制单个条目，目录会递归复制
+// 目标已存在时：两者均为目录则合并；否则按冲突策略处理
+func (c *copier) copyEntry(src, dst string) error {
+	if err := c.ctx.Err(); err != nil {
+		return err
+	}
+	defer c.opts.Progress.AddEntries(1)
+
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+
+	if info.Mode()&os.ModeSymlink != 0 && c.opts.FollowSymlinks {
+		if _, err := c.pathProcessor.ProcessPath(src); err != nil {
+			return err
+		}
+		if info, err = os.Stat(src); err != nil {
+			return err
+		}
+	}
+
+	if dstInfo, err := os.Lstat(dst); err == nil {
+		mergeDirs := info.IsDir() && dstInfo.IsDir()
+		switch {
+		case mergeDirs && (c.opts.Conflict == ConflictOverwrite || c.opts.Conflict == ConflictSkip):
+			// 合并到已存在的目录
+		case c.opts.Conflict == ConflictSkip:
+			c.result.Skipped++
+			return nil
+		case c.opts.Conflict == ConflictOverwrite:
+			if c.saveVersion != nil {
+				if err := c.saveVersion(dst); err != nil {
+					return err
+				}
+			}
+			if dstInfo.IsDir() != info.IsDir() {
+				if err := os.RemoveAll(dst); err != nil {
+					return err
+				}
+			}
+		case c.opts.Conflict == ConflictRename:
+			if dst, err = uniquePath(dst); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("file already exists: %s", dst)
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	if c.target == "" {
+		c.target = dst
+	}
+
+	switch {
+	case info.IsDir():
+		return c.copyDir(src, dst, info)
+	case info.Mode()&os.ModeSymlink != 0:
+		return c.copySymlink(src, dst)
+	case info.Mode().IsRegular():
+		return c.copyFile(src, dst, info)
+	default:
+		return fmt.Errorf("unsupported file type: %s", info.Mode().Type())
+	}
+}
+
+// copyDir 递归复制目录，单个子条目失败不会中断整个复制
+func (c *copier) copyDir(src, dst string, info os.FileInfo) error {
+	realPath, err := filepath.EvalSymlinks(src)
+	if err != nil {
+		return err
+	}
+	if c.visiting[realPath] {
+		return fmt.Errorf("symlink loop detected: %s", src)
+	}
+	c.visiting[realPath] = true
+	defer delete(c.visiting, realPath)
+
+	// 先以可写权限创建，复制完子条目后再恢复原始权限；合并到已存在的目录时保留其原有属性
+	_, statErr := os.Lstat(dst)
+	created := os.IsNotExist(statErr)
+	if err := os.MkdirAll(dst, 0700|info.Mode().Perm()); err != nil {
+		return err
+	}
+
+	entries, err := readDir(c.ctx, src)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		childSrc := filepath.Join(src, entry.Name())
+		childDst := filepath.Join(dst, entry.Name())
+		if err := c.copyEntry(childSrc, childDst); err != nil {
+			if ctxErr := c.ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			c.result.addError(childSrc, err)
+		}
+	}
+
+	if created {
+		if err := copyXattrs(src, dst); err != nil {
+			return err
+		}
+		if err := os.Chmod(dst, info.Mode().Perm()|info.Mode()&(os.ModeSetuid|os.ModeSetgid|os.ModeSticky)); err != nil {
+			return err
+		}
+		if err := os.Chtimes(dst, time.Time{}, info.ModTime()); err != nil {
+			return err
+		}
+		c.result.Copied++
+	}
+	return nil
+}
+
+// copySymlink 复制符号链接本身
+func (c *copier) copySymlink(src, dst string) error {
+	target, err := os.Readlink(src)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Lstat(dst); err == nil {
+		if err := os.Remove(dst); err != nil {
+			return err
+		}
+	}
+	if err := os.Symlink(target, dst); err != nil {
+		return err
+	}
+	c.result.Copied++
+	return nil
+}
+
+// copyFile 复制普通文件，保留权限、修改时间和扩展属性
+// 内容先写入目标目录下的临时文件，完成后原子地替换目标
+func (c *copier) copyFile(src, dst string, info os.FileInfo) error {
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer srcFile.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".copy-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	var w io.Writer = tmp
+	var h hash.Hash
+	if c.hashes != nil {
+		h = sha256.New()
+		w = io.MultiWriter(tmp, h)
+	}
+	_, err = copyContext(c.ctx, w, srcFile, c.opts.Progress)
+	if err == nil {
+		// 在收紧权限之前复制扩展属性，设置 user.* 属性需要写权限
+		err = copyXattrs(src, tmpPath)
+	}
+	if err == nil {
+		err = tmp.Chmod(info.Mode().Perm() | info.Mode()&(os.ModeSetuid|os.ModeSetgid|os.ModeSticky))
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := os.Chtimes(tmpPath, time.Time{}, info.ModTime()); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, dst); err != nil {
+		return err
+	}
+	if h != nil {
+		c.hashes[dst] = hex.EncodeToString(h.Sum(nil))
+	}
+	c.result.Copied++
+	return nil
+}
+
+// Copy 实现 Service 接口的 Copy 方法
+func (s *service) Copy(ctx context.Context, src, dst string, opts CopyOptions) (CopyResult, error) {
+	processSrc := s.pathProcessor.ProcessPathNoFollow
+	if opts.FollowSymlinks {
+		processSrc = s.pathProcessor.ProcessPath
+	}
+	processedSrc, err := processSrc(src)
+	if err != nil {
+		return CopyResult{}, err
+	}
+
+	processedDst, err := s.pathProcessor.ProcessPath(dst)
+	if err != nil {
+		return CopyResult{}, err
+	}
+
+	if _, err := os.Lstat(processedSrc); err != nil {
+		return CopyResult{}, err
+	}
+	if isSubPath(processedSrc, processedDst) {
+		return CopyResult{}, fmt.Errorf("cannot copy %s into itself", src)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(processedDst), 0755); err != nil {
+		return CopyResult{}, fmt.Errorf("failed to create parent directory: %v", err)
+	}
+
+	measureTree(ctx, processedSrc, opts.FollowSymlinks, opts.Progress)
+
+	c := newCopier(ctx, opts, s.pathProcessor)
+	c.saveVersion = func(path string) error { return s.saveVersion(ctx, path, VersionOverwrite) }
+	err = c.copyEntry(processedSrc, processedDst)
+	if c.target != "" {
+		s.notify(ChangeEvent{Op: ChangeWrite, Path: c.target})
+	}
+	if err != nil {
+		return c.result, err
+	}
+	// 部分条目失败时已复制的内容保留在目标位置，统计随错误一起返回
+	if c.result.Failed > 0 {
+		return c.result, fmt.Errorf("copy incomplete: %d entries failed, first error: %s", c.result.Failed, c.result.Errors[0])
+	}
+	return c.result, nil
+}
@@ -0,0 +1,44 @@
+package file
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCopyPartialFailure(t *testing.T) {
+	root := t.TempDir()
+	s := newTestService(t, root)
+	src := filepath.Join(root, "src")
+	writeFile(t, filepath.Join(src, "a.txt"), "a")
+	writeFile(t, filepath.Join(src, "sub", "b.txt"), "b")
+	// 跟随符号链接时悬空链接无法复制
+	if err := os.Symlink(filepath.Join(root, "missing"), filepath.Join(src, "broken")); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := filepath.Join(root, "dst")
+	result, err := s.Copy(context.Background(), src, dst, CopyOptions{FollowSymlinks: true})
+	if err == nil || !strings.Contains(err.Error(), "1 entries failed") {
+		t.Fatalf("Copy() error = %v, want partial failure", err)
+	}
+	if result.Failed != 1 || len(result.Errors) != 1 || !strings.Contains(result.Errors[0], "broken") {
+		t.Errorf("Copy() result = %+v, want one failed entry", result)
+	}
+	if result.Copied == 0 {
+		t.Errorf("Copy() copied nothing")
+	}
+	// 其余条目仍被复制
+	if data, err := os.ReadFile(filepath.Join(dst, "sub", "b.txt")); err != nil || string(data) != "b" {
+		t.Errorf("dst/sub/b.txt = %q, %v", data, err)
+	}
+
+	// 没有失败的条目时不返回错误
+	os.Remove(filepath.Join(src, "broken"))
+	result, err = s.Copy(context.Background(), src, filepath.Join(root, "dst2"), CopyOptions{})
+	if err != nil || result.Failed != 0 {
+		t.Errorf("Copy() = %+v, %v; want success", result, err)
+	}
+}
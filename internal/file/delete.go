@@ -0,0 +1,81 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// DeleteOptions 删除选项
+type DeleteOptions struct {
+	Permanent bool      // 永久删除，不移入回收站；回收站未启用时总是永久删除
+	Progress  *Progress // 可选，用于报告进度
+}
+
+// removeTree 递归删除路径，删除每个条目前检查 ctx 是否已取消，删除后更新进度
+func removeTree(ctx context.Context, path string, progress *Progress) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	info, err := os.Lstat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	if info.IsDir() {
+		entries, err := readDir(ctx, path)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if err := removeTree(ctx, filepath.Join(path, entry.Name()), progress); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if info.Mode().IsRegular() {
+		progress.AddBytes(info.Size())
+	}
+	progress.AddEntries(1)
+	return nil
+}
+
+// Delete 实现 Service 接口的 Delete 方法
+// 默认移入回收站并返回回收站条目；永久删除时返回 nil
+func (s *service) Delete(ctx context.Context, path string, opts DeleteOptions) (*TrashItem, error) {
+	processedPath, err := s.pathProcessor.ProcessPathNoFollow(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// 检查文件是否存在
+	info, err := os.Lstat(processedPath)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("file or directory does not exist: %s", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// 移入回收站的内容由回收站保留，只在永久删除时保存版本
+	if s.config.Trash.Enabled && !opts.Permanent {
+		return s.moveToTrash(ctx, processedPath, info, opts.Progress)
+	}
+	if err := s.saveVersion(ctx, processedPath, VersionDelete); err != nil {
+		return nil, err
+	}
+
+	measureTree(ctx, processedPath, false, opts.Progress)
+	err = removeTree(ctx, processedPath, opts.Progress)
+	s.notify(ChangeEvent{Op: ChangeRemove, Path: processedPath})
+	return nil, err
+}
@@ -0,0 +1,232 @@
+package file
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxDiffEdits Myers 算法允许的最大编辑距离，超出后把剩余的差异部分视为整体替换
+// 回溯需要保存每一步的状态，内存占用与编辑距离的平方成正比
+const maxDiffEdits = 2000
+
+// diffKind 差异操作类型
+type diffKind byte
+
+const (
+	diffEqual  diffKind = ' '
+	diffDelete diffKind = '-'
+	diffInsert diffKind = '+'
+)
+
+// diffOp 一行差异，oldPos 和 newPos 为该行之前两侧已经经过的行数
+type diffOp struct {
+	kind   diffKind
+	line   string
+	oldPos int
+	newPos int
+}
+
+// splitLines 按行拆分文本，每行保留行尾的换行符，最后一行可能没有换行符
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines 计算从 a 到 b 的逐行编辑序列
+// 先去掉公共的前缀和后缀，再对中间部分使用 Myers 算法
+func diffLines(a, b []string) []diffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]diffOp, 0, len(a)+len(b))
+	for i := 0; i < prefix; i++ {
+		ops = append(ops, diffOp{kind: diffEqual, line: a[i], oldPos: i, newPos: i})
+	}
+	middle := myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])
+	for _, op := range middle {
+		op.oldPos += prefix
+		op.newPos += prefix
+		ops = append(ops, op)
+	}
+	for i := 0; i < suffix; i++ {
+		oldPos, newPos := len(a)-suffix+i, len(b)-suffix+i
+		ops = append(ops, diffOp{kind: diffEqual, line: a[oldPos], oldPos: oldPos, newPos: newPos})
+	}
+	return ops
+}
+
+// myers 使用 Myers 贪心算法计算最短编辑序列，编辑距离超过 maxDiffEdits 时返回整体替换
+func myers(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	max := n + m
+	if max == 0 {
+		return nil
+	}
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	// trace[d] 保存第 d 步开始前 v 在 [-d, d] 范围内的值
+	var trace [][]int
+
+	for d := 0; d <= max && d <= maxDiffEdits; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(a, b, trace)
+			}
+		}
+	}
+	return replaceAll(a, b)
+}
+
+// backtrack 根据每一步保存的状态从终点回溯出编辑序列
+func backtrack(a, b []string, trace [][]int) []diffOp {
+	x, y := len(a), len(b)
+	var reversed []diffOp
+	for d := len(trace) - 1; d >= 0; d-- {
+		at := func(k int) int { return trace[d][k+d] }
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := 0
+		if d > 0 {
+			prevX = at(prevK)
+		}
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			reversed = append(reversed, diffOp{kind: diffEqual, line: a[x], oldPos: x, newPos: y})
+		}
+		if d == 0 {
+			break
+		}
+		if x == prevX {
+			y--
+			reversed = append(reversed, diffOp{kind: diffInsert, line: b[y], oldPos: x, newPos: y})
+		} else {
+			x--
+			reversed = append(reversed, diffOp{kind: diffDelete, line: a[x], oldPos: x, newPos: y})
+		}
+	}
+
+	ops := make([]diffOp, len(reversed))
+	for i, op := range reversed {
+		ops[len(reversed)-1-i] = op
+	}
+	return ops
+}
+
+// replaceAll 删除 a 的全部行并插入 b 的全部行
+func replaceAll(a, b []string) []diffOp {
+	ops := make([]diffOp, 0, len(a)+len(b))
+	for i, line := range a {
+		ops = append(ops, diffOp{kind: diffDelete, line: line, oldPos: i, newPos: 0})
+	}
+	for i, line := range b {
+		ops = append(ops, diffOp{kind: diffInsert, line: line, oldPos: len(a), newPos: i})
+	}
+	return ops
+}
+
+// unifiedDiff 生成 unified 格式的差异，返回差异文本以及新增和删除的行数，内容相同时文本为空
+func unifiedDiff(oldLabel, newLabel string, a, b []string, context int) (string, int, int) {
+	if context < 0 {
+		context = 0
+	}
+	ops := diffLines(a, b)
+
+	var added, removed int
+	var changes []int
+	for i, op := range ops {
+		switch op.kind {
+		case diffInsert:
+			added++
+			changes = append(changes, i)
+		case diffDelete:
+			removed++
+			changes = append(changes, i)
+		}
+	}
+	if len(changes) == 0 {
+		return "", 0, 0
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", oldLabel, newLabel)
+	for i := 0; i < len(changes); {
+		// 相邻差异之间的相同行不超过 2*context 时合并为一个 hunk
+		start := changes[i] - context
+		if start < 0 {
+			start = 0
+		}
+		j := i
+		for j+1 < len(changes) && changes[j+1]-changes[j] <= 2*context+1 {
+			j++
+		}
+		end := changes[j] + context + 1
+		if end > len(ops) {
+			end = len(ops)
+		}
+		writeHunk(&out, ops[start:end])
+		i = j + 1
+	}
+	return out.String(), added, removed
+}
+
+// writeHunk 写入一个 hunk，行数为 0 的一侧起始行号为其前一行
+func writeHunk(out *strings.Builder, ops []diffOp) {
+	var oldLen, newLen int
+	for _, op := range ops {
+		if op.kind != diffInsert {
+			oldLen++
+		}
+		if op.kind != diffDelete {
+			newLen++
+		}
+	}
+	oldStart, newStart := ops[0].oldPos, ops[0].newPos
+	if oldLen > 0 {
+		oldStart++
+	}
+	if newLen > 0 {
+		newStart++
+	}
+
+	fmt.Fprintf(out, "@@ -%d,%d +%d,%d @@\n", oldStart, oldLen, newStart, newLen)
+	for _, op := range ops {
+		out.WriteByte(byte(op.kind))
+		out.WriteString(op.line)
+		if !strings.HasSuffix(op.line, "\n") {
+			out.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package file
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitLines(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"", nil},
+		{"a", []string{"a"}},
+		{"a\n", []string{"a\n"}},
+		{"a\nb", []string{"a\n", "b"}},
+		{"\n\n", []string{"\n", "\n"}},
+	}
+	for _, tt := range tests {
+		if got := splitLines(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitLines(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name        string
+		a, b        string
+		context     int
+		want        string
+		wantAdded   int
+		wantRemoved int
+	}{
+		{
+			name: "identical",
+			a:    "a\nb\n",
+			b:    "a\nb\n",
+			want: "",
+		},
+		{
+			name:      "change and append",
+			a:         "a=1\nb=2\nc=3\n",
+			b:         "a=1\nb=20\nc=3\nd=4\n",
+			context:   DefaultDiffContext,
+			want:      "--- old\n+++ new\n@@ -1,3 +1,4 @@\n a=1\n-b=2\n+b=20\n c=3\n+d=4\n",
+			wantAdded: 2, wantRemoved: 1,
+		},
+		{
+			name:      "from empty",
+			a:         "",
+			b:         "x\ny\n",
+			want:      "--- old\n+++ new\n@@ -0,0 +1,2 @@\n+x\n+y\n",
+			wantAdded: 2,
+		},
+		{
+			name:        "to empty",
+			a:           "x\n",
+			b:           "",
+			want:        "--- old\n+++ new\n@@ -1,1 +0,0 @@\n-x\n",
+			wantRemoved: 1,
+		},
+		{
+			name:      "missing newline at end",
+			a:         "a\nb",
+			b:         "a\nb\n",
+			context:   DefaultDiffContext,
+			want:      "--- old\n+++ new\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+			wantAdded: 1, wantRemoved: 1,
+		},
+		{
+			name:      "separate hunks",
+			a:         "1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			b:         "1\nX\n3\n4\n5\n6\n7\nY\n9\n",
+			context:   1,
+			want:      "--- old\n+++ new\n@@ -1,3 +1,3 @@\n 1\n-2\n+X\n 3\n@@ -7,3 +7,3 @@\n 7\n-8\n+Y\n 9\n",
+			wantAdded: 2, wantRemoved: 2,
+		},
+		{
+			name:      "hunks merged when context overlaps",
+			a:         "1\n2\n3\n4\n5\n",
+			b:         "1\nX\n3\nY\n5\n",
+			context:   1,
+			want:      "--- old\n+++ new\n@@ -1,5 +1,5 @@\n 1\n-2\n+X\n 3\n-4\n+Y\n 5\n",
+			wantAdded: 2, wantRemoved: 2,
+		},
+		{
+			name:      "insertion without context",
+			a:         "1\n2\n",
+			b:         "1\nX\n2\n",
+			context:   0,
+			want:      "--- old\n+++ new\n@@ -1,0 +2,1 @@\n+X\n",
+			wantAdded: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, added, removed := unifiedDiff("old", "new", splitLines(tt.a), splitLines(tt.b), tt.context)
+			if got != tt.want || added != tt.wantAdded || removed != tt.wantRemoved {
+				t.Errorf("unifiedDiff() = %q, +%d -%d\nwant %q, +%d -%d", got, added, removed, tt.want, tt.wantAdded, tt.wantRemoved)
+			}
+		})
+	}
+}
+
+// applyOps 从编辑序列还原两侧的内容
+func applyOps(ops []diffOp) (oldLines, newLines []string) {
+	for _, op := range ops {
+		if op.kind != diffInsert {
+			oldLines = append(oldLines, op.line)
+		}
+		if op.kind != diffDelete {
+			newLines = append(newLines, op.line)
+		}
+	}
+	return oldLines, newLines
+}
+
+func TestDiffLines(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	randomLines := func(n int) []string {
+		var lines []string
+		for i := 0; i < n; i++ {
+			lines = append(lines, fmt.Sprintf("%d\n", rng.Intn(4)))
+		}
+		return lines
+	}
+
+	for i := 0; i < 500; i++ {
+		a, b := randomLines(rng.Intn(12)), randomLines(rng.Intn(12))
+		ops := diffLines(a, b)
+		oldLines, newLines := applyOps(ops)
+		if strings.Join(oldLines, "") != strings.Join(a, "") || strings.Join(newLines, "") != strings.Join(b, "") {
+			t.Fatalf("diffLines(%q, %q) = %v does not reproduce both sides", a, b, ops)
+		}
+	}
+
+	// 最短编辑序列：ABCABBA -> CBABAC 的编辑距离为 5
+	ops := diffLines(splitLines("A\nB\nC\nA\nB\nB\nA\n"), splitLines("C\nB\nA\nB\nA\nC\n"))
+	edits := 0
+	for _, op := range ops {
+		if op.kind != diffEqual {
+			edits++
+		}
+	}
+	if edits != 5 {
+		t.Errorf("edit distance = %d, want 5", edits)
+	}
+
+	// 编辑距离超过上限时整体替换中间部分，结果仍然正确
+	var a, b []string
+	for i := 0; i < maxDiffEdits+10; i++ {
+		a = append(a, fmt.Sprintf("a%d\n", i))
+		b = append(b, fmt.Sprintf("b%d\n", i))
+	}
+	a = append([]string{"same\n"}, a...)
+	b = append([]string{"same\n"}, b...)
+	ops = diffLines(a, b)
+	oldLines, newLines := applyOps(ops)
+	if !reflect.DeepEqual(oldLines, a) || !reflect.DeepEqual(newLines, b) || ops[0].kind != diffEqual {
+		t.Error("diffLines() fallback does not reproduce both sides")
+	}
+}
@@ -0,0 +1,517 @@
+package file
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultDuplicateGroups 默认最多返回的重复组数
+	DefaultDuplicateGroups = 1000
+	// MaxDuplicateGroups 允许返回的最大重复组数
+	MaxDuplicateGroups = 10000
+
+	// partialHashSize 部分哈希读取文件开头和结尾各这么多字节
+	partialHashSize = 16 * 1024
+)
+
+// DedupeAction 重复文件的处理方式
+type DedupeAction string
+
+const (
+	DedupeHardLink DedupeAction = "hardlink" // 用指向保留文件的硬链接替换其余副本
+	DedupeDelete   DedupeAction = "delete"   // 删除其余副本
+)
+
+// ParseDedupeAction 解析重复文件的处理方式
+func ParseDedupeAction(value string) (DedupeAction, error) {
+	switch action := DedupeAction(strings.ToLower(value)); action {
+	case DedupeHardLink, DedupeDelete:
+		return action, nil
+	default:
+		return "", fmt.Errorf("invalid dedupe action: %s", value)
+	}
+}
+
+// KeepPolicy 每组中保留哪个文件
+type KeepPolicy string
+
+const (
+	KeepFirst  KeepPolicy = "first"  // 路径按字典序排在最前的文件
+	KeepOldest KeepPolicy = "oldest" // 修改时间最早的文件
+	KeepNewest KeepPolicy = "newest" // 修改时间最晚的文件
+)
+
+// ParseKeepPolicy 解析保留策略，空字符串表示 first
+func ParseKeepPolicy(value string) (KeepPolicy, error) {
+	switch policy := KeepPolicy(strings.ToLower(value)); policy {
+	case "":
+		return KeepFirst, nil
+	case KeepFirst, KeepOldest, KeepNewest:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("invalid keep policy: %s", value)
+	}
+}
+
+// DuplicateOptions 重复文件查找选项
+type DuplicateOptions struct {
+	MinSize    int64     // 只比较不小于此大小的文件，空文件总是被忽略
+	Include    []string  // 只比较匹配这些通配符的文件
+	Exclude    []string  // 排除匹配这些通配符的条目，被排除的目录不会继续遍历
+	MaxDepth   int       // 最大遍历深度，0 表示不限制
+	HideHidden bool      // 是否跳过以 "." 开头的条目
+	MaxGroups  int       // 最多返回的重复组数，0 表示使用 DefaultDuplicateGroups；统计总是覆盖所有组
+	Progress   *Progress // 进度，可为 nil
+}
+
+// DedupeOptions 重复文件处理选项
+type DedupeOptions struct {
+	DuplicateOptions
+	Action DedupeAction // 处理方式
+	Keep   KeepPolicy   // 保留策略
+}
+
+// DuplicateFile 重复组中的一个文件
+type DuplicateFile struct {
+	Path    string    `json:"path"`            // 文件路径
+	ModTime time.Time `json:"modTime"`         // 修改时间
+	Inode   uint64    `json:"inode,omitempty"` // inode 编号，相同的文件互为硬链接
+}
+
+// DuplicateGroup 内容相同的一组文件
+type DuplicateGroup struct {
+	Size     int64           `json:"size"`     // 单个文件大小
+	Checksum string          `json:"checksum"` // SHA-256 校验和
+	Files    []DuplicateFile `json:"files"`    // 按路径排序的文件
+	Wasted   int64           `json:"wasted"`   // 可回收的空间：互为硬链接的文件只计算一次
+}
+
+// DuplicateReport 重复文件查找结果
+type DuplicateReport struct {
+	Groups         []DuplicateGroup `json:"groups"`         // 按可回收空间从大到小排序
+	Scanned        int              `json:"scanned"`        // 参与比较的普通文件数
+	DuplicateFiles int              `json:"duplicateFiles"` // 多余的副本数，不含每组保留的一份
+	WastedSpace    int64            `json:"wastedSpace"`    // 所有组可回收的空间
+	Failed         int              `json:"failed"`         // 无法读取而被跳过的文件数
+	Truncated      bool             `json:"truncated"`      // 是否因组数达到上限省略了部分组
+}
+
+// DedupeResult 重复文件处理结果
+type DedupeResult struct {
+	Groups    int      `json:"groups"`           // 处理的重复组数
+	Linked    int      `json:"linked"`           // 被替换为硬链接的文件数
+	Deleted   int      `json:"deleted"`          // 被删除的文件数
+	Skipped   int      `json:"skipped"`          // 扫描后发生变化而跳过的文件数
+	Failed    int      `json:"failed"`           // 处理失败的文件数
+	Reclaimed int64    `json:"reclaimed"`        // 回收的空间，不含移入回收站的文件
+	Errors    []string `json:"errors,omitempty"` // 失败原因
+}
+
+// addError 记录一个失败的文件
+func (r *DedupeResult) addError(path string, err error) {
+	r.Failed++
+	if len(r.Errors) < maxReportedErrors {
+		r.Errors = append(r.Errors, fmt.Sprintf("%s: %v", path, err))
+	}
+}
+
+// fileIdentity 文件数据的标识，设备和 inode 相同的路径互为硬链接
+// 平台不提供 inode 编号时以路径区分
+type fileIdentity struct {
+	dev  uint64
+	ino  uint64
+	path string
+}
+
+// dupCandidate 参与比较的文件
+type dupCandidate struct {
+	path string
+	info os.FileInfo
+	id   fileIdentity
+}
+
+// dupGroup 内容相同的候选文件，inodes 中每项为一组互为硬链接的文件
+type dupGroup struct {
+	size     int64
+	checksum string
+	inodes   [][]dupCandidate
+}
+
+// newDupCandidate 构造候选文件
+func newDupCandidate(path string, info os.FileInfo) dupCandidate {
+	id := fileIdentity{dev: fileDevice(info), ino: fileInode(info)}
+	if id.ino == 0 {
+		id.path = path
+	}
+	return dupCandidate{path: path, info: info, id: id}
+}
+
+// groupByIdentity 将互为硬链接的文件合并为一项，保持首次出现的顺序
+func groupByIdentity(files []dupCandidate) [][]dupCandidate {
+	index := make(map[fileIdentity]int)
+	var inodes [][]dupCandidate
+	for _, f := range files {
+		if i, ok := index[f.id]; ok {
+			inodes[i] = append(inodes[i], f)
+			continue
+		}
+		index[f.id] = len(inodes)
+		inodes = append(inodes, []dupCandidate{f})
+	}
+	return inodes
+}
+
+// partialHash 计算文件开头和结尾各 partialHashSize 字节的哈希，用于在读取全部内容之前排除大部分不同的文件
+func partialHash(path string, size int64) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, io.LimitReader(f, partialHashSize)); err != nil {
+		return "", err
+	}
+	if size > 2*partialHashSize {
+		if _, err := io.Copy(h, io.NewSectionReader(f, size-partialHashSize, partialHashSize)); err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// findDuplicates 扫描 processedPath 下的子树，依次按大小、部分哈希和完整哈希分组
+// 每一步只保留至少包含两个不同 inode 的组，已经互为硬链接的文件不会被重复读取
+func (s *service) findDuplicates(ctx context.Context, processedPath string, opts DuplicateOptions) ([]dupGroup, DuplicateReport, error) {
+	var report DuplicateReport
+
+	info, err := os.Stat(processedPath)
+	if err != nil {
+		return nil, report, err
+	}
+	if !info.IsDir() {
+		return nil, report, fmt.Errorf("path is not a directory: %s", processedPath)
+	}
+	filter, err := newPathFilter(opts.Include, opts.Exclude)
+	if err != nil {
+		return nil, report, err
+	}
+	minSize := opts.MinSize
+	if minSize < 1 {
+		minSize = 1
+	}
+
+	bySize := make(map[int64][]dupCandidate)
+	walkOpts := walkOptions{maxDepth: opts.MaxDepth, hideHidden: opts.HideHidden, filter: filter, reserved: s.pathProcessor.Reserved}
+	err = walkTree(ctx, processedPath, walkOpts, func(dir string, entry os.DirEntry, rel string) error {
+		// 回收站中的文件已被删除，不参与查找和处理
+		if entry.IsDir() && s.trash.contains(filepath.Join(dir, entry.Name())) {
+			return errSkipDir
+		}
+		if !entry.Type().IsRegular() || !filter.included(entry.Name(), rel) {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil || info.Size() < minSize {
+			return nil
+		}
+		report.Scanned++
+		bySize[info.Size()] = append(bySize[info.Size()], newDupCandidate(filepath.Join(dir, entry.Name()), info))
+		return nil
+	})
+	if err != nil {
+		return nil, report, err
+	}
+
+	// 按部分哈希细分大小相同的文件
+	var candidates []dupGroup
+	for size, files := range bySize {
+		inodes := groupByIdentity(files)
+		if len(inodes) < 2 {
+			continue
+		}
+		byPartial := make(map[string][][]dupCandidate)
+		var order []string
+		for _, linked := range inodes {
+			if err := ctx.Err(); err != nil {
+				return nil, report, err
+			}
+			sum, err := partialHash(linked[0].path, size)
+			if err != nil {
+				report.Failed += len(linked)
+				continue
+			}
+			if _, ok := byPartial[sum]; !ok {
+				order = append(order, sum)
+			}
+			byPartial[sum] = append(byPartial[sum], linked)
+		}
+		for _, sum := range order {
+			if len(byPartial[sum]) >= 2 {
+				candidates = append(candidates, dupGroup{size: size, inodes: byPartial[sum]})
+			}
+		}
+	}
+
+	var bytesTotal, entriesTotal int64
+	for _, group := range candidates {
+		bytesTotal += group.size * int64(len(group.inodes))
+		entriesTotal += int64(len(group.inodes))
+	}
+	opts.Progress.SetTotal(bytesTotal, entriesTotal)
+
+	// 按完整哈希确认，结果进入校验和缓存，后续查找或下载时可以复用
+	var groups []dupGroup
+	for _, group := range candidates {
+		byFull := make(map[string][][]dupCandidate)
+		var order []string
+		for _, linked := range group.inodes {
+			sums, _, _, err := s.fileChecksums(ctx, linked[0].path, []HashAlgorithm{HashSHA256})
+			opts.Progress.AddBytes(group.size)
+			opts.Progress.AddEntries(1)
+			if err != nil {
+				if ctxErr := ctx.Err(); ctxErr != nil {
+					return nil, report, ctxErr
+				}
+				report.Failed += len(linked)
+				continue
+			}
+			sum := sums[HashSHA256]
+			if _, ok := byFull[sum]; !ok {
+				order = append(order, sum)
+			}
+			byFull[sum] = append(byFull[sum], linked)
+		}
+		for _, sum := range order {
+			if len(byFull[sum]) >= 2 {
+				groups = append(groups, dupGroup{size: group.size, checksum: sum, inodes: byFull[sum]})
+			}
+		}
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		wi, wj := groups[i].wasted(), groups[j].wasted()
+		if wi != wj {
+			return wi > wj
+		}
+		return groups[i].inodes[0][0].path < groups[j].inodes[0][0].path
+	})
+	for _, group := range groups {
+		report.DuplicateFiles += len(group.inodes) - 1
+		report.WastedSpace += group.wasted()
+	}
+	return groups, report, nil
+}
+
+// wasted 返回组内可回收的空间
+func (g dupGroup) wasted() int64 {
+	return g.size * int64(len(g.inodes)-1)
+}
+
+// toDuplicateGroup 转换为按路径排序的 DuplicateGroup
+func (g dupGroup) toDuplicateGroup() DuplicateGroup {
+	group := DuplicateGroup{Size: g.size, Checksum: g.checksum, Wasted: g.wasted()}
+	for _, linked := range g.inodes {
+		for _, f := range linked {
+			group.Files = append(group.Files, DuplicateFile{Path: f.path, ModTime: f.info.ModTime(), Inode: f.id.ino})
+		}
+	}
+	sort.Slice(group.Files, func(i, j int) bool { return group.Files[i].Path < group.Files[j].Path })
+	return group
+}
+
+// FindDuplicates 实现 Service 接口的 FindDuplicates 方法
+func (s *service) FindDuplicates(ctx context.Context, path string, opts DuplicateOptions) (DuplicateReport, error) {
+	processedPath, err := s.pathProcessor.ProcessPath(path)
+	if err != nil {
+		return DuplicateReport{}, err
+	}
+
+	groups, report, err := s.findDuplicates(ctx, processedPath, opts)
+	if err != nil {
+		return DuplicateReport{}, err
+	}
+
+	maxGroups := opts.MaxGroups
+	if maxGroups <= 0 {
+		maxGroups = DefaultDuplicateGroups
+	}
+	if maxGroups > MaxDuplicateGroups {
+		maxGroups = MaxDuplicateGroups
+	}
+	if len(groups) > maxGroups {
+		groups = groups[:maxGroups]
+		report.Truncated = true
+	}
+	report.Groups = make([]DuplicateGroup, 0, len(groups))
+	for _, group := range groups {
+		report.Groups = append(report.Groups, group.toDuplicateGroup())
+	}
+	return report, nil
+}
+
+// Deduplicate 实现 Service 接口的 Deduplicate 方法
+// 处理前重新检查每个文件，扫描后被修改、替换或删除的文件会被跳过
+func (s *service) Deduplicate(ctx context.Context, path string, opts DedupeOptions) (DedupeResult, error) {
+	var result DedupeResult
+	if _, err := ParseDedupeAction(string(opts.Action)); err != nil {
+		return result, err
+	}
+	keep, err := ParseKeepPolicy(string(opts.Keep))
+	if err != nil {
+		return result, err
+	}
+
+	processedPath, err := s.pathProcessor.ProcessPath(path)
+	if err != nil {
+		return result, err
+	}
+
+	// 扫描阶段的进度不计入处理阶段
+	progress := opts.Progress
+	opts.Progress = nil
+	groups, _, err := s.findDuplicates(ctx, processedPath, opts.DuplicateOptions)
+	if err != nil {
+		return result, err
+	}
+
+	var total int64
+	for _, group := range groups {
+		total += int64(len(group.inodes) - 1)
+	}
+	progress.SetTotal(0, total)
+	trashed := opts.Action == DedupeDelete && s.config.Trash.Enabled
+
+	for _, group := range groups {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		result.Groups++
+
+		keeper := group.keeper(keep)
+		if !unchanged(keeper[0]) {
+			result.Skipped += len(group.inodes) - 1
+			progress.AddEntries(int64(len(group.inodes) - 1))
+			continue
+		}
+		for _, linked := range group.inodes {
+			if linked[0].id == keeper[0].id {
+				continue
+			}
+			reclaimed := true
+			for _, f := range linked {
+				if err := ctx.Err(); err != nil {
+					return result, err
+				}
+				if !unchanged(f) {
+					result.Skipped++
+					reclaimed = false
+					continue
+				}
+				if err := s.dedupeFile(ctx, f.path, keeper[0].path, opts.Action); err != nil {
+					result.addError(f.path, err)
+					reclaimed = false
+					continue
+				}
+				if opts.Action == DedupeHardLink {
+					result.Linked++
+				} else {
+					result.Deleted++
+				}
+			}
+			// 子树之外仍有硬链接指向同一 inode 时数据不会被释放，移入回收站的文件在清除回收站后才释放
+			if reclaimed && !trashed && fileLinks(linked[0].info) <= uint64(len(linked)) {
+				result.Reclaimed += group.size
+			}
+			progress.AddEntries(1)
+		}
+	}
+	return result, nil
+}
+
+// keeper 按保留策略选出要保留的一组硬链接
+func (g dupGroup) keeper(policy KeepPolicy) []dupCandidate {
+	best := g.inodes[0]
+	for _, linked := range g.inodes[1:] {
+		switch policy {
+		case KeepOldest:
+			if linked[0].info.ModTime().Before(best[0].info.ModTime()) {
+				best = linked
+			}
+		case KeepNewest:
+			if linked[0].info.ModTime().After(best[0].info.ModTime()) {
+				best = linked
+			}
+		default:
+			if minPath(linked) < minPath(best) {
+				best = linked
+			}
+		}
+	}
+	return best
+}
+
+// minPath 返回一组硬链接中字典序最小的路径
+func minPath(linked []dupCandidate) string {
+	path := linked[0].path
+	for _, f := range linked[1:] {
+		if f.path < path {
+			path = f.path
+		}
+	}
+	return path
+}
+
+// unchanged 检查文件自扫描以来是否未被修改、替换或删除
+func unchanged(f dupCandidate) bool {
+	info, err := os.Lstat(f.path)
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+	return info.Size() == f.info.Size() && info.ModTime().Equal(f.info.ModTime()) && newDupCandidate(f.path, info).id == f.id
+}
+
+// dedupeFile 处理一个多余的副本
+// 替换为硬链接时先在同一目录下创建临时链接再重命名，保证替换是原子的；新链接使用保留文件的权限和所有者
+func (s *service) dedupeFile(ctx context.Context, path, keeper string, action DedupeAction) error {
+	if action == DedupeDelete {
+		// 与 Delete 一致，启用回收站时移入回收站，内容由回收站保留
+		if s.config.Trash.Enabled {
+			info, err := os.Lstat(path)
+			if err != nil {
+				return err
+			}
+			_, err = s.moveToTrash(ctx, path, info, nil)
+			return err
+		}
+		if err := s.saveVersion(ctx, path, VersionDelete); err != nil {
+			return err
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		s.notify(ChangeEvent{Op: ChangeRemove, Path: path})
+		return nil
+	}
+
+	tmp := tempSibling(path, "dedupe")
+	if err := os.Link(keeper, tmp); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	s.notify(ChangeEvent{Op: ChangeWrite, Path: path})
+	return nil
+}
@@ -0,0 +1,34 @@
+package file
+
+// ChangeOp 变更类型
+type ChangeOp string
+
+const (
+	ChangeWrite  ChangeOp = "write"  // 创建或修改了文件或目录，目录表示其子树可能有变化
+	ChangeRemove ChangeOp = "remove" // 删除了文件或目录
+	ChangeMove   ChangeOp = "move"   // 文件或目录从 OldPath 移动到 Path
+)
+
+// ChangeEvent 通过 Service 完成的修改操作，路径均为处理后的绝对路径
+// 操作部分失败时也会发送事件，监听方应以磁盘上的实际状态为准
+type ChangeEvent struct {
+	Op      ChangeOp // 变更类型
+	Path    string   // 变更的路径
+	OldPath string   // 移动前的路径，仅 ChangeMove 使用
+}
+
+// Subscribe 实现 Service 接口的 Subscribe 方法
+func (s *service) Subscribe(fn func(ChangeEvent)) {
+	s.listenersMu.Lock()
+	defer s.listenersMu.Unlock()
+	s.listeners = append(s.listeners, fn)
+}
+
+// notify 同步通知所有监听者
+func (s *service) notify(event ChangeEvent) {
+	s.listenersMu.RLock()
+	defer s.listenersMu.RUnlock()
+	for _, fn := range s.listeners {
+		fn(event)
+	}
+}
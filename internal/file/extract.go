@@ -0,0 +1,601 @@
+package file
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/bzip2"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	// ratioMinSize 解压后总大小不超过此值时不检查压缩比，避免误判高度可压缩的小文件
+	ratioMinSize = 1 << 20
+	// maxSymlinkTargetSize 归档中符号链接目标的最大长度
+	maxSymlinkTargetSize = 4096
+)
+
+// ErrArchiveLimit 归档超出解压限制，可能是解压炸弹
+var ErrArchiveLimit = errors.New("archive exceeds extraction limits")
+
+// ExtractOptions 解压选项
+type ExtractOptions struct {
+	Format   ArchiveFormat  // 归档格式，为空时根据扩展名判断
+	Conflict ConflictPolicy // 目标路径已存在时的冲突策略，目录与目录之间在 overwrite 和 skip 策略下合并
+	Progress *Progress      // 进度，可为 nil
+}
+
+// ExtractResult 解压结果
+type ExtractResult struct {
+	Entries int   `json:"entries"` // 归档中解压的条目数
+	Bytes   int64 `json:"bytes"`   // 解压的文件内容字节数
+	Skipped int   `json:"skipped"` // 因冲突策略跳过的条目数
+	Ignored int   `json:"ignored"` // 设备、管道等不支持而被忽略的条目数
+}
+
+// extractLimits 解压限制
+type extractLimits struct {
+	maxEntries int
+	maxSize    int64
+	maxRatio   int64
+	archive    int64 // 归档文件大小
+}
+
+// extractor 一次解压操作，条目先解压到暂存目录，全部成功后再合并到目标目录
+type extractor struct {
+	ctx      context.Context
+	stage    string
+	limits   extractLimits
+	progress *Progress
+	result   ExtractResult
+
+	dirs     []extractedDir
+	symlinks []extractedLink
+}
+
+// extractedDir 解压完成后再设置权限和修改时间的目录，解压期间目录必须保持可写
+type extractedDir struct {
+	path    string
+	mode    os.FileMode
+	modTime time.Time
+}
+
+// extractedLink 解压完成后再创建的符号链接，避免后续条目经由链接写到暂存目录之外
+type extractedLink struct {
+	path    string
+	target  string
+	modTime time.Time
+}
+
+// cleanMemberName 将归档条目名称规范化为以 / 分隔的相对路径，名称只包含 . 和 / 时返回空字符串
+// 拒绝绝对路径和包含 .. 的名称，防止 zip-slip
+func cleanMemberName(name string) (string, error) {
+	clean := strings.ReplaceAll(name, "\\", "/")
+	if strings.ContainsRune(clean, 0) || strings.HasPrefix(clean, "/") || filepath.VolumeName(clean) != "" {
+		return "", fmt.Errorf("invalid archive entry: %q", name)
+	}
+	var parts []string
+	for _, part := range strings.Split(clean, "/") {
+		switch part {
+		case "", ".":
+		case "..":
+			return "", fmt.Errorf("archive entry escapes destination: %q", name)
+		default:
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, "/"), nil
+}
+
+// entryPath 校验条目名称并返回其在暂存目录中的路径，名称为空时返回空字符串
+func (e *extractor) entryPath(name string) (string, error) {
+	clean, err := cleanMemberName(name)
+	if err != nil || clean == "" {
+		return "", err
+	}
+	return filepath.Join(e.stage, filepath.FromSlash(clean)), nil
+}
+
+// entryName 返回暂存目录中路径对应的条目名称，用于错误信息
+func (e *extractor) entryName(path string) string {
+	if rel, err := filepath.Rel(e.stage, path); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return path
+}
+
+// countEntry 计入一个条目并检查条目数限制
+func (e *extractor) countEntry() error {
+	if err := e.ctx.Err(); err != nil {
+		return err
+	}
+	e.result.Entries++
+	e.progress.AddEntries(1)
+	if e.limits.maxEntries > 0 && e.result.Entries > e.limits.maxEntries {
+		return fmt.Errorf("%w: more than %d entries", ErrArchiveLimit, e.limits.maxEntries)
+	}
+	return nil
+}
+
+// checkSize 检查解压后的总大小和压缩比限制
+func (e *extractor) checkSize(total int64) error {
+	if e.limits.maxSize > 0 && total > e.limits.maxSize {
+		return fmt.Errorf("%w: more than %d bytes", ErrArchiveLimit, e.limits.maxSize)
+	}
+	if e.limits.maxRatio > 0 && total > ratioMinSize && total > e.limits.archive*e.limits.maxRatio {
+		return fmt.Errorf("%w: compression ratio above %d", ErrArchiveLimit, e.limits.maxRatio)
+	}
+	return nil
+}
+
+// limitedWriter 写入时累计解压的总字节数并检查限制，使声明大小与实际内容不符的条目同样受到限制
+type limitedWriter struct {
+	e *extractor
+	w io.Writer
+}
+
+// Write 实现 io.Writer 接口
+func (lw *limitedWriter) Write(b []byte) (int, error) {
+	if err := lw.e.checkSize(lw.e.result.Bytes + int64(len(b))); err != nil {
+		return 0, err
+	}
+	n, err := lw.w.Write(b)
+	lw.e.result.Bytes += int64(n)
+	return n, err
+}
+
+// writeDir 创建目录，权限和修改时间在解压完成后设置
+func (e *extractor) writeDir(path string, mode os.FileMode, modTime time.Time) error {
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return err
+	}
+	e.dirs = append(e.dirs, extractedDir{path: path, mode: mode.Perm(), modTime: modTime})
+	return nil
+}
+
+// writeFile 写入文件内容，同名的已解压条目被替换；不保留 setuid、setgid 位
+func (e *extractor) writeFile(path string, mode os.FileMode, modTime time.Time, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if info, err := os.Lstat(path); err == nil {
+		if info.IsDir() {
+			return fmt.Errorf("archive entry conflicts with directory: %s", e.entryName(path))
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = copyContext(e.ctx, &limitedWriter{e: e, w: f}, r, e.progress)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(path, mode.Perm()); err != nil {
+		return err
+	}
+	return os.Chtimes(path, modTime, modTime)
+}
+
+// addSymlink 记录符号链接，目标必须是相对路径且位于暂存目录之内
+func (e *extractor) addSymlink(path, target string, modTime time.Time) error {
+	if target == "" || filepath.IsAbs(target) || strings.HasPrefix(target, "/") {
+		return fmt.Errorf("archive symlink points outside destination: %s -> %s", e.entryName(path), target)
+	}
+	if !isSubPath(e.stage, resolveLinkTarget(path, filepath.FromSlash(target))) {
+		return fmt.Errorf("archive symlink points outside destination: %s -> %s", e.entryName(path), target)
+	}
+	e.symlinks = append(e.symlinks, extractedLink{path: path, target: filepath.FromSlash(target), modTime: modTime})
+	return nil
+}
+
+// writeHardLink 创建指向归档中已解压文件的硬链接
+func (e *extractor) writeHardLink(path, linkName string) error {
+	target, err := e.entryPath(linkName)
+	if err != nil || target == "" {
+		return fmt.Errorf("invalid hard link target: %q", linkName)
+	}
+	info, err := os.Lstat(target)
+	if err != nil || !info.Mode().IsRegular() {
+		return fmt.Errorf("hard link target not found in archive: %q", linkName)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if _, err := os.Lstat(path); err == nil {
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+	}
+	return os.Link(target, path)
+}
+
+// checkSymlinks 在创建符号链接之前，按所有已记录的链接逐个分量解析每个链接的位置和目标，
+// 拒绝经由其他链接（如 d/l1 -> .. 与 d/l2 -> l1/..）离开暂存目录的链接以及链接循环
+// 同一路径记录多个链接时以最后一个为准，与 finish 中的创建顺序一致
+func (e *extractor) checkSymlinks() error {
+	links := make(map[string]string, len(e.symlinks))
+	for _, link := range e.symlinks {
+		links[e.entryName(link.path)] = filepath.ToSlash(link.target)
+	}
+
+	for _, link := range e.symlinks {
+		name := e.entryName(link.path)
+		pending := append(strings.Split(e.entryName(filepath.Dir(link.path)), "/"), strings.Split(filepath.ToSlash(link.target), "/")...)
+		var resolved []string
+		followed := 0
+		for len(pending) > 0 {
+			part := pending[0]
+			pending = pending[1:]
+			switch part {
+			case "", ".":
+				continue
+			case "..":
+				if len(resolved) == 0 {
+					return fmt.Errorf("archive symlink points outside destination: %s -> %s", name, link.target)
+				}
+				resolved = resolved[:len(resolved)-1]
+				continue
+			}
+			resolved = append(resolved, part)
+			target, ok := links[strings.Join(resolved, "/")]
+			if !ok {
+				continue
+			}
+			if followed++; followed > maxSymlinks {
+				return fmt.Errorf("too many levels of symbolic links in archive: %s -> %s", name, link.target)
+			}
+			if strings.HasPrefix(target, "/") {
+				return fmt.Errorf("archive symlink points outside destination: %s -> %s", name, link.target)
+			}
+			resolved = resolved[:len(resolved)-1]
+			pending = append(strings.Split(target, "/"), pending...)
+		}
+	}
+	return nil
+}
+
+// finish 检查并创建符号链接，再由深到浅设置目录的权限和修改时间
+func (e *extractor) finish() error {
+	if err := e.checkSymlinks(); err != nil {
+		return err
+	}
+	for _, link := range e.symlinks {
+		if err := os.MkdirAll(filepath.Dir(link.path), 0755); err != nil {
+			return err
+		}
+		if info, err := os.Lstat(link.path); err == nil {
+			if info.IsDir() {
+				return fmt.Errorf("archive entry conflicts with directory: %s", e.entryName(link.path))
+			}
+			if err := os.Remove(link.path); err != nil {
+				return err
+			}
+		}
+		if err := os.Symlink(link.target, link.path); err != nil {
+			return err
+		}
+	}
+
+	sort.Slice(e.dirs, func(i, j int) bool { return len(e.dirs[i].path) > len(e.dirs[j].path) })
+	for _, dir := range e.dirs {
+		// 目录至少保留所有者的读写执行权限，以便之后移动和删除
+		if err := os.Chmod(dir.path, dir.mode|0700); err != nil {
+			return err
+		}
+		os.Chtimes(dir.path, dir.modTime, dir.modTime)
+	}
+	return nil
+}
+
+// extractZip 解压 zip 归档
+func (e *extractor) extractZip(path string) error {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	// zip 的中央目录包含所有条目的声明大小，可以在写入任何内容之前拒绝明显超限的归档
+	var declared uint64
+	for _, f := range zr.File {
+		declared += f.UncompressedSize64
+	}
+	if e.limits.maxEntries > 0 && len(zr.File) > e.limits.maxEntries {
+		return fmt.Errorf("%w: more than %d entries", ErrArchiveLimit, e.limits.maxEntries)
+	}
+	if declared > 1<<62 {
+		return fmt.Errorf("%w: declared size too large", ErrArchiveLimit)
+	}
+	if err := e.checkSize(int64(declared)); err != nil {
+		return err
+	}
+	e.progress.SetTotal(int64(declared), int64(len(zr.File)))
+
+	for _, f := range zr.File {
+		if err := e.countEntry(); err != nil {
+			return err
+		}
+		target, err := e.entryPath(f.Name)
+		if err != nil {
+			return err
+		}
+		if target == "" {
+			continue
+		}
+
+		mode := f.Mode()
+		switch {
+		case mode.IsDir() || strings.HasSuffix(f.Name, "/"):
+			err = e.writeDir(target, mode, f.Modified)
+		case mode&os.ModeSymlink != 0:
+			err = e.zipSymlink(f, target)
+		case mode.IsRegular():
+			err = e.zipFile(f, target)
+		default:
+			e.result.Ignored++
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// zipFile 解压 zip 中的文件
+func (e *extractor) zipFile(f *zip.File, target string) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return e.writeFile(target, f.Mode(), f.Modified, rc)
+}
+
+// zipSymlink 读取 zip 中以文件内容保存的符号链接目标
+func (e *extractor) zipSymlink(f *zip.File, target string) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	link, err := io.ReadAll(io.LimitReader(rc, maxSymlinkTargetSize+1))
+	if err != nil {
+		return err
+	}
+	if len(link) > maxSymlinkTargetSize {
+		return fmt.Errorf("archive symlink target too long: %s", f.Name)
+	}
+	return e.addSymlink(target, string(link), f.Modified)
+}
+
+// extractTar 解压 tar 归档，r 为解压缩后的数据流
+func (e *extractor) extractTar(r io.Reader) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := e.countEntry(); err != nil {
+			return err
+		}
+		target, err := e.entryPath(hdr.Name)
+		if err != nil {
+			return err
+		}
+		if target == "" {
+			continue
+		}
+
+		mode := hdr.FileInfo().Mode()
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = e.writeDir(target, mode, hdr.ModTime)
+		case tar.TypeReg, tar.TypeRegA:
+			err = e.writeFile(target, mode, hdr.ModTime, tr)
+		case tar.TypeSymlink:
+			err = e.addSymlink(target, hdr.Linkname, hdr.ModTime)
+		case tar.TypeLink:
+			err = e.writeHardLink(target, hdr.Linkname)
+		default:
+			e.result.Ignored++
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// decompressor 按格式打开 tar 归档的解压缩流
+func decompressor(f io.Reader, format ArchiveFormat) (io.Reader, func(), error) {
+	switch format {
+	case ArchiveTar:
+		return f, func() {}, nil
+	case ArchiveTarGz:
+		gr, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, nil, err
+		}
+		return gr, func() { gr.Close() }, nil
+	case ArchiveTarBz2:
+		return bzip2.NewReader(f), func() {}, nil
+	case ArchiveTarZst:
+		zr, err := zstd.NewReader(f)
+		if err != nil {
+			return nil, nil, err
+		}
+		return zr, zr.Close, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported archive format: %s", format)
+	}
+}
+
+// Extract 实现 Service 接口的 Extract 方法
+// 先解压到目标目录下的暂存目录，超出限制或条目不安全时删除暂存目录，不会在目标目录中留下部分内容
+func (s *service) Extract(ctx context.Context, path, dst string, opts ExtractOptions) (ExtractResult, error) {
+	processedPath, err := s.pathProcessor.ProcessPath(path)
+	if err != nil {
+		return ExtractResult{}, err
+	}
+	info, err := os.Stat(processedPath)
+	if err != nil {
+		return ExtractResult{}, err
+	}
+	if info.IsDir() {
+		return ExtractResult{}, fmt.Errorf("path is a directory: %s", path)
+	}
+	if opts.Format == "" {
+		var ok bool
+		if opts.Format, ok = DetectArchiveFormat(processedPath); !ok {
+			return ExtractResult{}, fmt.Errorf("unknown archive format: %s", path)
+		}
+	}
+
+	processedDst := filepath.Dir(processedPath)
+	if dst != "" {
+		if processedDst, err = s.pathProcessor.ProcessPath(dst); err != nil {
+			return ExtractResult{}, err
+		}
+	}
+	if err := os.MkdirAll(processedDst, 0755); err != nil {
+		return ExtractResult{}, fmt.Errorf("failed to create destination directory: %v", err)
+	}
+
+	stage := tempSibling(filepath.Join(processedDst, filepath.Base(processedPath)), "extract")
+	if err := os.Mkdir(stage, 0700); err != nil {
+		return ExtractResult{}, err
+	}
+	defer os.RemoveAll(stage)
+
+	e := &extractor{
+		ctx:   ctx,
+		stage: stage,
+		limits: extractLimits{
+			maxEntries: s.config.Archive.MaxEntries,
+			maxSize:    s.config.Archive.MaxSize,
+			maxRatio:   int64(s.config.Archive.MaxRatio),
+			archive:    info.Size(),
+		},
+		progress: opts.Progress,
+	}
+	if opts.Format == ArchiveZip {
+		err = e.extractZip(processedPath)
+	} else {
+		err = e.extractTarFile(processedPath, opts.Format)
+	}
+	if err == nil {
+		err = e.finish()
+	}
+	if err != nil {
+		return e.result, err
+	}
+
+	err = s.mergeExtracted(ctx, stage, processedDst, opts.Conflict, &e.result)
+	s.notify(ChangeEvent{Op: ChangeWrite, Path: processedDst})
+	return e.result, err
+}
+
+// extractTarFile 打开并解压 tar 系列归档
+func (e *extractor) extractTarFile(path string, format ArchiveFormat) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r, closeReader, err := decompressor(f, format)
+	if err != nil {
+		return err
+	}
+	defer closeReader()
+	return e.extractTar(r)
+}
+
+// mergeExtracted 将暂存目录中的条目重命名到目标目录，冲突时与 copier 的处理一致：
+// 两者均为目录且策略为 overwrite 或 skip 时合并，否则按冲突策略跳过、替换、重命名或失败
+// 策略为 fail 时先检查所有顶层条目，存在冲突则不移动任何条目
+func (s *service) mergeExtracted(ctx context.Context, stage, dst string, policy ConflictPolicy, result *ExtractResult) error {
+	entries, err := readDir(ctx, stage)
+	if err != nil {
+		return err
+	}
+	if policy == ConflictFail || policy == "" {
+		for _, entry := range entries {
+			if _, err := os.Lstat(filepath.Join(dst, entry.Name())); err == nil {
+				return fmt.Errorf("file already exists: %s", filepath.Join(dst, entry.Name()))
+			}
+		}
+	}
+
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		src := filepath.Join(stage, entry.Name())
+		target := filepath.Join(dst, entry.Name())
+
+		dstInfo, err := os.Lstat(target)
+		if os.IsNotExist(err) {
+			if err := os.Rename(src, target); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case entry.IsDir() && dstInfo.IsDir() && (policy == ConflictOverwrite || policy == ConflictSkip):
+			if err := s.mergeExtracted(ctx, src, target, policy, result); err != nil {
+				return err
+			}
+		case policy == ConflictSkip:
+			result.Skipped++
+		case policy == ConflictOverwrite:
+			if err := s.saveVersion(ctx, target, VersionOverwrite); err != nil {
+				return err
+			}
+			if dstInfo.IsDir() {
+				if err := os.RemoveAll(target); err != nil {
+					return err
+				}
+			}
+			if err := os.Rename(src, target); err != nil {
+				return err
+			}
+		case policy == ConflictRename:
+			unique, err := uniquePath(target)
+			if err != nil {
+				return err
+			}
+			if err := os.Rename(src, unique); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("file already exists: %s", target)
+		}
+	}
+	return nil
+}
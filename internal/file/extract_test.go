@@ -0,0 +1,343 @@
+package file
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"jia-file/internal/config"
+)
+
+// newTestService 创建以 root 为根目录、不启用回收站和版本历史的文件服务
+func newTestService(t testing.TB, root string) *service {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.File.RootPath = root
+	return &service{
+		config:        cfg,
+		pathProcessor: NewPathProcessor(root),
+		checksums:     newChecksumCache(checksumCacheSize),
+		usage:         newUsageCache(0),
+		archives:      newArchiveIndexCache(archiveIndexCacheSize),
+	}
+}
+
+// testEntry 测试归档中的条目，link 非空时为符号链接，hardLink 非空时为硬链接
+type testEntry struct {
+	name     string
+	body     string
+	dir      bool
+	link     string
+	hardLink string
+}
+
+// writeTestTar 生成 tar 归档，gz 为 true 时使用 gzip 压缩
+func writeTestTar(t *testing.T, path string, gz bool, entries []testEntry) {
+	t.Helper()
+	var buf bytes.Buffer
+	var w io.Writer = &buf
+	var zw *gzip.Writer
+	if gz {
+		zw = gzip.NewWriter(&buf)
+		w = zw
+	}
+	tw := tar.NewWriter(w)
+	for _, entry := range entries {
+		hdr := &tar.Header{Name: entry.name, Mode: 0644, ModTime: time.Now()}
+		switch {
+		case entry.dir:
+			hdr.Typeflag, hdr.Mode = tar.TypeDir, 0755
+		case entry.link != "":
+			hdr.Typeflag, hdr.Linkname = tar.TypeSymlink, entry.link
+		case entry.hardLink != "":
+			hdr.Typeflag, hdr.Linkname = tar.TypeLink, entry.hardLink
+		default:
+			hdr.Typeflag, hdr.Size = tar.TypeReg, int64(len(entry.body))
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			if _, err := tw.Write([]byte(entry.body)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if zw != nil {
+		if err := zw.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writeTestZip 生成 zip 归档
+func writeTestZip(t *testing.T, path string, entries []testEntry) {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, entry := range entries {
+		hdr := &zip.FileHeader{Name: entry.name, Method: zip.Deflate, Modified: time.Now()}
+		body := entry.body
+		switch {
+		case entry.dir:
+			hdr.SetMode(os.ModeDir | 0755)
+		case entry.link != "":
+			hdr.SetMode(os.ModeSymlink | 0777)
+			body = entry.link
+		default:
+			hdr.SetMode(0644)
+		}
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExtract(t *testing.T) {
+	big := strings.Repeat("0", 2<<20)
+	tests := []struct {
+		name    string
+		zip     bool
+		gz      bool
+		limits  func(cfg *config.Config)
+		entries []testEntry
+		wantErr string
+		check   func(t *testing.T, dst string)
+	}{
+		{
+			name:    "zip-slip name",
+			entries: []testEntry{{name: "../evil", body: "x"}},
+			wantErr: "escapes destination",
+		},
+		{
+			name:    "zip-slip nested name",
+			entries: []testEntry{{name: "a/../../evil", body: "x"}},
+			wantErr: "escapes destination",
+		},
+		{
+			name:    "zip-slip backslash name",
+			zip:     true,
+			entries: []testEntry{{name: `..\evil`, body: "x"}},
+			wantErr: "escapes destination",
+		},
+		{
+			name:    "absolute name",
+			entries: []testEntry{{name: "/etc/evil", body: "x"}},
+			wantErr: "invalid archive entry",
+		},
+		{
+			name:    "absolute symlink",
+			entries: []testEntry{{name: "l", link: "/etc"}},
+			wantErr: "outside destination",
+		},
+		{
+			name:    "relative symlink escape",
+			entries: []testEntry{{name: "d/l", link: "../.."}},
+			wantErr: "outside destination",
+		},
+		{
+			name:    "zip symlink escape",
+			zip:     true,
+			entries: []testEntry{{name: "l", link: "../x"}},
+			wantErr: "outside destination",
+		},
+		{
+			name:    "chained symlinks",
+			entries: []testEntry{{name: "d/l1", link: ".."}, {name: "d/l2", link: "l1/.."}},
+			wantErr: "outside destination",
+		},
+		{
+			name:    "chained symlinks recorded in reverse order",
+			entries: []testEntry{{name: "d/l2", link: "l1/.."}, {name: "d/l1", link: ".."}},
+			wantErr: "outside destination",
+		},
+		{
+			name:    "symlink located under another symlink",
+			entries: []testEntry{{name: "a", link: "."}, {name: "a/l", link: ".."}},
+			wantErr: "outside destination",
+		},
+		{
+			name:    "symlink loop",
+			entries: []testEntry{{name: "l1", link: "l2"}, {name: "l2", link: "l1"}},
+			wantErr: "too many levels",
+		},
+		{
+			name:    "symlinks within destination",
+			entries: []testEntry{{name: "d/f", body: "data"}, {name: "d/l1", link: ".."}, {name: "l2", link: "d/l1/d/f"}},
+			check: func(t *testing.T, dst string) {
+				data, err := os.ReadFile(filepath.Join(dst, "l2"))
+				if err != nil || string(data) != "data" {
+					t.Fatalf("read through links = %q, %v", data, err)
+				}
+			},
+		},
+		{
+			name:    "hard link escape",
+			entries: []testEntry{{name: "h", hardLink: "../outside"}},
+			wantErr: "invalid hard link target",
+		},
+		{
+			name:    "hard link to missing entry",
+			entries: []testEntry{{name: "h", hardLink: "missing"}},
+			wantErr: "not found in archive",
+		},
+		{
+			name:    "hard link to symlink",
+			entries: []testEntry{{name: "l", link: "f"}, {name: "f", body: "x"}, {name: "h", hardLink: "l"}},
+			wantErr: "not found in archive",
+		},
+		{
+			name:    "hard link within archive",
+			entries: []testEntry{{name: "f", body: "data"}, {name: "h", hardLink: "f"}},
+			check: func(t *testing.T, dst string) {
+				a, _ := os.Stat(filepath.Join(dst, "f"))
+				b, err := os.Stat(filepath.Join(dst, "h"))
+				if err != nil || !os.SameFile(a, b) {
+					t.Fatalf("h is not a hard link of f: %v", err)
+				}
+			},
+		},
+		{
+			name:    "entry limit",
+			limits:  func(cfg *config.Config) { cfg.Archive.MaxEntries = 2 },
+			entries: []testEntry{{name: "a", body: "x"}, {name: "b", body: "x"}, {name: "c", body: "x"}},
+			wantErr: "more than 2 entries",
+		},
+		{
+			name:    "zip entry limit",
+			zip:     true,
+			limits:  func(cfg *config.Config) { cfg.Archive.MaxEntries = 2 },
+			entries: []testEntry{{name: "a", body: "x"}, {name: "b", body: "x"}, {name: "c", body: "x"}},
+			wantErr: "more than 2 entries",
+		},
+		{
+			name:    "size limit",
+			limits:  func(cfg *config.Config) { cfg.Archive.MaxSize = 10 },
+			entries: []testEntry{{name: "a", body: "0123456789"}, {name: "b", body: "x"}},
+			wantErr: "more than 10 bytes",
+		},
+		{
+			name:    "zip declared size limit",
+			zip:     true,
+			limits:  func(cfg *config.Config) { cfg.Archive.MaxSize = 10 },
+			entries: []testEntry{{name: "a", body: "01234567890"}},
+			wantErr: "more than 10 bytes",
+		},
+		{
+			name:    "ratio limit",
+			gz:      true,
+			limits:  func(cfg *config.Config) { cfg.Archive.MaxRatio = 10 },
+			entries: []testEntry{{name: "zeros", body: big}},
+			wantErr: "compression ratio above 10",
+		},
+		{
+			name:    "zip ratio limit",
+			zip:     true,
+			limits:  func(cfg *config.Config) { cfg.Archive.MaxRatio = 10 },
+			entries: []testEntry{{name: "zeros", body: big}},
+			wantErr: "compression ratio above 10",
+		},
+		{
+			name:    "small compressible file below ratio threshold",
+			gz:      true,
+			limits:  func(cfg *config.Config) { cfg.Archive.MaxRatio = 10 },
+			entries: []testEntry{{name: "zeros", body: strings.Repeat("0", 1000)}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			s := newTestService(t, root)
+			if tt.limits != nil {
+				tt.limits(s.config)
+			}
+
+			archive := filepath.Join(root, "test.tar")
+			switch {
+			case tt.zip:
+				archive = filepath.Join(root, "test.zip")
+				writeTestZip(t, archive, tt.entries)
+			case tt.gz:
+				archive = filepath.Join(root, "test.tar.gz")
+				writeTestTar(t, archive, true, tt.entries)
+			default:
+				writeTestTar(t, archive, false, tt.entries)
+			}
+
+			dst := filepath.Join(root, "out")
+			_, err := s.Extract(context.Background(), archive, dst, ExtractOptions{})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Extract() error = %v, want %q", err, tt.wantErr)
+				}
+				if strings.Contains(tt.wantErr, "more than") || strings.Contains(tt.wantErr, "ratio") {
+					if !errors.Is(err, ErrArchiveLimit) {
+						t.Errorf("Extract() error = %v, want ErrArchiveLimit", err)
+					}
+				}
+				// 失败时目标目录中不留下任何内容，包括暂存目录
+				entries, _ := os.ReadDir(dst)
+				if len(entries) != 0 {
+					t.Errorf("destination not empty after failure: %v", entries)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Extract() error = %v", err)
+			}
+			if tt.check != nil {
+				tt.check(t, dst)
+			}
+		})
+	}
+}
+
+func TestCleanMemberName(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{"a/b", "a/b", false},
+		{"./a//b/", "a/b", false},
+		{`a\b`, "a/b", false},
+		{"./", "", false},
+		{"..", "", true},
+		{"a/../b", "", true},
+		{`..\b`, "", true},
+		{"/a", "", true},
+		{"a\x00b", "", true},
+	}
+	for _, tt := range tests {
+		got, err := cleanMemberName(tt.name)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("cleanMemberName(%q) = %q, %v; want %q, error %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
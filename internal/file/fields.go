@@ -0,0 +1,90 @@
+package file
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// FieldSet 字段投影，记录需要返回的 FileInfo 字段（JSON 字段名）
+// 空集合表示返回全部字段
+type FieldSet map[string]bool
+
+// fileInfoFields FileInfo 支持投影的字段
+var fileInfoFields = map[string]bool{
+	"name":          true,
+	"isDir":         true,
+	"size":          true,
+	"sizeHuman":     true,
+	"path":          true,
+	"ext":           true,
+	"mimeType":      true,
+	"createTime":    true,
+	"modTime":       true,
+	"accessTime":    true,
+	"mode":          true,
+	"isHidden":      true,
+	"isSymlink":     true,
+	"symlinkTarget": true,
+	"broken":        true,
+	"changeTime":    true,
+	"uid":           true,
+	"gid":           true,
+	"owner":         true,
+	"group":         true,
+	"inode":         true,
+	"device":        true,
+	"nlink":         true,
+	"blocks":        true,
+	"xattrs":        true,
+}
+
+// ParseFields 解析逗号分隔的字段列表，如 "name,size,modTime"
+func ParseFields(value string) (FieldSet, error) {
+	fields := make(FieldSet)
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if !fileInfoFields[field] {
+			return nil, fmt.Errorf("unknown field: %s", field)
+		}
+		fields[field] = true
+	}
+	return fields, nil
+}
+
+// Has 判断是否需要返回指定字段
+func (f FieldSet) Has(field string) bool {
+	return len(f) == 0 || f[field]
+}
+
+// Requested 判断字段是否被显式请求，用于默认不返回的字段
+func (f FieldSet) Requested(field string) bool {
+	return f[field]
+}
+
+// Project 按字段投影文件信息，空集合时原样返回
+func (f FieldSet) Project(info FileInfo) (interface{}, error) {
+	if len(f) == 0 {
+		return info, nil
+	}
+
+	content, err := json.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(content, &all); err != nil {
+		return nil, err
+	}
+
+	projected := make(map[string]json.RawMessage, len(f))
+	for field := range f {
+		if value, ok := all[field]; ok {
+			projected[field] = value
+		}
+	}
+	return projected, nil
+}
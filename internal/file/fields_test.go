@@ -0,0 +1,48 @@
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseFields(t *testing.T) {
+	fields, err := ParseFields("name, size,,mimeType")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fields) != 3 || !fields["name"] || !fields["size"] || !fields["mimeType"] {
+		t.Errorf("ParseFields() = %v", fields)
+	}
+	if _, err := ParseFields("name,unknown"); err == nil {
+		t.Error("ParseFields(unknown) error = nil")
+	}
+}
+
+func TestGetFileInfoFields(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "data"), "plain text")
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		fields   FieldSet
+		wantMime bool
+	}{
+		{fields: nil},
+		{fields: FieldSet{"name": true, "size": true}},
+		{fields: FieldSet{"mimeType": true}, wantMime: true},
+	}
+	for _, tt := range tests {
+		info, err := getFileInfo(entries[0], dir, tt.fields)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 没有扩展名的文件只能读取内容检测，未请求时不应检测
+		if (info.MimeType != "") != tt.wantMime {
+			t.Errorf("getFileInfo(%v) mimeType = %q, want detected %v", tt.fields, info.MimeType, tt.wantMime)
+		}
+	}
+}
@@ -1,68 +1,183 @@
 package file
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"jia-file/internal/config"
+	"jia-file/internal/logger"
 	"mime"
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 )
 
 // FileInfo 文件信息结构
 type FileInfo struct {
-	Name          string    `json:"name"`          // 文件名
-	IsDir         bool      `json:"isDir"`         // 是否为目录
-	Size          int64     `json:"size"`          // 文件大小（字节）
-	SizeHuman     string    `json:"sizeHuman"`     // 人类可读的文件大小
-	Path          string    `json:"path"`          // 完整路径
-	Ext           string    `json:"ext"`           // 文件扩展名
-	MimeType      string    `json:"mimeType"`      // MIME类型
-	CreateTime    time.Time `json:"createTime"`    // 创建时间
-	ModTime       time.Time `json:"modTime"`       // 修改时间
-	AccessTime    time.Time `json:"accessTime"`    // 访问时间
-	Mode          string    `json:"mode"`          // 文件权限
-	IsHidden      bool      `json:"isHidden"`      // 是否为隐藏文件
-	IsSymlink     bool      `json:"isSymlink"`     // 是否为符号链接
-	SymlinkTarget string    `json:"symlinkTarget"` // 符号链接目标
+	Name          string     `json:"name"`                 // 文件名
+	IsDir         bool       `json:"isDir"`                // 是否为目录
+	Size          int64      `json:"size"`                 // 文件大小（字节）
+	SizeHuman     string     `json:"sizeHuman"`            // 人类可读的文件大小
+	Path          string     `json:"path"`                 // 完整路径
+	Ext           string     `json:"ext"`                  // 文件扩展名
+	MimeType      string     `json:"mimeType,omitempty"`   // MIME类型，列表等接口只在字段投影中显式请求 mimeType 时返回
+	CreateTime    *time.Time `json:"createTime,omitempty"` // 创建时间（birth time），平台或文件系统不支持时省略
+	ModTime       time.Time  `json:"modTime"`              // 修改时间
+	AccessTime    *time.Time `json:"accessTime,omitempty"` // 访问时间，平台不支持时省略
+	ChangeTime    *time.Time `json:"changeTime,omitempty"` // 状态改变时间（ctime），平台不支持时省略
+	Mode          string     `json:"mode"`                 // 文件权限
+	IsHidden      bool       `json:"isHidden"`             // 是否为隐藏文件
+	IsSymlink     bool       `json:"isSymlink"`            // 是否为符号链接
+	SymlinkTarget string     `json:"symlinkTarget"`        // 符号链接目标
+	Broken        bool       `json:"broken,omitempty"`     // 符号链接的目标是否不存在
+	Target        *FileInfo  `json:"target,omitempty"`     // 符号链接最终指向的文件信息，仅 GetInfo 返回，目标在根目录之外时省略
+
+	// 以下字段由平台提供，不支持的平台上省略
+	UID    *uint32 `json:"uid,omitempty"`    // 所有者用户ID
+	GID    *uint32 `json:"gid,omitempty"`    // 所属组ID
+	Owner  string  `json:"owner,omitempty"`  // 所有者用户名，无法解析时省略
+	Group  string  `json:"group,omitempty"`  // 所属组名，无法解析时省略
+	Inode  uint64  `json:"inode,omitempty"`  // inode 编号
+	Device uint64  `json:"device,omitempty"` // 所在设备编号
+	Nlink  uint64  `json:"nlink,omitempty"`  // 硬链接数
+	Blocks *int64  `json:"blocks,omitempty"` // 占用的 512 字节块数
+
+	Xattrs []Xattr `json:"xattrs,omitempty"` // 扩展属性，只在字段投影中显式请求 xattrs 时返回
 }
 
 // Service 文件服务接口
+// 所有方法都接收 context.Context：客户端断开连接或超时后，目录读取、数据复制和递归遍历等耗时循环会尽快退出并返回 ctx.Err()
 type Service interface {
-	// List 列出指定目录下的文件和文件夹
-	List(path string) ([]FileInfo, error)
+	// List 分页列出指定目录下的文件和文件夹，支持排序、过滤和字段投影；路径可以指向归档中的目录，如 /data/build.zip!/bin
+	List(ctx context.Context, path string, opts ListOptions) (ListResult, error)
+	// Tree 从指定目录开始按层展开目录树
+	Tree(ctx context.Context, path string, opts TreeOptions) (TreeResult, error)
+	// Search 在指定目录的子树中按名称搜索，每找到一个匹配项就调用 fn
+	Search(ctx context.Context, path string, opts SearchOptions, fn func(FileInfo) error) (SearchSummary, error)
+	// Grep 在指定文件或目录子树的文本文件中搜索内容，每找到一个匹配行就调用 fn
+	Grep(ctx context.Context, path string, opts GrepOptions, fn func(GrepMatch) error) (GrepSummary, error)
 	// CreateDir 创建目录
-	CreateDir(path string) error
-	// CreateFile 创建文件
-	CreateFile(path string, content []byte) error
-	// Delete 删除文件或目录
-	Delete(path string) error
-	// Move 移动文件或目录
-	Move(src, dst string) error
-	// Copy 复制文件或目录
-	Copy(src, dst string) error
-	// GetInfo 获取文件信息
-	GetInfo(path string) (FileInfo, error)
+	CreateDir(ctx context.Context, path string) error
+	// CreateFile 创建文件，文件已存在时返回错误
+	CreateFile(ctx context.Context, path string, content io.Reader) error
+	// WriteFile 以流的方式写入文件，按冲突策略处理已存在的目标
+	WriteFile(ctx context.Context, path string, content io.Reader, opts WriteOptions) (FileInfo, error)
+	// Delete 删除文件或目录，默认移入回收站并返回回收站条目，永久删除时返回 nil
+	Delete(ctx context.Context, path string, opts DeleteOptions) (*TrashItem, error)
+	// Move 移动文件或目录，跨文件系统时自动回退为复制后删除
+	Move(ctx context.Context, src, dst string, opts MoveOptions) (FileInfo, error)
+	// Copy 复制文件或目录，目录会被递归复制；有条目复制失败时返回错误，统计仍在结果中
+	Copy(ctx context.Context, src, dst string, opts CopyOptions) (CopyResult, error)
+	// GetInfo 获取文件信息，不跟随符号链接
+	GetInfo(ctx context.Context, path string) (FileInfo, error)
+	// CreateSymlink 在 link 处创建指向 target 的符号链接，target 为相对路径时相对于 link 所在目录
+	CreateSymlink(ctx context.Context, target, link string, opts LinkOptions) (FileInfo, error)
+	// CreateHardLink 在 link 处创建指向已存在文件 target 的硬链接
+	CreateHardLink(ctx context.Context, target, link string, opts LinkOptions) (FileInfo, error)
+	// Chmod 修改权限，返回修改后的文件信息
+	Chmod(ctx context.Context, path string, opts ChmodOptions) (FileInfo, error)
+	// Chown 修改所有者和所属组，返回修改后的文件信息
+	Chown(ctx context.Context, path string, opts ChownOptions) (FileInfo, error)
+	// Chtimes 修改访问时间和修改时间，返回修改后的文件信息
+	Chtimes(ctx context.Context, path string, opts ChtimesOptions) (FileInfo, error)
+	// ListXattrs 列出扩展属性及其值
+	ListXattrs(ctx context.Context, path string) ([]Xattr, error)
+	// GetXattr 读取单个扩展属性
+	GetXattr(ctx context.Context, path, name string) (Xattr, error)
+	// SetXattr 设置扩展属性，属性不存在时创建
+	SetXattr(ctx context.Context, path, name string, value []byte) error
+	// RemoveXattr 删除扩展属性
+	RemoveXattr(ctx context.Context, path, name string) error
+	// Checksum 在一次读取中计算文件的一个或多个校验和，结果按路径、大小、修改时间和 inode 缓存
+	Checksum(ctx context.Context, path string, algorithms []HashAlgorithm) (ChecksumResult, error)
+	// ChecksumManifest 计算目录下所有文件的校验和，每计算完一个文件就调用 fn
+	ChecksumManifest(ctx context.Context, path string, algorithm HashAlgorithm, fn func(ManifestEntry) error) (ManifestSummary, error)
+	// CachedChecksum 返回与 info 描述的文件状态一致的缓存校验和，不读取文件内容
+	CachedChecksum(ctx context.Context, path string, info FileInfo, algorithm HashAlgorithm) (string, bool)
+	// FindDuplicates 查找目录下内容相同的文件，依次按大小、部分哈希和完整哈希分组
+	FindDuplicates(ctx context.Context, path string, opts DuplicateOptions) (DuplicateReport, error)
+	// Deduplicate 查找重复文件并将每组中保留文件之外的副本替换为硬链接或删除
+	Deduplicate(ctx context.Context, path string, opts DedupeOptions) (DedupeResult, error)
+	// DiskUsage 统计目录的磁盘用量、最大的文件和目录以及所在文件系统的用量，结果按目录缓存
+	DiskUsage(ctx context.Context, path string, opts DiskUsageOptions) (DiskUsage, error)
+	// Archive 将一个或多个路径打包为归档并流式写入 w，不使用临时文件
+	Archive(ctx context.Context, paths []string, opts ArchiveOptions, w io.Writer) (ArchiveSummary, error)
+	// Compress 将一个或多个路径打包为服务器上的归档文件
+	Compress(ctx context.Context, paths []string, dst string, opts CompressOptions) (FileInfo, error)
+	// Extract 将归档解压到目标目录，检查条目路径、符号链接以及条目数、总大小和压缩比限制
+	Extract(ctx context.Context, path, dst string, opts ExtractOptions) (ExtractResult, error)
+	// ListTrash 列出回收站中的条目，最近删除的在前
+	ListTrash(ctx context.Context, opts TrashListOptions) (TrashList, error)
+	// RestoreTrash 将回收站条目恢复到原路径或指定路径
+	RestoreTrash(ctx context.Context, id string, opts RestoreOptions) (FileInfo, error)
+	// PurgeTrash 永久删除回收站中的条目
+	PurgeTrash(ctx context.Context, opts PurgeOptions) (PurgeResult, error)
+	// StartTrashPurge 按固定间隔在后台清除超过保留时间或超出总大小限制的回收站条目，关闭 stop 通道时停止
+	StartTrashPurge(interval time.Duration, stop <-chan struct{})
+	// ListVersions 列出文件的历史版本，最新的在前；覆盖或删除文件前会自动保存其内容
+	ListVersions(ctx context.Context, path string) (VersionHistory, error)
+	// OpenVersion 打开文件的指定历史版本用于读取
+	OpenVersion(ctx context.Context, path string, id int) (io.ReadSeekCloser, Version, error)
+	// DiffVersions 比较文本文件的两个历史版本，或历史版本与当前内容
+	DiffVersions(ctx context.Context, path string, opts DiffOptions) (VersionDiff, error)
+	// RestoreVersion 将文件恢复为指定历史版本的内容，当前内容保存为新版本
+	RestoreVersion(ctx context.Context, path string, id int) (FileInfo, error)
+	// StartVersionPrune 按固定间隔在后台按保留策略清理历史版本和不再被引用的内容，关闭 stop 通道时停止
+	StartVersionPrune(interval time.Duration, stop <-chan struct{})
+	// Open 打开文件用于读取，返回可定位的读取器和文件信息；路径可以指向归档中的文件
+	Open(ctx context.Context, path string) (io.ReadSeekCloser, FileInfo, error)
 	// CreateDocument 创建文档文件
-	CreateDocument(path string, docType string, content string) error
+	CreateDocument(ctx context.Context, path string, docType string, content string) error
+	// Subscribe 注册变更监听，每次修改操作完成后同步调用 fn，fn 不应阻塞
+	Subscribe(fn func(ChangeEvent))
 }
 
 // service 文件服务实现
 type service struct {
-	config *config.Config
+	config        *config.Config
 	pathProcessor *PathProcessor
+
+	listenersMu sync.RWMutex
+	listeners   []func(ChangeEvent)
+
+	checksums *checksumCache
+	usage     *usageCache
+	archives  *archiveIndexCache
+	ignore    *ignoreRules
+	trash     *trash
+	versions  *versionStore
 }
 
 // NewService 创建文件服务实例
 func NewService() Service {
 	cfg, _ := config.LoadConfig("")
-	return &service{
-		config: cfg,
+	s := &service{
+		config:        cfg,
 		pathProcessor: NewPathProcessor(cfg.File.RootPath),
-	}
+		checksums:     newChecksumCache(checksumCacheSize),
+		usage:         newUsageCache(cfg.Usage.CacheSize),
+		archives:      newArchiveIndexCache(archiveIndexCacheSize),
+		trash:         newTrash(cfg),
+	}
+	// 断点续传的暂存目录只能通过 tus 接口访问
+	s.pathProcessor.Reserve(cfg.Upload.TempDir)
+	// 忽略配置不存在时不忽略任何条目
+	if ignoreConfig, err := config.LoadIgnoreConfig(cfg.File.IgnoreConfig); err == nil {
+		s.ignore = newIgnoreRules(ignoreConfig, cfg.File.RootPath)
+	}
+	// 保留策略配置不存在时所有路径使用默认策略
+	retention, err := config.LoadVersionRetentionConfig(cfg.Version.RetentionConfig)
+	if err != nil && !os.IsNotExist(err) {
+		logger.Error("Load version retention config error: %v", err)
+	}
+	s.versions = newVersionStore(cfg, retention)
+	// 通过 Service 完成的修改使包含该路径的磁盘用量缓存过期
+	s.Subscribe(s.usage.invalidate)
+	return s
 }
 
 // formatFileSize 将文件大小转换为人类可读的格式
@@ -103,7 +218,7 @@ func detectMimeType(path string, isDir bool) string {
 	}
 
 	mimeType := http.DetectContentType(buffer)
-	
+
 	if mimeType == "application/octet-stream" {
 		switch {
 		case strings.HasPrefix(path, "."):
@@ -122,8 +237,40 @@ func detectMimeType(path string, isDir bool) string {
 	return mimeType
 }
 
-// getFileInfo 获取单个文件的详细信息
-func getFileInfo(entry os.DirEntry, path string) (FileInfo, error) {
+// readDirBatchSize 分批读取目录时每批的条目数
+const readDirBatchSize = 1024
+
+// readDir 分批读取目录并按文件名排序，每批之间检查 ctx 是否已取消
+func readDir(ctx context.Context, path string) ([]os.DirEntry, error) {
+	dir, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer dir.Close()
+
+	var entries []os.DirEntry
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		batch, err := dir.ReadDir(readDirBatchSize)
+		entries = append(entries, batch...)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries, nil
+}
+
+// getFileInfo 获取单个文件的详细信息，MIME 类型需要读取文件内容，只在 fields 显式包含 mimeType 时检测
+func getFileInfo(entry os.DirEntry, path string, fields FieldSet) (FileInfo, error) {
 	info, err := entry.Info()
 	if err != nil {
 		return FileInfo{}, err
@@ -131,8 +278,11 @@ func getFileInfo(entry os.DirEntry, path string) (FileInfo, error) {
 
 	fullPath := filepath.Join(path, entry.Name())
 	ext := filepath.Ext(entry.Name())
-	
-	mimeType := detectMimeType(fullPath, entry.IsDir())
+
+	mimeType := ""
+	if fields.Requested("mimeType") {
+		mimeType = detectMimeType(fullPath, entry.IsDir())
+	}
 
 	isSymlink := info.Mode()&os.ModeSymlink != 0
 	symlinkTarget := ""
@@ -141,16 +291,18 @@ func getFileInfo(entry os.DirEntry, path string) (FileInfo, error) {
 			symlinkTarget = target
 		}
 	}
-
-	stat, err := os.Stat(fullPath)
-	createTime := time.Time{}
-	accessTime := time.Time{}
-	if err == nil {
-		createTime = stat.ModTime()
-		accessTime = stat.ModTime()
+	broken := false
+	if isSymlink && fields.Has("broken") {
+		_, err := os.Stat(fullPath)
+		broken = err != nil
+	}
+	// 扩展属性需要额外的系统调用，只在显式请求时读取；符号链接本身没有用户扩展属性
+	var xattrs []Xattr
+	if fields.Requested("xattrs") && !isSymlink {
+		xattrs, _ = readXattrs(fullPath)
 	}
 
-	return FileInfo{
+	fileInfo := FileInfo{
 		Name:          entry.Name(),
 		IsDir:         entry.IsDir(),
 		Size:          info.Size(),
@@ -158,143 +310,93 @@ func getFileInfo(entry os.DirEntry, path string) (FileInfo, error) {
 		Path:          fullPath,
 		Ext:           ext,
 		MimeType:      mimeType,
-		CreateTime:    createTime,
 		ModTime:       info.ModTime(),
-		AccessTime:    accessTime,
 		Mode:          info.Mode().String(),
 		IsHidden:      strings.HasPrefix(entry.Name(), "."),
 		IsSymlink:     isSymlink,
 		SymlinkTarget: symlinkTarget,
-	}, nil
-}
-
-// List 实现 Service 接口的 List 方法
-func (s *service) List(path string) ([]FileInfo, error) {
-	processedPath, err := s.pathProcessor.ProcessPath(path)
-	if err != nil {
-		return nil, err
-	}
-
-	if _, err := os.Stat(processedPath); os.IsNotExist(err) {
-		return nil, fmt.Errorf("directory does not exist: %s", path)
-	}
-
-	entries, err := os.ReadDir(processedPath)
-	if err != nil {
-		return nil, fmt.Errorf("error reading directory: %v", err)
-	}
-
-	files := make([]FileInfo, 0, len(entries))
-	for _, entry := range entries {
-		if fileInfo, err := getFileInfo(entry, processedPath); err == nil {
-			files = append(files, fileInfo)
-		}
+		Broken:        broken,
+		Xattrs:        xattrs,
 	}
-
-	return files, nil
+	fillPlatformInfo(&fileInfo, info, fullPath, fields)
+	return fileInfo, nil
 }
 
 // CreateDir 实现 Service 接口的 CreateDir 方法
-func (s *service) CreateDir(path string) error {
+func (s *service) CreateDir(ctx context.Context, path string) error {
 	processedPath, err := s.pathProcessor.ProcessPath(path)
 	if err != nil {
 		return err
 	}
-	return os.MkdirAll(processedPath, 0755)
-}
-
-// CreateFile 实现 Service 接口的 CreateFile 方法
-func (s *service) CreateFile(path string, content []byte) error {
-	processedPath, err := s.pathProcessor.ProcessPath(path)
-	if err != nil {
+	if err := os.MkdirAll(processedPath, 0755); err != nil {
 		return err
 	}
-
-	// 检查文件是否已存在
-	if _, err := os.Stat(processedPath); err == nil {
-		return fmt.Errorf("file already exists: %s", path)
-	}
-
-	// 确保父目录存在
-	dir := filepath.Dir(processedPath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("failed to create parent directory: %v", err)
-	}
-
-	return os.WriteFile(processedPath, content, 0644)
+	s.notify(ChangeEvent{Op: ChangeWrite, Path: processedPath})
+	return nil
 }
 
-// Delete 实现 Service 接口的 Delete 方法
-func (s *service) Delete(path string) error {
-	processedPath, err := s.pathProcessor.ProcessPath(path)
-	if err != nil {
-		return err
-	}
-
-	// 检查文件是否存在
-	if _, err := os.Stat(processedPath); os.IsNotExist(err) {
-		return fmt.Errorf("file or directory does not exist: %s", path)
-	}
-
-	return os.RemoveAll(processedPath)
+// CreateFile 实现 Service 接口的 CreateFile 方法
+func (s *service) CreateFile(ctx context.Context, path string, content io.Reader) error {
+	_, err := s.WriteFile(ctx, path, content, WriteOptions{Conflict: ConflictFail})
+	return err
 }
 
-// Move 实现 Service 接口的 Move 方法
-func (s *service) Move(src, dst string) error {
-	processedSrc, err := s.pathProcessor.ProcessPath(src)
+// GetInfo 实现 Service 接口的 GetInfo 方法
+// 不跟随符号链接：返回链接本身的信息，并附带链接目标、目标的文件信息和是否失效
+func (s *service) GetInfo(ctx context.Context, path string) (FileInfo, error) {
+	processedPath, err := s.pathProcessor.ProcessPathNoFollow(path)
 	if err != nil {
-		return err
+		return FileInfo{}, err
 	}
 
-	processedDst, err := s.pathProcessor.ProcessPath(dst)
+	info, err := os.Lstat(processedPath)
 	if err != nil {
-		return err
+		return FileInfo{}, err
 	}
 
-	return os.Rename(processedSrc, processedDst)
+	fileInfo := buildFileInfo(info, processedPath, path)
+	if fileInfo.IsSymlink {
+		s.fillLinkInfo(&fileInfo, processedPath)
+	}
+	return fileInfo, nil
 }
 
-// Copy 实现 Service 接口的 Copy 方法
-func (s *service) Copy(src, dst string) error {
-	processedSrc, err := s.pathProcessor.ProcessPath(src)
+// Open 实现 Service 接口的 Open 方法
+func (s *service) Open(ctx context.Context, path string) (io.ReadSeekCloser, FileInfo, error) {
+	archivePath, member, ok, err := s.archiveMemberPath(path)
 	if err != nil {
-		return err
+		return nil, FileInfo{}, err
 	}
-
-	processedDst, err := s.pathProcessor.ProcessPath(dst)
-	if err != nil {
-		return err
+	if ok {
+		return s.openArchiveMember(ctx, archivePath, member)
 	}
 
-	srcFile, err := os.Open(processedSrc)
+	processedPath, err := s.pathProcessor.ProcessPath(path)
 	if err != nil {
-		return err
+		return nil, FileInfo{}, err
 	}
-	defer srcFile.Close()
 
-	dstFile, err := os.Create(processedDst)
+	file, err := os.Open(processedPath)
 	if err != nil {
-		return err
+		return nil, FileInfo{}, err
 	}
-	defer dstFile.Close()
-
-	_, err = io.Copy(dstFile, srcFile)
-	return err
-}
 
-// GetInfo 实现 Service 接口的 GetInfo 方法
-func (s *service) GetInfo(path string) (FileInfo, error) {
-	processedPath, err := s.pathProcessor.ProcessPath(path)
+	info, err := file.Stat()
 	if err != nil {
-		return FileInfo{}, err
+		file.Close()
+		return nil, FileInfo{}, err
 	}
-
-	info, err := os.Stat(processedPath)
-	if err != nil {
-		return FileInfo{}, err
+	if info.IsDir() {
+		file.Close()
+		return nil, FileInfo{}, fmt.Errorf("path is a directory: %s", path)
 	}
 
-	return FileInfo{
+	return file, buildFileInfo(info, processedPath, path), nil
+}
+
+// buildFileInfo 根据 os.FileInfo 构造文件信息
+func buildFileInfo(info os.FileInfo, processedPath, path string) FileInfo {
+	fileInfo := FileInfo{
 		Name:          info.Name(),
 		IsDir:         info.IsDir(),
 		Size:          info.Size(),
@@ -302,18 +404,18 @@ func (s *service) GetInfo(path string) (FileInfo, error) {
 		Path:          path,
 		Ext:           filepath.Ext(info.Name()),
 		MimeType:      detectMimeType(processedPath, info.IsDir()),
-		CreateTime:    info.ModTime(),
 		ModTime:       info.ModTime(),
-		AccessTime:    info.ModTime(),
 		Mode:          info.Mode().String(),
 		IsHidden:      strings.HasPrefix(info.Name(), "."),
 		IsSymlink:     info.Mode()&os.ModeSymlink != 0,
 		SymlinkTarget: "",
-	}, nil
+	}
+	fillPlatformInfo(&fileInfo, info, processedPath, nil)
+	return fileInfo
 }
 
 // CreateDocument 实现 Service 接口的 CreateDocument 方法
-func (s *service) CreateDocument(path string, docType string, content string) error {
+func (s *service) CreateDocument(ctx context.Context, path string, docType string, content string) error {
 	processedPath, err := s.pathProcessor.ProcessPath(path)
 	if err != nil {
 		return err
@@ -325,10 +427,18 @@ func (s *service) CreateDocument(path string, docType string, content string) er
 		return fmt.Errorf("创建目录失败: %v", err)
 	}
 
+	if err := s.saveVersion(ctx, processedPath, VersionOverwrite); err != nil {
+		return err
+	}
+
 	// 创建空文件
 	file, err := os.Create(processedPath)
 	if err != nil {
 		return err
 	}
-	return file.Close()
-} 
\ No newline at end of file
+	if err := file.Close(); err != nil {
+		return err
+	}
+	s.notify(ChangeEvent{Op: ChangeWrite, Path: processedPath})
+	return nil
+}
@@ -0,0 +1,334 @@
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+const (
+	// DefaultGrepMatches 未指定 maxMatches 时返回的最大匹配行数
+	DefaultGrepMatches = 1000
+	// MaxGrepMatches 允许的最大匹配行数
+	MaxGrepMatches = 100000
+	// DefaultGrepMatchesPerFile 未指定 maxPerFile 时单个文件返回的最大匹配行数
+	DefaultGrepMatchesPerFile = 100
+	// MaxGrepContext 上下文行数上限
+	MaxGrepContext = 20
+
+	// grepSniffSize 判断是否为文本文件时读取的字节数
+	grepSniffSize = 512
+	// grepMaxLineSize 单行最大长度，超过时停止扫描该文件
+	grepMaxLineSize = 1 << 20
+)
+
+// GrepOptions 内容搜索选项
+type GrepOptions struct {
+	Pattern     string   // 搜索内容
+	Regex       bool     // Pattern 是否为正则表达式，否则按字面量匹配
+	IgnoreCase  bool     // 是否忽略大小写
+	Before      int      // 匹配行之前的上下文行数
+	After       int      // 匹配行之后的上下文行数
+	MaxPerFile  int      // 单个文件最多返回的匹配行数，0 表示使用 DefaultGrepMatchesPerFile
+	MaxMatches  int      // 最多返回的匹配行数，0 表示使用 DefaultGrepMatches
+	MaxFileSize int64    // 跳过大于此大小的文件，0 表示不限制
+	Include     []string // 只搜索匹配这些通配符的文件
+	Exclude     []string // 排除匹配这些通配符的条目，被排除的目录不会继续遍历
+	MaxDepth    int      // 最大遍历深度，0 表示不限制
+	HideHidden  bool     // 是否跳过以 "." 开头的条目
+	Workers     int      // 并发扫描的文件数，0 表示 1
+}
+
+// GrepMatch 匹配行
+type GrepMatch struct {
+	Path   string   `json:"path"`             // 文件路径
+	Line   int      `json:"line"`             // 行号，从 1 开始
+	Column int      `json:"column"`           // 第一个匹配在行内的字节偏移，从 1 开始
+	Text   string   `json:"text"`             // 匹配行内容
+	Before []string `json:"before,omitempty"` // 匹配行之前的上下文
+	After  []string `json:"after,omitempty"`  // 匹配行之后的上下文
+}
+
+// GrepSummary 内容搜索结束时的统计
+type GrepSummary struct {
+	Matches      int  `json:"matches"`      // 返回的匹配行数
+	FilesScanned int  `json:"filesScanned"` // 扫描的文本文件数
+	FilesMatched int  `json:"filesMatched"` // 包含匹配的文件数
+	FilesSkipped int  `json:"filesSkipped"` // 因二进制、过大或无法读取而跳过的文件数
+	Truncated    bool `json:"truncated"`    // 是否因匹配数达到上限提前结束
+}
+
+// grepFileResult 单个文件的扫描结果
+type grepFileResult struct {
+	matches []GrepMatch
+	skipped bool
+}
+
+// compileGrepPattern 编译搜索内容，字面量模式会被转义
+func compileGrepPattern(opts GrepOptions) (*regexp.Regexp, error) {
+	if opts.Pattern == "" {
+		return nil, fmt.Errorf("pattern is required")
+	}
+
+	pattern := opts.Pattern
+	if !opts.Regex {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if opts.IgnoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex pattern: %v", err)
+	}
+	return re, nil
+}
+
+// IsTextContent 根据文件开头的内容判断是否为文本文件
+// 与 detectMimeType 一样使用 http.DetectContentType 嗅探，包含 NUL 字节的内容视为二进制
+func IsTextContent(head []byte) bool {
+	if bytes.IndexByte(head, 0) >= 0 {
+		return false
+	}
+	mimeType := http.DetectContentType(head)
+	return strings.HasPrefix(mimeType, "text/") ||
+		strings.Contains(mimeType, "json") ||
+		strings.Contains(mimeType, "xml") ||
+		strings.Contains(mimeType, "javascript")
+}
+
+// grepFile 扫描单个文件，返回最多 maxPerFile 个匹配行
+func grepFile(ctx context.Context, path string, re *regexp.Regexp, opts GrepOptions, maxPerFile int) grepFileResult {
+	f, err := os.Open(path)
+	if err != nil {
+		return grepFileResult{skipped: true}
+	}
+	defer f.Close()
+
+	if opts.MaxFileSize > 0 {
+		info, err := f.Stat()
+		if err != nil || info.Size() > opts.MaxFileSize {
+			return grepFileResult{skipped: true}
+		}
+	}
+
+	head := make([]byte, grepSniffSize)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return grepFileResult{skipped: true}
+	}
+	head = head[:n]
+	if !IsTextContent(head) {
+		return grepFileResult{skipped: true}
+	}
+
+	scanner := bufio.NewScanner(io.MultiReader(bytes.NewReader(head), f))
+	scanner.Buffer(make([]byte, 64*1024), grepMaxLineSize)
+
+	var (
+		matches []GrepMatch
+		before  []string
+		pending []int // 仍在收集后续上下文的匹配
+	)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		if lineNo%256 == 0 && ctx.Err() != nil {
+			return grepFileResult{matches: matches}
+		}
+		line := scanner.Text()
+
+		remaining := pending[:0]
+		for _, idx := range pending {
+			matches[idx].After = append(matches[idx].After, line)
+			if len(matches[idx].After) < opts.After {
+				remaining = append(remaining, idx)
+			}
+		}
+		pending = remaining
+
+		if len(matches) < maxPerFile {
+			if loc := re.FindStringIndex(line); loc != nil {
+				matches = append(matches, GrepMatch{
+					Path:   path,
+					Line:   lineNo,
+					Column: loc[0] + 1,
+					Text:   line,
+					Before: append([]string(nil), before...),
+				})
+				if opts.After > 0 {
+					pending = append(pending, len(matches)-1)
+				}
+			}
+		} else if len(pending) == 0 {
+			break
+		}
+
+		if opts.Before > 0 {
+			before = append(before, line)
+			if len(before) > opts.Before {
+				before = before[1:]
+			}
+		}
+	}
+	// 超长的行或读取错误只结束该文件的扫描，已找到的匹配照常返回
+	return grepFileResult{matches: matches}
+}
+
+// Grep 实现 Service 接口的 Grep 方法
+// 一个协程遍历目录，opts.Workers 个协程并发扫描文件；同一文件的匹配按行号顺序连续返回，不同文件之间的顺序不固定
+func (s *service) Grep(ctx context.Context, path string, opts GrepOptions, fn func(GrepMatch) error) (GrepSummary, error) {
+	processedPath, err := s.pathProcessor.ProcessPath(path)
+	if err != nil {
+		return GrepSummary{}, err
+	}
+
+	info, err := os.Stat(processedPath)
+	if err != nil {
+		return GrepSummary{}, err
+	}
+
+	re, err := compileGrepPattern(opts)
+	if err != nil {
+		return GrepSummary{}, err
+	}
+	filter, err := newPathFilter(opts.Include, opts.Exclude)
+	if err != nil {
+		return GrepSummary{}, err
+	}
+
+	maxMatches := opts.MaxMatches
+	if maxMatches <= 0 {
+		maxMatches = DefaultGrepMatches
+	}
+	if maxMatches > MaxGrepMatches {
+		maxMatches = MaxGrepMatches
+	}
+	maxPerFile := opts.MaxPerFile
+	if maxPerFile <= 0 {
+		maxPerFile = DefaultGrepMatchesPerFile
+	}
+	if maxPerFile > maxMatches {
+		maxPerFile = maxMatches
+	}
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+	opts.Before = clampContext(opts.Before)
+	opts.After = clampContext(opts.After)
+
+	scanCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// 遍历目录，将待扫描的文件交给工作协程
+	paths := make(chan string, workers*4)
+	walkDone := make(chan struct{})
+	var walkErr error
+	go func() {
+		defer close(walkDone)
+		defer close(paths)
+
+		if !info.IsDir() {
+			paths <- processedPath
+			return
+		}
+		walkOpts := walkOptions{maxDepth: opts.MaxDepth, hideHidden: opts.HideHidden, filter: filter, reserved: s.pathProcessor.Reserved}
+		walkErr = walkTree(scanCtx, processedPath, walkOpts, func(dir string, entry os.DirEntry, rel string) error {
+			if !entry.Type().IsRegular() || !filter.included(entry.Name(), rel) {
+				return nil
+			}
+			select {
+			case paths <- filepath.Join(dir, entry.Name()):
+				return nil
+			case <-scanCtx.Done():
+				return scanCtx.Err()
+			}
+		})
+	}()
+
+	results := make(chan grepFileResult, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for p := range paths {
+				if scanCtx.Err() != nil {
+					continue
+				}
+				result := grepFile(scanCtx, p, re, opts, maxPerFile)
+				select {
+				case results <- result:
+				case <-scanCtx.Done():
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var summary GrepSummary
+	var fnErr error
+	for result := range results {
+		if fnErr != nil || summary.Truncated {
+			// 已停止，继续读取直到工作协程退出
+			continue
+		}
+		if result.skipped {
+			summary.FilesSkipped++
+			continue
+		}
+		summary.FilesScanned++
+		if len(result.matches) > 0 {
+			summary.FilesMatched++
+		}
+		for _, match := range result.matches {
+			if err := fn(match); err != nil {
+				fnErr = err
+				cancel()
+				break
+			}
+			summary.Matches++
+			if summary.Matches >= maxMatches {
+				summary.Truncated = true
+				cancel()
+				break
+			}
+		}
+	}
+	<-walkDone
+
+	if fnErr != nil {
+		return summary, fnErr
+	}
+	if summary.Truncated {
+		return summary, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return summary, err
+	}
+	if walkErr != nil {
+		return summary, walkErr
+	}
+	return summary, nil
+}
+
+// clampContext 将上下文行数限制在 [0, MaxGrepContext]
+func clampContext(lines int) int {
+	if lines < 0 {
+		return 0
+	}
+	if lines > MaxGrepContext {
+		return MaxGrepContext
+	}
+	return lines
+}
@@ -0,0 +1,83 @@
+package file
+
+import (
+	"path/filepath"
+	"strings"
+
+	"jia-file/internal/config"
+)
+
+// ignoreRules IgnoreConfig 中的忽略规则
+//   - paths：绝对路径或相对于根目录的路径，匹配该路径及其下的所有条目；不含路径分隔符的项（如 .git）匹配任意位置的同名条目
+//   - extensions：文件扩展名，如 .tmp，不区分大小写，只匹配文件
+//   - patterns：与条目名称匹配的通配符，如 ~$*
+type ignoreRules struct {
+	paths      []string
+	names      map[string]bool
+	extensions map[string]bool
+	patterns   []string
+}
+
+// newIgnoreRules 根据忽略配置创建规则，cfg 为 nil 时返回 nil，表示不忽略任何条目
+func newIgnoreRules(cfg *config.IgnoreConfig, rootPath string) *ignoreRules {
+	if cfg == nil {
+		return nil
+	}
+
+	rules := &ignoreRules{names: make(map[string]bool), extensions: make(map[string]bool)}
+	for _, path := range cfg.Paths {
+		switch {
+		case path == "":
+		case !strings.ContainsAny(path, `/\`):
+			rules.names[path] = true
+		case filepath.IsAbs(path):
+			rules.paths = append(rules.paths, filepath.Clean(path))
+		default:
+			abs, err := filepath.Abs(filepath.Join(rootPath, path))
+			if err == nil {
+				rules.paths = append(rules.paths, abs)
+			}
+		}
+	}
+	for _, ext := range cfg.Extensions {
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		rules.extensions[strings.ToLower(ext)] = true
+	}
+	for _, pattern := range cfg.Patterns {
+		if _, err := filepath.Match(pattern, ""); err == nil && pattern != "" {
+			rules.patterns = append(rules.patterns, pattern)
+		}
+	}
+	return rules
+}
+
+// ignored 判断处理后的绝对路径是否被忽略，允许在 nil 上调用
+func (r *ignoreRules) ignored(path string, isDir bool) bool {
+	if r == nil {
+		return false
+	}
+
+	name := filepath.Base(path)
+	if r.names[name] {
+		return true
+	}
+	if !isDir && r.extensions[strings.ToLower(filepath.Ext(name))] {
+		return true
+	}
+	for _, pattern := range r.patterns {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+	}
+	for _, ignoredPath := range r.paths {
+		if path == ignoredPath || strings.HasPrefix(path, ignoredPath+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
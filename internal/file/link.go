@@ -0,0 +1,152 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// LinkOptions 创建链接选项
+type LinkOptions struct {
+	Conflict ConflictPolicy // 链接路径已存在时的冲突策略
+}
+
+// resolveLinkTarget 返回链接目标对应的绝对路径，相对目标相对于链接所在目录
+func resolveLinkTarget(linkPath, target string) string {
+	if filepath.IsAbs(target) {
+		return filepath.Clean(target)
+	}
+	return filepath.Join(filepath.Dir(linkPath), target)
+}
+
+// fillLinkInfo 为符号链接补充链接目标、目标的文件信息以及是否失效
+// 目标位于根目录之外时只报告是否失效，不返回目标的文件信息
+func (s *service) fillLinkInfo(fileInfo *FileInfo, processedPath string) {
+	target, err := os.Readlink(processedPath)
+	if err != nil {
+		return
+	}
+	fileInfo.SymlinkTarget = target
+
+	// 链接链中任意一环不存在或形成环都视为失效
+	resolved, err := filepath.EvalSymlinks(processedPath)
+	if err != nil {
+		fileInfo.Broken = true
+		return
+	}
+	if _, err := s.pathProcessor.ProcessPath(resolved); err != nil {
+		return
+	}
+	targetInfo, err := os.Lstat(resolved)
+	if err != nil {
+		return
+	}
+	resolvedInfo := buildFileInfo(targetInfo, resolved, resolved)
+	fileInfo.Target = &resolvedInfo
+}
+
+// createLink 在 link 处创建链接，按冲突策略处理已存在的路径
+// 覆盖时先在同一目录下创建临时链接再重命名，保证替换是原子的
+func (s *service) createLink(ctx context.Context, link string, opts LinkOptions, create func(path string) error) (FileInfo, error) {
+	processedLink, err := s.pathProcessor.ProcessPathNoFollow(link)
+	if err != nil {
+		return FileInfo{}, err
+	}
+
+	target, exists, err := resolveConflict(processedLink, opts.Conflict)
+	if err != nil {
+		return FileInfo{}, err
+	}
+
+	switch {
+	case exists && opts.Conflict == ConflictSkip:
+	case exists:
+		existing, err := os.Lstat(target)
+		if err != nil {
+			return FileInfo{}, err
+		}
+		if existing.IsDir() {
+			return FileInfo{}, fmt.Errorf("cannot replace directory with a link: %s", link)
+		}
+		if err := s.saveVersion(ctx, target, VersionOverwrite); err != nil {
+			return FileInfo{}, err
+		}
+		tmp := tempSibling(target, "link")
+		if err := create(tmp); err != nil {
+			return FileInfo{}, err
+		}
+		if err := os.Rename(tmp, target); err != nil {
+			os.Remove(tmp)
+			return FileInfo{}, err
+		}
+	default:
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return FileInfo{}, fmt.Errorf("failed to create parent directory: %v", err)
+		}
+		if err := create(target); err != nil {
+			return FileInfo{}, err
+		}
+	}
+	s.notify(ChangeEvent{Op: ChangeWrite, Path: target})
+
+	info, err := os.Lstat(target)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	fileInfo := buildFileInfo(info, target, target)
+	if fileInfo.IsSymlink {
+		s.fillLinkInfo(&fileInfo, target)
+	}
+	return fileInfo, nil
+}
+
+// CreateSymlink 实现 Service 接口的 CreateSymlink 方法
+// 目标按原样写入链接，相对目标使链接随所在目录一起移动时仍然有效；目标允许暂不存在
+func (s *service) CreateSymlink(ctx context.Context, target, link string, opts LinkOptions) (FileInfo, error) {
+	if target == "" {
+		return FileInfo{}, fmt.Errorf("symlink target is empty")
+	}
+
+	return s.createLink(ctx, link, opts, func(path string) error {
+		if err := os.Symlink(target, path); err != nil {
+			return err
+		}
+		// 相对目标中的 ".." 取决于链接所在目录的真实位置，创建后按实际文件系统解析，
+		// 确保链接不会指向根目录之外
+		if _, err := s.pathProcessor.ProcessPath(path); err != nil {
+			os.Remove(path)
+			return fmt.Errorf("invalid symlink target: %v", err)
+		}
+		return nil
+	})
+}
+
+// CreateHardLink 实现 Service 接口的 CreateHardLink 方法
+// 与符号链接一致，相对目标相对于链接所在目录
+func (s *service) CreateHardLink(ctx context.Context, target, link string, opts LinkOptions) (FileInfo, error) {
+	if target == "" {
+		return FileInfo{}, fmt.Errorf("link target is empty")
+	}
+	processedLink, err := s.pathProcessor.ProcessPathNoFollow(link)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	// 硬链接指向目标路径本身（不跟随其符号链接）
+	processedTarget, err := s.pathProcessor.ProcessPathNoFollow(resolveLinkTarget(processedLink, target))
+	if err != nil {
+		return FileInfo{}, fmt.Errorf("invalid link target: %v", err)
+	}
+
+	info, err := os.Lstat(processedTarget)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	if info.IsDir() {
+		return FileInfo{}, fmt.Errorf("cannot create hard link to directory: %s", target)
+	}
+
+	return s.createLink(ctx, link, opts, func(path string) error {
+		return os.Link(processedTarget, path)
+	})
+}
@@ -0,0 +1,339 @@
+package file
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultListLimit 未指定 limit 时每页返回的条目数
+	DefaultListLimit = 1000
+	// MaxListLimit 每页最多返回的条目数
+	MaxListLimit = 10000
+)
+
+// SortField 排序字段
+type SortField string
+
+const (
+	SortByName    SortField = "name"    // 按名称排序
+	SortBySize    SortField = "size"    // 按大小排序
+	SortByModTime SortField = "modTime" // 按修改时间排序
+	SortByType    SortField = "type"    // 按扩展名排序
+)
+
+// ParseSortField 解析排序字段，空字符串表示按名称排序
+func ParseSortField(value string) (SortField, error) {
+	switch field := SortField(value); field {
+	case "":
+		return SortByName, nil
+	case SortByName, SortBySize, SortByModTime, SortByType:
+		return field, nil
+	default:
+		return "", fmt.Errorf("invalid sort field: %s", value)
+	}
+}
+
+// ListOptions 列出目录选项
+type ListOptions struct {
+	Cursor    string    // 上一页返回的 NextCursor，为空表示第一页
+	Limit     int       // 每页条目数，0 表示使用 DefaultListLimit
+	Sort      SortField // 排序字段
+	Desc      bool      // 是否降序
+	DirsFirst bool      // 目录是否排在文件之前
+
+	Glob          string    // 文件名通配符，如 "*.log"
+	Extensions    []string  // 扩展名过滤，如 ".go"，不区分大小写
+	MinSize       int64     // 最小文件大小，0 表示不限制
+	MaxSize       int64     // 最大文件大小，0 表示不限制
+	ModifiedSince time.Time // 只返回在此时间之后修改的条目
+	HideHidden    bool      // 是否隐藏以 "." 开头的条目
+	Type          string    // 条目类型过滤：file、dir、symlink
+
+	Fields FieldSet // 字段投影，mimeType 和 xattrs 只在显式请求时计算
+}
+
+// ListResult 列出目录结果
+type ListResult struct {
+	Items      []FileInfo `json:"items"`                // 当前页的条目
+	NextCursor string     `json:"nextCursor,omitempty"` // 下一页游标，没有更多数据时为空
+	Total      int        `json:"total"`                // 满足过滤条件的条目总数
+}
+
+// listEntry 排序和过滤用的目录条目
+type listEntry struct {
+	Name    string `json:"n"`
+	IsDir   bool   `json:"d"`
+	Size    int64  `json:"s"`
+	ModTime int64  `json:"m"`
+
+	entry os.DirEntry
+	info  os.FileInfo
+}
+
+// listCursor 分页游标，记录上一页最后一个条目的排序键和排序参数
+type listCursor struct {
+	Sort      SortField `json:"sort"`
+	Desc      bool      `json:"desc"`
+	DirsFirst bool      `json:"dirsFirst"`
+	Last      listEntry `json:"last"`
+}
+
+// encodeCursor 将游标编码为不透明字符串
+func encodeCursor(cursor listCursor) string {
+	content, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(content)
+}
+
+// decodeCursor 解析游标字符串
+func decodeCursor(value string) (listCursor, error) {
+	var cursor listCursor
+	content, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return cursor, fmt.Errorf("invalid cursor")
+	}
+	if err := json.Unmarshal(content, &cursor); err != nil {
+		return cursor, fmt.Errorf("invalid cursor")
+	}
+	return cursor, nil
+}
+
+// needInfo 判断过滤或排序是否需要读取条目的 os.FileInfo
+func (o ListOptions) needInfo() bool {
+	return o.Sort == SortBySize || o.Sort == SortByModTime ||
+		o.MinSize > 0 || o.MaxSize > 0 || !o.ModifiedSince.IsZero()
+}
+
+// match 判断条目是否满足过滤条件
+func (o ListOptions) match(e *listEntry) (bool, error) {
+	if o.HideHidden && strings.HasPrefix(e.Name, ".") {
+		return false, nil
+	}
+
+	switch o.Type {
+	case "file":
+		if !e.entry.Type().IsRegular() {
+			return false, nil
+		}
+	case "dir":
+		if !e.entry.IsDir() {
+			return false, nil
+		}
+	case "symlink":
+		if e.entry.Type()&os.ModeSymlink == 0 {
+			return false, nil
+		}
+	}
+
+	if o.Glob != "" {
+		matched, err := filepath.Match(o.Glob, e.Name)
+		if err != nil {
+			return false, err
+		}
+		if !matched {
+			return false, nil
+		}
+	}
+
+	if len(o.Extensions) > 0 {
+		ext := strings.ToLower(filepath.Ext(e.Name))
+		found := false
+		for _, want := range o.Extensions {
+			want = strings.ToLower(want)
+			if !strings.HasPrefix(want, ".") {
+				want = "." + want
+			}
+			if ext == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false, nil
+		}
+	}
+
+	if o.MinSize > 0 && e.Size < o.MinSize {
+		return false, nil
+	}
+	if o.MaxSize > 0 && e.Size > o.MaxSize {
+		return false, nil
+	}
+	if !o.ModifiedSince.IsZero() && e.ModTime <= o.ModifiedSince.UnixNano() {
+		return false, nil
+	}
+	return true, nil
+}
+
+// compareEntries 按排序参数比较两个条目，名称作为最后的比较键以保证全序
+func compareEntries(a, b *listEntry, field SortField, desc, dirsFirst bool) int {
+	if dirsFirst && a.IsDir != b.IsDir {
+		if a.IsDir {
+			return -1
+		}
+		return 1
+	}
+
+	result := 0
+	switch field {
+	case SortBySize:
+		result = compareInt64(a.Size, b.Size)
+	case SortByModTime:
+		result = compareInt64(a.ModTime, b.ModTime)
+	case SortByType:
+		result = strings.Compare(strings.ToLower(filepath.Ext(a.Name)), strings.ToLower(filepath.Ext(b.Name)))
+	}
+	if result == 0 {
+		result = strings.Compare(a.Name, b.Name)
+	}
+	if desc {
+		result = -result
+	}
+	return result
+}
+
+func compareInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// List 实现 Service 接口的 List 方法
+// 只为当前页的条目构造完整的文件信息，因此超大目录的分页代价主要是一次目录读取和排序
+// 路径指向归档内部（如 /data/build.zip!/bin）时列出归档中的成员，不解压归档
+func (s *service) List(ctx context.Context, path string, opts ListOptions) (ListResult, error) {
+	archivePath, member, ok, err := s.archiveMemberPath(path)
+	if err != nil {
+		return ListResult{}, err
+	}
+	if ok {
+		return s.listArchive(ctx, archivePath, member, opts)
+	}
+
+	processedPath, err := s.pathProcessor.ProcessPath(path)
+	if err != nil {
+		return ListResult{}, err
+	}
+
+	if _, err := os.Stat(processedPath); os.IsNotExist(err) {
+		return ListResult{}, fmt.Errorf("directory does not exist: %s", path)
+	}
+
+	dirEntries, err := readDir(ctx, processedPath)
+	if err != nil {
+		return ListResult{}, fmt.Errorf("error reading directory: %w", err)
+	}
+	// 不列出服务内部使用的目录，如断点续传的暂存目录
+	visible := dirEntries[:0]
+	for _, entry := range dirEntries {
+		if !s.pathProcessor.Reserved(filepath.Join(processedPath, entry.Name())) {
+			visible = append(visible, entry)
+		}
+	}
+	dirEntries = visible
+
+	return listPage(ctx, dirEntries, opts, func(entry os.DirEntry) (FileInfo, error) {
+		return getFileInfo(entry, processedPath, opts.Fields)
+	})
+}
+
+// listPage 对目录条目过滤、排序和分页，并通过 fileInfo 为当前页的条目构造文件信息
+func listPage(ctx context.Context, dirEntries []os.DirEntry, opts ListOptions, fileInfo func(os.DirEntry) (FileInfo, error)) (ListResult, error) {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = DefaultListLimit
+	}
+	if limit > MaxListLimit {
+		limit = MaxListLimit
+	}
+
+	var cursor *listCursor
+	if opts.Cursor != "" {
+		decoded, err := decodeCursor(opts.Cursor)
+		if err != nil {
+			return ListResult{}, err
+		}
+		if decoded.Sort != opts.Sort || decoded.Desc != opts.Desc || decoded.DirsFirst != opts.DirsFirst {
+			return ListResult{}, fmt.Errorf("cursor does not match sort options")
+		}
+		cursor = &decoded
+	}
+
+	needInfo := opts.needInfo()
+	entries := make([]*listEntry, 0, len(dirEntries))
+	for _, dirEntry := range dirEntries {
+		if err := ctx.Err(); err != nil {
+			return ListResult{}, err
+		}
+
+		e := &listEntry{Name: dirEntry.Name(), IsDir: dirEntry.IsDir(), entry: dirEntry}
+		if needInfo {
+			info, err := dirEntry.Info()
+			if err != nil {
+				// 条目可能在读取目录后被删除
+				continue
+			}
+			e.info = info
+			e.Size = info.Size()
+			e.ModTime = info.ModTime().UnixNano()
+		}
+
+		matched, err := opts.match(e)
+		if err != nil {
+			return ListResult{}, err
+		}
+		if matched {
+			entries = append(entries, e)
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return compareEntries(entries[i], entries[j], opts.Sort, opts.Desc, opts.DirsFirst) < 0
+	})
+
+	start := 0
+	if cursor != nil {
+		start = sort.Search(len(entries), func(i int) bool {
+			return compareEntries(entries[i], &cursor.Last, opts.Sort, opts.Desc, opts.DirsFirst) > 0
+		})
+	}
+	end := start + limit
+	if end > len(entries) {
+		end = len(entries)
+	}
+
+	result := ListResult{
+		Items: make([]FileInfo, 0, end-start),
+		Total: len(entries),
+	}
+	for _, e := range entries[start:end] {
+		if err := ctx.Err(); err != nil {
+			return ListResult{}, err
+		}
+		if info, err := fileInfo(e.entry); err == nil {
+			result.Items = append(result.Items, info)
+		}
+	}
+
+	if end < len(entries) {
+		result.NextCursor = encodeCursor(listCursor{
+			Sort:      opts.Sort,
+			Desc:      opts.Desc,
+			DirsFirst: opts.DirsFirst,
+			Last:      *entries[end-1],
+		})
+	}
+	return result, nil
+}
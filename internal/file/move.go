@@ -0,0 +1,214 @@
+package file
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// MoveOptions 移动选项
+type MoveOptions struct {
+	Conflict ConflictPolicy // 冲突策略
+	Progress *Progress      // 可选，用于报告跨文件系统复制的进度
+}
+
+// tempSibling 在 path 同目录下生成一个临时路径，保证与 path 位于同一文件系统
+func tempSibling(path, suffix string) string {
+	return filepath.Join(filepath.Dir(path), fmt.Sprintf(".%s.%s-%d", filepath.Base(path), suffix, time.Now().UnixNano()))
+}
+
+// moveTo 将 src 移动到不存在的 dst
+// 同一文件系统内直接重命名；跨文件系统（EXDEV）时先复制到 dst 旁的临时路径，
+// 复制时计算内容的 SHA-256 并与写入后的内容比较，校验通过后再重命名到 dst，最后删除 src
+func moveTo(ctx context.Context, src, dst string, progress *Progress) error {
+	err := os.Rename(src, dst)
+	if err == nil || !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+
+	measureTree(ctx, src, false, progress)
+	tmp := tempSibling(dst, "move")
+	// 移动时复制链接本身，不跟随符号链接，无需检查链接目标
+	c := newCopier(ctx, CopyOptions{Conflict: ConflictFail, Progress: progress}, nil)
+	c.hashes = make(map[string]string)
+	if err := c.copyEntry(src, tmp); err != nil {
+		os.RemoveAll(tmp)
+		return fmt.Errorf("cross-device copy failed: %v", err)
+	}
+	if c.result.Failed > 0 {
+		os.RemoveAll(tmp)
+		return fmt.Errorf("cross-device copy failed: %d entries failed, first error: %s", c.result.Failed, c.result.Errors[0])
+	}
+
+	if err := verifyTree(ctx, src, tmp, c.hashes); err != nil {
+		os.RemoveAll(tmp)
+		return fmt.Errorf("cross-device copy verification failed: %v", err)
+	}
+
+	if err := os.Rename(tmp, dst); err != nil {
+		os.RemoveAll(tmp)
+		return err
+	}
+
+	if err := os.RemoveAll(src); err != nil {
+		return fmt.Errorf("moved to %s but failed to remove source: %v", dst, err)
+	}
+	return nil
+}
+
+// replace 用 src 替换已存在的 dst
+// 先将 dst 重命名为备份，移动成功后再删除备份；移动失败时恢复原有的 dst
+func replace(ctx context.Context, src, dst string, progress *Progress) error {
+	backup := tempSibling(dst, "backup")
+	if err := os.Rename(dst, backup); err != nil {
+		return err
+	}
+
+	if err := moveTo(ctx, src, dst, progress); err != nil {
+		if restoreErr := os.Rename(backup, dst); restoreErr != nil {
+			return fmt.Errorf("%v; additionally failed to restore %s from %s: %v", err, dst, backup, restoreErr)
+		}
+		return err
+	}
+
+	return os.RemoveAll(backup)
+}
+
+// verifyTree 校验 dst 与 src 一致：条目类型、符号链接目标，以及普通文件的大小和内容
+// hashes 为复制时从 src 读到的内容的 SHA-256，键为 dst 下的路径；dst 中的文件重新读取后与之比较
+func verifyTree(ctx context.Context, src, dst string, hashes map[string]string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		srcInfo, err := d.Info()
+		if err != nil {
+			return err
+		}
+		dstInfo, err := os.Lstat(target)
+		if err != nil {
+			return err
+		}
+
+		if srcInfo.Mode().Type() != dstInfo.Mode().Type() {
+			return fmt.Errorf("type mismatch: %s", rel)
+		}
+		if srcInfo.Mode().IsRegular() {
+			if srcInfo.Size() != dstInfo.Size() {
+				return fmt.Errorf("size mismatch: %s", rel)
+			}
+			sum, err := fileSHA256(ctx, target)
+			if err != nil {
+				return err
+			}
+			if expected, ok := hashes[target]; !ok || sum != expected {
+				return fmt.Errorf("content mismatch: %s", rel)
+			}
+		}
+		if srcInfo.Mode()&os.ModeSymlink != 0 {
+			srcLink, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			dstLink, err := os.Readlink(target)
+			if err != nil {
+				return err
+			}
+			if srcLink != dstLink {
+				return fmt.Errorf("symlink target mismatch: %s", rel)
+			}
+		}
+		return nil
+	})
+}
+
+// fileSHA256 计算文件内容的 SHA-256
+func fileSHA256(ctx context.Context, path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := copyContext(ctx, h, f, nil); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Move 实现 Service 接口的 Move 方法
+func (s *service) Move(ctx context.Context, src, dst string, opts MoveOptions) (FileInfo, error) {
+	processedSrc, err := s.pathProcessor.ProcessPathNoFollow(src)
+	if err != nil {
+		return FileInfo{}, err
+	}
+
+	processedDst, err := s.pathProcessor.ProcessPathNoFollow(dst)
+	if err != nil {
+		return FileInfo{}, err
+	}
+
+	srcInfo, err := os.Lstat(processedSrc)
+	if err != nil {
+		return FileInfo{}, err
+	}
+
+	if filepath.Clean(processedSrc) == filepath.Clean(processedDst) {
+		return FileInfo{}, fmt.Errorf("source and destination are the same: %s", src)
+	}
+	if srcInfo.IsDir() && isSubPath(processedSrc, processedDst) {
+		return FileInfo{}, fmt.Errorf("cannot move directory %s into itself", src)
+	}
+
+	target, exists, err := resolveConflict(processedDst, opts.Conflict)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	// 跳过时源保持不变，不发出变更事件，返回已存在目标的信息
+	if exists && opts.Conflict == ConflictSkip {
+		info, err := os.Lstat(target)
+		if err != nil {
+			return FileInfo{}, err
+		}
+		return buildFileInfo(info, target, target), nil
+	}
+
+	if !exists {
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return FileInfo{}, fmt.Errorf("failed to create parent directory: %v", err)
+		}
+		err = moveTo(ctx, processedSrc, target, opts.Progress)
+	} else if opts.Conflict == ConflictOverwrite {
+		if err = s.saveVersion(ctx, target, VersionOverwrite); err == nil {
+			err = replace(ctx, processedSrc, target, opts.Progress)
+		}
+	}
+	if err != nil {
+		return FileInfo{}, err
+	}
+	s.notify(ChangeEvent{Op: ChangeMove, Path: target, OldPath: processedSrc})
+
+	info, err := os.Lstat(target)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	return buildFileInfo(info, target, target), nil
+}
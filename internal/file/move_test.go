@@ -0,0 +1,121 @@
+package file
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFile 写入文件，自动创建父目录
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerifyTree(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(t *testing.T, dst string)
+		wantErr string
+	}{
+		{name: "identical copy"},
+		{
+			name: "content changed with same size",
+			modify: func(t *testing.T, dst string) {
+				writeFile(t, filepath.Join(dst, "sub/b.txt"), "BBBB")
+			},
+			wantErr: "content mismatch: sub/b.txt",
+		},
+		{
+			name: "size changed",
+			modify: func(t *testing.T, dst string) {
+				writeFile(t, filepath.Join(dst, "a.txt"), "a")
+			},
+			wantErr: "size mismatch: a.txt",
+		},
+		{
+			name: "symlink target changed",
+			modify: func(t *testing.T, dst string) {
+				os.Remove(filepath.Join(dst, "link"))
+				if err := os.Symlink("sub/b.txt", filepath.Join(dst, "link")); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: "symlink target mismatch: link",
+		},
+		{
+			name: "entry missing",
+			modify: func(t *testing.T, dst string) {
+				os.Remove(filepath.Join(dst, "sub/b.txt"))
+			},
+			wantErr: "no such file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			src, dst := filepath.Join(dir, "src"), filepath.Join(dir, "dst")
+			writeFile(t, filepath.Join(src, "a.txt"), "aaaa")
+			writeFile(t, filepath.Join(src, "sub/b.txt"), "bbbb")
+			if err := os.Symlink("a.txt", filepath.Join(src, "link")); err != nil {
+				t.Fatal(err)
+			}
+
+			c := newCopier(context.Background(), CopyOptions{Conflict: ConflictFail}, nil)
+			c.hashes = make(map[string]string)
+			if err := c.copyEntry(src, dst); err != nil || c.result.Failed > 0 {
+				t.Fatalf("copyEntry() error = %v, result = %+v", err, c.result)
+			}
+			if tt.modify != nil {
+				tt.modify(t, dst)
+			}
+
+			err := verifyTree(context.Background(), src, dst, c.hashes)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("verifyTree() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("verifyTree() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMoveSkipExisting(t *testing.T) {
+	root := t.TempDir()
+	s := newTestService(t, root)
+	var events []ChangeEvent
+	s.Subscribe(func(event ChangeEvent) { events = append(events, event) })
+
+	src, dst := filepath.Join(root, "src.txt"), filepath.Join(root, "dst.txt")
+	writeFile(t, src, "new")
+	writeFile(t, dst, "old")
+
+	info, err := s.Move(context.Background(), src, dst, MoveOptions{Conflict: ConflictSkip})
+	if err != nil {
+		t.Fatalf("Move() error = %v", err)
+	}
+	if info.Path != dst || info.Size != 3 {
+		t.Errorf("Move() info = %+v, want existing target", info)
+	}
+	if data, err := os.ReadFile(src); err != nil || string(data) != "new" {
+		t.Errorf("src = %q, %v; want unchanged", data, err)
+	}
+	if data, err := os.ReadFile(dst); err != nil || string(data) != "old" {
+		t.Errorf("dst = %q, %v; want unchanged", data, err)
+	}
+	if len(events) != 0 {
+		t.Errorf("events = %+v, want none", events)
+	}
+}
@@ -1,6 +1,7 @@
 package file
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -10,6 +11,8 @@ import (
 // PathProcessor 路径处理器
 type PathProcessor struct {
 	rootPath string
+	// reserved 服务内部使用的目录，如断点续传的暂存目录，只在启动时由 Reserve 设置
+	reserved []string
 }
 
 // NewPathProcessor 创建路径处理器
@@ -19,13 +22,61 @@ func NewPathProcessor(rootPath string) *PathProcessor {
 	}
 }
 
+// RootPath 返回根目录，未设置时返回空字符串
+func (p *PathProcessor) RootPath() string {
+	return p.rootPath
+}
+
+// Reserve 将 dir 标记为服务内部使用的目录：设置了根目录时 ProcessPath 拒绝该目录及其下的路径，
+// 列表、搜索和磁盘用量等遍历跳过该目录。只能在开始处理请求之前调用
+func (p *PathProcessor) Reserve(dir string) {
+	if abs, err := filepath.Abs(dir); err == nil {
+		p.reserved = append(p.reserved, abs)
+	}
+}
+
+// Reserved 判断处理后的绝对路径是否为内部使用的目录或位于其下
+func (p *PathProcessor) Reserved(path string) bool {
+	for _, dir := range p.reserved {
+		if isSubPath(dir, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// maxSymlinks 解析一个路径时最多跟随的符号链接数，与 Linux 的 MAXSYMLINKS 一致
+const maxSymlinks = 40
+
+// ErrOutsideRoot 路径本身或经由符号链接解析后位于根目录之外
+var ErrOutsideRoot = errors.New("path is outside root directory")
+
+// ErrReserved 路径本身或经由符号链接解析后位于服务内部使用的目录中
+var ErrReserved = errors.New("path is reserved for internal use")
+
 // ProcessPath 处理路径
 // 如果设置了rootPath：
 //   - 对于相对路径，将其与rootPath拼接
 //   - 对于绝对路径，验证是否在rootPath下
+//   - 逐个分量解析路径并跟随途经的符号链接（包括最后一个分量），任何一步离开rootPath都返回 ErrOutsideRoot
+//   - 路径或解析结果位于 Reserve 标记的目录中时返回 ErrReserved
+//   - 尚不存在的分量按字面处理，因此可以用于即将创建的文件或目录
+//
+// 返回的是未解析符号链接的路径，与解析时检查的路径一致
 // 如果未设置rootPath：
 //   - 直接返回传入的路径
 func (p *PathProcessor) ProcessPath(path string) (string, error) {
+	return p.process(path, true)
+}
+
+// ProcessPathNoFollow 与 ProcessPath 相同，但不跟随最后一个分量的符号链接
+// 用于操作链接本身的场景，如获取链接信息、删除、移动和创建链接
+func (p *PathProcessor) ProcessPathNoFollow(path string) (string, error) {
+	return p.process(path, false)
+}
+
+// process 将路径映射到根目录下并检查解析过程是否离开根目录
+func (p *PathProcessor) process(path string, followFinal bool) (string, error) {
 	// 如果未设置rootPath，直接返回原路径
 	if p.rootPath == "" {
 		return path, nil
@@ -36,22 +87,135 @@ func (p *PathProcessor) ProcessPath(path string) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("invalid root path: %v", err)
 	}
+	// 根目录本身可能经由符号链接访问，绝对路径形式的链接目标可能使用任意一种形式
+	realRoot, err := filepath.EvalSymlinks(rootPath)
+	if err != nil {
+		realRoot = rootPath
+	}
 
-	// 如果path是绝对路径
+	var rel []string
 	if filepath.IsAbs(path) {
-		// 检查path是否在rootPath下
-		rel, err := filepath.Rel(rootPath, path)
+		parts := splitPath(filepath.Clean(path))
+		var ok bool
+		if rel, ok = trimRoot(parts, rootPath, realRoot); !ok {
+			return "", ErrOutsideRoot
+		}
+	} else {
+		rel = splitPath(filepath.Clean(path))
+		if len(rel) > 0 && rel[0] == ".." {
+			return "", ErrOutsideRoot
+		}
+	}
+
+	resolved, err := resolveBeneath(rootPath, []string{rootPath, realRoot}, rel, followFinal)
+	if err != nil {
+		return "", err
+	}
+	processed := filepath.Join(append([]string{rootPath}, rel...)...)
+	if p.Reserved(processed) || p.Reserved(filepath.Join(append([]string{rootPath}, resolved...)...)) {
+		return "", ErrReserved
+	}
+	return processed, nil
+}
+
+// resolveBeneath 从根目录开始逐个分量解析 rel，语义类似 openat2 的 RESOLVE_BENEATH：
+// 跟随途经的符号链接，相对链接在其所在目录下展开，绝对链接必须指向 roots 中任意一种形式的根目录之下，
+// ".." 不能越过根目录。不存在或无法访问的分量及其后的部分按字面处理，实际操作时会返回相应的错误
+// 返回解析后相对于根目录的分量
+func resolveBeneath(root string, roots []string, rel []string, followFinal bool) ([]string, error) {
+	pending := append([]string(nil), rel...)
+	var resolved []string
+	links := 0
+	exists := true
+
+	for len(pending) > 0 {
+		name := pending[0]
+		pending = pending[1:]
+
+		switch name {
+		case ".":
+			continue
+		case "..":
+			if len(resolved) == 0 {
+				return nil, ErrOutsideRoot
+			}
+			if !exists {
+				// 与内核一致：不存在的目录下不能再用 ".." 返回
+				return nil, fmt.Errorf("path does not exist: %s", filepath.Join(append([]string{root}, resolved...)...))
+			}
+			resolved = resolved[:len(resolved)-1]
+			continue
+		}
+
+		resolved = append(resolved, name)
+		if !exists || (len(pending) == 0 && !followFinal) {
+			continue
+		}
+
+		current := filepath.Join(append([]string{root}, resolved...)...)
+		info, err := os.Lstat(current)
 		if err != nil {
-			return "", fmt.Errorf("path is outside root directory: %v", err)
+			exists = false
+			continue
 		}
-		if strings.HasPrefix(rel, "..") {
-			return "", fmt.Errorf("path is outside root directory")
+		if info.Mode()&os.ModeSymlink == 0 {
+			continue
+		}
+
+		links++
+		if links > maxSymlinks {
+			return nil, fmt.Errorf("too many levels of symbolic links: %s", current)
+		}
+		target, err := os.Readlink(current)
+		if err != nil {
+			return nil, err
+		}
+
+		resolved = resolved[:len(resolved)-1]
+		if filepath.IsAbs(target) {
+			rest, ok := trimRoot(splitPath(target), roots...)
+			if !ok {
+				return nil, fmt.Errorf("%w: symlink %s points to %s", ErrOutsideRoot, current, target)
+			}
+			resolved = resolved[:0]
+			pending = append(rest, pending...)
+		} else {
+			pending = append(splitPath(target), pending...)
 		}
-		return path, nil
 	}
+	return resolved, nil
+}
+
+// trimRoot 若 parts 以 roots 中某个根目录的分量开头，返回去掉根目录后剩余的分量
+// parts 不做清理，根目录分量中出现 ".." 时视为不匹配
+func trimRoot(parts []string, roots ...string) ([]string, bool) {
+	for _, root := range roots {
+		rootParts := splitPath(root)
+		if len(parts) < len(rootParts) {
+			continue
+		}
+		i, j := 0, 0
+		for ; i < len(parts) && j < len(rootParts); i++ {
+			if parts[i] == "." {
+				continue
+			}
+			if parts[i] != rootParts[j] {
+				break
+			}
+			j++
+		}
+		if j == len(rootParts) {
+			return append([]string(nil), parts[i:]...), true
+		}
+	}
+	return nil, false
+}
 
-	// 如果path是相对路径，与rootPath拼接
-	return filepath.Join(rootPath, path), nil
+// splitPath 将路径拆分为分量，忽略空分量（连续或首尾的分隔符）
+func splitPath(path string) []string {
+	return strings.FieldsFunc(path, func(c rune) bool {
+		return c == '/' || c == filepath.Separator
+	})
 }
 
 // ValidatePath 验证路径是否有效
@@ -0,0 +1,351 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newPathFixture 创建测试用目录结构，返回根目录和根目录外的目录
+//
+//	outside/secret.txt
+//	root/..config
+//	root/dir/file.txt
+//	root/dir/up      -> ..
+//	root/dir/upup    -> ../..
+//	root/link-in     -> dir
+//	root/link-abs    -> <root>/dir
+//	root/link-chain  -> link-in
+//	root/link-dotdot -> dir/../dir
+//	root/link-out    -> <outside>
+//	root/link-outrel -> ../outside
+//	root/link-absdot -> <root>/../outside
+//	root/dangling    -> missing
+//	root/dangle-out  -> ../missing
+//	root/loop1       -> loop2
+//	root/loop2       -> loop1
+func newPathFixture(t testing.TB) (root, outside string) {
+	t.Helper()
+	base := t.TempDir()
+	// t.TempDir 可能位于符号链接之下（如 macOS 的 /var），统一使用真实路径
+	base, err := filepath.EvalSymlinks(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root = filepath.Join(base, "root")
+	outside = filepath.Join(base, "outside")
+
+	for _, dir := range []string{outside, filepath.Join(root, "dir")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, file := range []string{
+		filepath.Join(outside, "secret.txt"),
+		filepath.Join(root, "..config"),
+		filepath.Join(root, "dir", "file.txt"),
+	} {
+		if err := os.WriteFile(file, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	links := map[string]string{
+		"dir/up":      "..",
+		"dir/upup":    "../..",
+		"link-in":     "dir",
+		"link-abs":    filepath.Join(root, "dir"),
+		"link-chain":  "link-in",
+		"link-dotdot": "dir/../dir",
+		"link-out":    outside,
+		"link-outrel": "../outside",
+		"link-absdot": root + string(filepath.Separator) + ".." + string(filepath.Separator) + "outside",
+		"dangling":    "missing",
+		"dangle-out":  "../missing",
+		"loop1":       "loop2",
+		"loop2":       "loop1",
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(root, filepath.FromSlash(name))); err != nil {
+			t.Skipf("symlinks not supported: %v", err)
+		}
+	}
+	return root, outside
+}
+
+func TestProcessPath(t *testing.T) {
+	root, outside := newPathFixture(t)
+	p := NewPathProcessor(root)
+	join := func(parts ...string) string {
+		return filepath.Join(append([]string{root}, parts...)...)
+	}
+
+	const (
+		ok      = ""
+		escape  = "outside"
+		failure = "error"
+	)
+	tests := []struct {
+		name     string
+		path     string
+		noFollow bool
+		want     string // 期望的返回路径，want 为空时不检查
+		err      string
+	}{
+		{name: "root absolute", path: root, want: root},
+		{name: "root relative", path: ".", want: root},
+		{name: "root with trailing separator", path: root + string(filepath.Separator), want: root},
+		{name: "relative file", path: "dir/file.txt", want: join("dir", "file.txt")},
+		{name: "absolute file", path: join("dir", "file.txt"), want: join("dir", "file.txt")},
+		{name: "dot-dot prefixed name", path: join("..config"), want: join("..config")},
+		{name: "dot-dot prefixed name relative", path: "..config", want: join("..config")},
+		{name: "dot-dot prefixed missing dir", path: join("..new", "file"), want: join("..new", "file")},
+		{name: "lexical dot-dot inside", path: join("dir", "..", "..config"), want: join("..config")},
+		{name: "uncleaned path", path: root + "//dir/./file.txt", want: join("dir", "file.txt")},
+
+		{name: "absolute parent", path: filepath.Dir(root), err: escape},
+		{name: "absolute sibling", path: outside, err: escape},
+		{name: "sibling sharing prefix", path: root + "-other", err: escape},
+		{name: "lexical escape", path: join("..", "outside"), err: escape},
+		{name: "relative escape", path: "../outside", err: escape},
+		{name: "relative escape after descent", path: "dir/../../outside", err: escape},
+
+		{name: "missing file", path: join("missing.txt"), want: join("missing.txt")},
+		{name: "missing nested", path: join("a", "b", "c"), want: join("a", "b", "c")},
+		{name: "missing under file", path: join("dir", "file.txt", "child"), want: join("dir", "file.txt", "child")},
+
+		{name: "relative link", path: join("link-in", "file.txt"), want: join("link-in", "file.txt")},
+		{name: "absolute link", path: join("link-abs", "file.txt"), want: join("link-abs", "file.txt")},
+		{name: "link chain", path: join("link-chain", "file.txt")},
+		{name: "link with dot-dot inside root", path: join("link-dotdot", "file.txt")},
+		{name: "link to parent within root", path: join("dir", "up", "dir", "file.txt")},
+		{name: "new file under link", path: join("link-in", "new", "file.txt")},
+		{name: "final link followed", path: join("link-in")},
+
+		{name: "absolute link out", path: join("link-out", "secret.txt"), err: escape},
+		{name: "absolute link out final", path: join("link-out"), err: escape},
+		{name: "absolute link out final nofollow", path: join("link-out"), noFollow: true, want: join("link-out")},
+		{name: "absolute link out nofollow child", path: join("link-out", "secret.txt"), noFollow: true, err: escape},
+		{name: "relative link out", path: join("link-outrel", "secret.txt"), err: escape},
+		{name: "relative link out new file", path: join("link-outrel", "new.txt"), err: escape},
+		{name: "absolute link with dot-dot out", path: join("link-absdot"), err: escape},
+		{name: "link climbing above root", path: join("dir", "upup", "outside"), err: escape},
+		{name: "link to parent then escape", path: join("dir", "up", "link-out"), err: escape},
+
+		{name: "dangling inside", path: join("dangling"), want: join("dangling")},
+		{name: "dangling outside", path: join("dangle-out"), err: escape},
+		{name: "dangling outside nofollow", path: join("dangle-out"), noFollow: true, want: join("dangle-out")},
+
+		{name: "loop", path: join("loop1"), err: failure},
+		{name: "loop nofollow", path: join("loop1"), noFollow: true, want: join("loop1")},
+		{name: "loop as directory", path: join("loop1", "x"), noFollow: true, err: failure},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			process := p.ProcessPath
+			if tt.noFollow {
+				process = p.ProcessPathNoFollow
+			}
+			got, err := process(tt.path)
+
+			switch tt.err {
+			case ok:
+				if err != nil {
+					t.Fatalf("ProcessPath(%q) error = %v", tt.path, err)
+				}
+				if tt.want != "" && got != tt.want {
+					t.Fatalf("ProcessPath(%q) = %q, want %q", tt.path, got, tt.want)
+				}
+			case escape:
+				if !errors.Is(err, ErrOutsideRoot) {
+					t.Fatalf("ProcessPath(%q) = %q, %v, want ErrOutsideRoot", tt.path, got, err)
+				}
+			default:
+				if err == nil {
+					t.Fatalf("ProcessPath(%q) = %q, want error", tt.path, got)
+				}
+			}
+		})
+	}
+}
+
+func TestProcessPathSymlinkedRoot(t *testing.T) {
+	root, _ := newPathFixture(t)
+	alias := filepath.Join(filepath.Dir(root), "alias")
+	if err := os.Symlink(root, alias); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	// 指向真实根目录的绝对链接在经由别名访问时同样有效
+	if err := os.Symlink(filepath.Join(root, "dir"), filepath.Join(root, "link-real")); err != nil {
+		t.Fatal(err)
+	}
+	p := NewPathProcessor(alias)
+
+	for _, path := range []string{
+		filepath.Join(alias, "dir", "file.txt"),
+		filepath.Join(root, "dir", "file.txt"),
+		filepath.Join(alias, "link-real", "file.txt"),
+		filepath.Join(alias, "link-abs", "file.txt"),
+	} {
+		got, err := p.ProcessPath(path)
+		if err != nil {
+			t.Fatalf("ProcessPath(%q) error = %v", path, err)
+		}
+		if !strings.HasPrefix(got, alias+string(filepath.Separator)) {
+			t.Fatalf("ProcessPath(%q) = %q, want path under %q", path, got, alias)
+		}
+	}
+	if _, err := p.ProcessPath(filepath.Join(alias, "link-out")); !errors.Is(err, ErrOutsideRoot) {
+		t.Fatalf("ProcessPath through alias: err = %v, want ErrOutsideRoot", err)
+	}
+}
+
+func TestProcessPathWithoutRoot(t *testing.T) {
+	p := NewPathProcessor("")
+	for _, path := range []string{"/etc/passwd", "../x", "relative/path"} {
+		got, err := p.ProcessPath(path)
+		if err != nil || got != path {
+			t.Fatalf("ProcessPath(%q) = %q, %v, want unchanged", path, got, err)
+		}
+	}
+}
+
+func TestProcessPathReserved(t *testing.T) {
+	root, _ := newPathFixture(t)
+	reserved := filepath.Join(root, ".uploads")
+	if err := os.MkdirAll(filepath.Join(reserved, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(".uploads/sub", filepath.Join(root, "link-reserved")); err != nil {
+		t.Fatal(err)
+	}
+	p := NewPathProcessor(root)
+	p.Reserve(reserved)
+
+	tests := []struct {
+		path     string
+		noFollow bool
+		wantErr  bool
+	}{
+		{path: reserved, wantErr: true},
+		{path: filepath.Join(reserved, "sub", "data"), wantErr: true},
+		{path: ".uploads/x", wantErr: true},
+		{path: filepath.Join(root, "dir", "..", ".uploads"), wantErr: true},
+		{path: filepath.Join(root, "link-reserved", "x"), wantErr: true},
+		{path: filepath.Join(root, "link-reserved"), wantErr: true},
+		// 不跟随最后一个分量时操作的是链接本身
+		{path: filepath.Join(root, "link-reserved"), noFollow: true},
+		{path: filepath.Join(root, ".uploads2")},
+		{path: root},
+	}
+	for _, tt := range tests {
+		process := p.ProcessPath
+		if tt.noFollow {
+			process = p.ProcessPathNoFollow
+		}
+		_, err := process(tt.path)
+		if tt.wantErr != errors.Is(err, ErrReserved) {
+			t.Errorf("process(%q, noFollow=%v) error = %v, want reserved %v", tt.path, tt.noFollow, err, tt.wantErr)
+		}
+	}
+}
+
+func TestReservedDirHidden(t *testing.T) {
+	root := t.TempDir()
+	s := newTestService(t, root)
+	reserved := filepath.Join(root, "staging")
+	s.pathProcessor.Reserve(reserved)
+	writeFile(t, filepath.Join(reserved, "upload.data"), "partial upload")
+	writeFile(t, filepath.Join(root, "a", "upload.txt"), "x")
+	ctx := context.Background()
+
+	list, err := s.List(ctx, root, ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.Total != 1 || list.Items[0].Name != "a" {
+		t.Errorf("List() = %+v, want only a", list.Items)
+	}
+
+	tree, err := s.Tree(ctx, root, TreeOptions{Depth: 3, MaxNodes: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tree.Nodes != 2 {
+		t.Errorf("Tree() nodes = %d, want 2", tree.Nodes)
+	}
+
+	var found []string
+	_, err = s.Search(ctx, root, SearchOptions{Pattern: "upload", Mode: MatchSubstring}, func(info FileInfo) error {
+		found = append(found, info.Path)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 || found[0] != filepath.Join(root, "a", "upload.txt") {
+		t.Errorf("Search() = %v, want only a/upload.txt", found)
+	}
+
+	usage, err := s.DiskUsage(ctx, root, DiskUsageOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.Files != 1 || usage.Size != 1 {
+		t.Errorf("DiskUsage() files = %d, size = %d; want 1, 1", usage.Files, usage.Size)
+	}
+
+	if _, err := s.Delete(ctx, reserved, DeleteOptions{}); !errors.Is(err, ErrReserved) {
+		t.Errorf("Delete(reserved) error = %v, want ErrReserved", err)
+	}
+}
+
+// FuzzProcessPath 检查 ProcessPath 接受的任何路径，其已存在部分解析后都位于根目录之内
+func FuzzProcessPath(f *testing.F) {
+	for _, seed := range []string{
+		"", ".", "..", "dir/file.txt", "..config", "link-in/file.txt", "link-out/secret.txt",
+		"link-outrel/x", "dir/upup/outside", "dir/up/dir/up/link-in", "dangle-out", "loop1/x",
+		"link-absdot", "dir/../link-out", "a/../../b", "//dir//file.txt", "link-chain/../link-out",
+	} {
+		f.Add(seed, false)
+		f.Add(seed, true)
+	}
+
+	root, _ := newPathFixture(f)
+	p := NewPathProcessor(root)
+
+	f.Fuzz(func(t *testing.T, path string, absolute bool) {
+		if strings.ContainsRune(path, 0) {
+			return
+		}
+		if absolute {
+			path = root + string(filepath.Separator) + path
+		}
+
+		got, err := p.ProcessPath(path)
+		if err != nil {
+			return
+		}
+		if got != root && !strings.HasPrefix(got, root+string(filepath.Separator)) {
+			t.Fatalf("ProcessPath(%q) = %q, not under root", path, got)
+		}
+
+		// 找到返回路径中已存在的最长前缀，其真实路径必须位于根目录内
+		existing := got
+		resolved, err := filepath.EvalSymlinks(existing)
+		for err != nil && existing != root {
+			existing = filepath.Dir(existing)
+			resolved, err = filepath.EvalSymlinks(existing)
+		}
+		if err != nil {
+			t.Fatalf("root cannot be resolved: %v", err)
+		}
+		if resolved != root && !strings.HasPrefix(resolved, root+string(filepath.Separator)) {
+			t.Fatalf("ProcessPath(%q) = %q resolves to %q outside root", path, got, resolved)
+		}
+	})
+}
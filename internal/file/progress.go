@@ -0,0 +1,128 @@
+package file
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+)
+
+// Progress 长时间操作的进度，可在操作执行过程中被并发读取
+// 所有方法都允许在 nil 上调用，便于不关心进度的调用方直接传入 nil
+type Progress struct {
+	bytesDone    atomic.Int64
+	bytesTotal   atomic.Int64
+	entriesDone  atomic.Int64
+	entriesTotal atomic.Int64
+}
+
+// ProgressSnapshot 进度快照
+type ProgressSnapshot struct {
+	BytesDone    int64 `json:"bytesDone"`    // 已处理字节数
+	BytesTotal   int64 `json:"bytesTotal"`   // 总字节数，未知时为 0
+	EntriesDone  int64 `json:"entriesDone"`  // 已处理条目数
+	EntriesTotal int64 `json:"entriesTotal"` // 总条目数，未知时为 0
+}
+
+// AddBytes 增加已处理字节数
+func (p *Progress) AddBytes(n int64) {
+	if p != nil {
+		p.bytesDone.Add(n)
+	}
+}
+
+// AddEntries 增加已处理条目数
+func (p *Progress) AddEntries(n int64) {
+	if p != nil {
+		p.entriesDone.Add(n)
+	}
+}
+
+// SetTotal 设置总字节数和总条目数
+func (p *Progress) SetTotal(bytes, entries int64) {
+	if p != nil {
+		p.bytesTotal.Store(bytes)
+		p.entriesTotal.Store(entries)
+	}
+}
+
+// Snapshot 返回当前进度快照
+func (p *Progress) Snapshot() ProgressSnapshot {
+	if p == nil {
+		return ProgressSnapshot{}
+	}
+	return ProgressSnapshot{
+		BytesDone:    p.bytesDone.Load(),
+		BytesTotal:   p.bytesTotal.Load(),
+		EntriesDone:  p.entriesDone.Load(),
+		EntriesTotal: p.entriesTotal.Load(),
+	}
+}
+
+// contextWriter 在每次写入前检查 ctx 是否已取消，并累计写入的字节数
+type contextWriter struct {
+	ctx      context.Context
+	w        io.Writer
+	progress *Progress
+}
+
+// Write 实现 io.Writer 接口
+func (cw *contextWriter) Write(b []byte) (int, error) {
+	if err := cw.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := cw.w.Write(b)
+	cw.progress.AddBytes(int64(n))
+	return n, err
+}
+
+// copyContext 可取消、可报告进度的 io.Copy
+func copyContext(ctx context.Context, dst io.Writer, src io.Reader, progress *Progress) (int64, error) {
+	return io.Copy(&contextWriter{ctx: ctx, w: dst, progress: progress}, src)
+}
+
+// measureTree 统计路径下的总字节数和条目数，用于设置进度总量
+func measureTree(ctx context.Context, path string, followSymlinks bool, progress *Progress) {
+	if progress == nil {
+		return
+	}
+
+	var bytes, entries int64
+	visiting := make(map[string]bool)
+	var walk func(string)
+	walk = func(p string) {
+		if ctx.Err() != nil {
+			return
+		}
+		info, err := os.Lstat(p)
+		if err == nil && followSymlinks && info.Mode()&os.ModeSymlink != 0 {
+			info, err = os.Stat(p)
+		}
+		if err != nil {
+			return
+		}
+		entries++
+		if info.Mode().IsRegular() {
+			bytes += info.Size()
+		}
+		if info.IsDir() {
+			realPath, err := filepath.EvalSymlinks(p)
+			if err != nil || visiting[realPath] {
+				return
+			}
+			visiting[realPath] = true
+			defer delete(visiting, realPath)
+
+			children, err := readDir(ctx, p)
+			if err != nil {
+				return
+			}
+			for _, child := range children {
+				walk(filepath.Join(p, child.Name()))
+			}
+		}
+	}
+	walk(path)
+	progress.SetTotal(bytes, entries)
+}
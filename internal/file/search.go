@@ -0,0 +1,259 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	// DefaultSearchResults 未指定 maxResults 时返回的最大结果数
+	DefaultSearchResults = 1000
+	// MaxSearchResults 允许的最大结果数
+	MaxSearchResults = 100000
+)
+
+// errStopWalk 结果数达到上限时用于提前结束遍历
+var errStopWalk = errors.New("stop walk")
+
+// errSkipDir 由 walkTree 的 visit 返回，表示不遍历该目录的内容
+var errSkipDir = errors.New("skip dir")
+
+// MatchMode 名称匹配方式
+type MatchMode string
+
+const (
+	MatchGlob      MatchMode = "glob"      // 通配符匹配，如 "*.go"
+	MatchRegex     MatchMode = "regex"     // 正则表达式匹配
+	MatchSubstring MatchMode = "substring" // 不区分大小写的子串匹配
+)
+
+// ParseMatchMode 解析匹配方式，空字符串表示子串匹配
+func ParseMatchMode(value string) (MatchMode, error) {
+	switch mode := MatchMode(value); mode {
+	case "":
+		return MatchSubstring, nil
+	case MatchGlob, MatchRegex, MatchSubstring:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid match mode: %s", value)
+	}
+}
+
+// newNameMatcher 创建名称匹配函数
+// glob 和 regex 默认区分大小写，ignoreCase 为 true 时不区分；substring 始终不区分大小写
+func newNameMatcher(mode MatchMode, pattern string, ignoreCase bool) (func(string) bool, error) {
+	switch mode {
+	case MatchGlob:
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid glob pattern: %v", err)
+		}
+		if ignoreCase {
+			pattern = strings.ToLower(pattern)
+		}
+		return func(name string) bool {
+			if ignoreCase {
+				name = strings.ToLower(name)
+			}
+			matched, _ := filepath.Match(pattern, name)
+			return matched
+		}, nil
+	case MatchRegex:
+		if ignoreCase {
+			pattern = "(?i)" + pattern
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex pattern: %v", err)
+		}
+		return re.MatchString, nil
+	default:
+		pattern = strings.ToLower(pattern)
+		return func(name string) bool {
+			return strings.Contains(strings.ToLower(name), pattern)
+		}, nil
+	}
+}
+
+// pathFilter include/exclude 通配符过滤
+// 不含 "/" 的模式匹配文件名，含 "/" 的模式匹配相对于搜索根目录的路径
+type pathFilter struct {
+	include []string
+	exclude []string
+}
+
+// newPathFilter 创建路径过滤器并校验模式
+func newPathFilter(include, exclude []string) (*pathFilter, error) {
+	for _, pattern := range append(append([]string{}, include...), exclude...) {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %v", pattern, err)
+		}
+	}
+	return &pathFilter{include: include, exclude: exclude}, nil
+}
+
+// matchAny 判断名称或相对路径是否匹配任一模式
+func matchAny(patterns []string, name, rel string) bool {
+	for _, pattern := range patterns {
+		target := name
+		if strings.Contains(pattern, "/") {
+			target = filepath.ToSlash(rel)
+		}
+		if matched, _ := filepath.Match(pattern, target); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// excluded 判断条目是否被排除，被排除的目录不会继续遍历
+func (f *pathFilter) excluded(name, rel string) bool {
+	return matchAny(f.exclude, name, rel)
+}
+
+// included 判断文件是否满足 include 条件，未设置 include 时全部满足
+func (f *pathFilter) included(name, rel string) bool {
+	return len(f.include) == 0 || matchAny(f.include, name, rel)
+}
+
+// walkOptions 子树遍历选项
+type walkOptions struct {
+	maxDepth   int         // 最大遍历深度，0 表示不限制
+	hideHidden bool        // 是否跳过以 "." 开头的条目
+	filter     *pathFilter // 被 exclude 排除的条目不会被访问，被排除的目录不会继续遍历
+	// reserved 可选，返回 true 的路径不会被访问，用于跳过服务内部使用的目录
+	reserved func(path string) bool
+}
+
+// walkTree 按名称顺序深度优先遍历 root 下的子树，对每个条目调用 visit
+// rel 为条目相对于 root 的路径；符号链接不会被跟随，无法读取的目录会被跳过
+// visit 对目录返回 errSkipDir 时跳过该目录的内容，返回其他错误时停止遍历
+func walkTree(ctx context.Context, root string, opts walkOptions, visit func(dir string, entry os.DirEntry, rel string) error) error {
+	var walk func(dir string, depth int) error
+	walk = func(dir string, depth int) error {
+		entries, err := readDir(ctx, dir)
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			return nil
+		}
+
+		for _, entry := range entries {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			name := entry.Name()
+			fullPath := filepath.Join(dir, name)
+			rel, _ := filepath.Rel(root, fullPath)
+			if opts.hideHidden && strings.HasPrefix(name, ".") {
+				continue
+			}
+			if opts.reserved != nil && opts.reserved(fullPath) {
+				continue
+			}
+			if opts.filter != nil && opts.filter.excluded(name, rel) {
+				continue
+			}
+
+			if err := visit(dir, entry, rel); err == errSkipDir {
+				continue
+			} else if err != nil {
+				return err
+			}
+			if entry.IsDir() && (opts.maxDepth <= 0 || depth < opts.maxDepth) {
+				if err := walk(fullPath, depth+1); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	return walk(root, 1)
+}
+
+// SearchOptions 文件名搜索选项
+type SearchOptions struct {
+	Pattern    string    // 匹配模式
+	Mode       MatchMode // 匹配方式
+	IgnoreCase bool      // glob 和 regex 是否忽略大小写
+	Include    []string  // 只返回匹配这些通配符的条目
+	Exclude    []string  // 排除匹配这些通配符的条目，被排除的目录不会继续遍历
+	MaxResults int       // 最多返回的结果数，0 表示使用 DefaultSearchResults
+	MaxDepth   int       // 最大遍历深度，0 表示不限制
+	HideHidden bool      // 是否跳过以 "." 开头的条目
+	Fields     FieldSet  // 字段投影
+}
+
+// SearchSummary 搜索结束时的统计
+type SearchSummary struct {
+	Matches   int  `json:"matches"`   // 匹配的条目数
+	Scanned   int  `json:"scanned"`   // 遍历的条目数
+	Truncated bool `json:"truncated"` // 是否因结果数达到上限提前结束
+}
+
+// Search 实现 Service 接口的 Search 方法
+// 深度优先遍历 path 下的子树，每找到一个匹配项就调用 fn，fn 返回错误时停止搜索；符号链接不会被跟随
+func (s *service) Search(ctx context.Context, path string, opts SearchOptions, fn func(FileInfo) error) (SearchSummary, error) {
+	processedPath, err := s.pathProcessor.ProcessPath(path)
+	if err != nil {
+		return SearchSummary{}, err
+	}
+
+	info, err := os.Stat(processedPath)
+	if err != nil {
+		return SearchSummary{}, err
+	}
+	if !info.IsDir() {
+		return SearchSummary{}, fmt.Errorf("path is not a directory: %s", path)
+	}
+
+	match, err := newNameMatcher(opts.Mode, opts.Pattern, opts.IgnoreCase)
+	if err != nil {
+		return SearchSummary{}, err
+	}
+	filter, err := newPathFilter(opts.Include, opts.Exclude)
+	if err != nil {
+		return SearchSummary{}, err
+	}
+
+	maxResults := opts.MaxResults
+	if maxResults <= 0 {
+		maxResults = DefaultSearchResults
+	}
+	if maxResults > MaxSearchResults {
+		maxResults = MaxSearchResults
+	}
+
+	var summary SearchSummary
+	walkOpts := walkOptions{maxDepth: opts.MaxDepth, hideHidden: opts.HideHidden, filter: filter, reserved: s.pathProcessor.Reserved}
+	err = walkTree(ctx, processedPath, walkOpts, func(dir string, entry os.DirEntry, rel string) error {
+		summary.Scanned++
+		if !filter.included(entry.Name(), rel) || !match(entry.Name()) {
+			return nil
+		}
+
+		fileInfo, err := getFileInfo(entry, dir, opts.Fields)
+		if err != nil {
+			return nil
+		}
+		if err := fn(fileInfo); err != nil {
+			return err
+		}
+		summary.Matches++
+		if summary.Matches >= maxResults {
+			summary.Truncated = true
+			return errStopWalk
+		}
+		return nil
+	})
+	if err != nil && err != errStopWalk {
+		return summary, err
+	}
+	return summary, nil
+}
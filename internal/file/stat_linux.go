@@ -0,0 +1,149 @@
+//go:build linux
+
+package file
+
+import (
+	"os"
+	"os/user"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// userNames、groupNames 缓存 ID 到名称的解析结果，列出大目录时避免重复读取 /etc/passwd 和 /etc/group
+var (
+	userNames  sync.Map
+	groupNames sync.Map
+)
+
+// fillPlatformInfo 从 stat 结果中补充 Linux 提供的元数据
+// 创建时间需要额外的 statx 调用，所有者和组名需要查询用户数据库，因此只在 fields 需要时获取
+func fillPlatformInfo(fileInfo *FileInfo, info os.FileInfo, path string, fields FieldSet) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return
+	}
+
+	accessTime := time.Unix(int64(stat.Atim.Sec), int64(stat.Atim.Nsec))
+	changeTime := time.Unix(int64(stat.Ctim.Sec), int64(stat.Ctim.Nsec))
+	uid, gid := stat.Uid, stat.Gid
+	blocks := int64(stat.Blocks)
+
+	fileInfo.AccessTime = &accessTime
+	fileInfo.ChangeTime = &changeTime
+	fileInfo.UID = &uid
+	fileInfo.GID = &gid
+	fileInfo.Inode = stat.Ino
+	fileInfo.Device = uint64(stat.Dev)
+	fileInfo.Nlink = uint64(stat.Nlink)
+	fileInfo.Blocks = &blocks
+
+	if fields.Has("owner") {
+		fileInfo.Owner = lookupName(&userNames, uid, func(id string) (string, error) {
+			u, err := user.LookupId(id)
+			if err != nil {
+				return "", err
+			}
+			return u.Username, nil
+		})
+	}
+	if fields.Has("group") {
+		fileInfo.Group = lookupName(&groupNames, gid, func(id string) (string, error) {
+			g, err := user.LookupGroupId(id)
+			if err != nil {
+				return "", err
+			}
+			return g.Name, nil
+		})
+	}
+	if fields.Has("createTime") {
+		if birthTime, ok := statxBirthTime(path, info.Mode()&os.ModeSymlink != 0); ok {
+			fileInfo.CreateTime = &birthTime
+		}
+	}
+}
+
+// fileInode 返回文件的 inode 编号
+func fileInode(info os.FileInfo) uint64 {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return stat.Ino
+	}
+	return 0
+}
+
+// fileDevice 返回文件所在设备的编号
+func fileDevice(info os.FileInfo) uint64 {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(stat.Dev)
+	}
+	return 0
+}
+
+// fileLinks 返回指向文件的硬链接数
+func fileLinks(info os.FileInfo) uint64 {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(stat.Nlink)
+	}
+	return 0
+}
+
+// fileAllocated 返回文件实际占用的磁盘空间
+func fileAllocated(info os.FileInfo) int64 {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return int64(stat.Blocks) * 512
+	}
+	return info.Size()
+}
+
+// filesystemUsage 返回 path 所在文件系统的容量和 inode 使用情况
+func filesystemUsage(path string) (*FilesystemUsage, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return nil, err
+	}
+	bsize := uint64(st.Bsize)
+	usage := &FilesystemUsage{
+		Total:      st.Blocks * bsize,
+		Free:       st.Bfree * bsize,
+		Available:  st.Bavail * bsize,
+		Used:       (st.Blocks - st.Bfree) * bsize,
+		Inodes:     st.Files,
+		InodesFree: st.Ffree,
+		InodesUsed: st.Files - st.Ffree,
+	}
+	return usage, nil
+}
+
+// lookupName 查询并缓存 ID 对应的名称，无法解析时返回空字符串
+func lookupName(cache *sync.Map, id uint32, lookup func(string) (string, error)) string {
+	if name, ok := cache.Load(id); ok {
+		return name.(string)
+	}
+	name, err := lookup(strconv.FormatUint(uint64(id), 10))
+	if err != nil {
+		name = ""
+	}
+	cache.Store(id, name)
+	return name
+}
+
+// statxBirthTime 通过 statx 获取创建时间，内核或文件系统不支持时返回 false
+// noFollow 与获取 os.FileInfo 时是否跟随符号链接保持一致
+func statxBirthTime(path string, noFollow bool) (time.Time, bool) {
+	flags := unix.AT_STATX_SYNC_AS_STAT
+	if noFollow {
+		flags |= unix.AT_SYMLINK_NOFOLLOW
+	}
+
+	var stx unix.Statx_t
+	if err := unix.Statx(unix.AT_FDCWD, path, flags, unix.STATX_BTIME, &stx); err != nil {
+		return time.Time{}, false
+	}
+	if stx.Mask&unix.STATX_BTIME == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(stx.Btime.Sec, int64(stx.Btime.Nsec)), true
+}
@@ -0,0 +1,23 @@
+//go:build !linux
+
+package file
+
+import "os"
+
+// fillPlatformInfo 非 Linux 平台不提供额外的元数据，相应字段被省略
+func fillPlatformInfo(fileInfo *FileInfo, info os.FileInfo, path string, fields FieldSet) {}
+
+// fileInode 非 Linux 平台不提供 inode 编号，返回 0
+func fileInode(info os.FileInfo) uint64 { return 0 }
+
+// fileDevice 非 Linux 平台不提供设备编号，返回 0
+func fileDevice(info os.FileInfo) uint64 { return 0 }
+
+// fileLinks 非 Linux 平台不提供硬链接数，返回 0
+func fileLinks(info os.FileInfo) uint64 { return 0 }
+
+// fileAllocated 非 Linux 平台不提供占用的块数，返回文件大小
+func fileAllocated(info os.FileInfo) int64 { return info.Size() }
+
+// filesystemUsage 非 Linux 平台不提供文件系统用量，返回 nil
+func filesystemUsage(path string) (*FilesystemUsage, error) { return nil, nil }
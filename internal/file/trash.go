@@ -0,0 +1,628 @@
+package file
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"jia-file/internal/config"
+	"jia-file/internal/logger"
+)
+
+const (
+	// trashInfoExt 回收站信息文件的扩展名
+	trashInfoExt = ".trashinfo"
+	// trashDateLayout 信息文件中 DeletionDate 的格式，按规范使用不带时区的本地时间
+	trashDateLayout = "2006-01-02T15:04:05"
+)
+
+// TrashItem 回收站中的条目
+type TrashItem struct {
+	ID           string    `json:"id"`           // 条目在回收站中的名称，用于恢复和清除
+	Name         string    `json:"name"`         // 原文件名
+	OriginalPath string    `json:"originalPath"` // 删除前的路径
+	DeletedAt    time.Time `json:"deletedAt"`    // 删除时间
+	IsDir        bool      `json:"isDir"`        // 是否为目录
+	Size         int64     `json:"size"`         // 占用的字节数，目录为其中所有文件的大小之和
+}
+
+// TrashListOptions 列出回收站选项
+type TrashListOptions struct {
+	Path string // 只返回原路径位于此路径之下的条目，为空时返回全部
+}
+
+// TrashList 回收站内容
+type TrashList struct {
+	Items []TrashItem `json:"items"` // 条目，最近删除的在前
+	Total int         `json:"total"` // 条目数
+	Size  int64       `json:"size"`  // 条目的总字节数
+}
+
+// RestoreOptions 从回收站恢复的选项
+type RestoreOptions struct {
+	Dst      string         // 恢复到的路径，为空时恢复到原路径
+	Conflict ConflictPolicy // 目标路径已存在时的冲突策略
+	Progress *Progress      // 可选，用于报告跨文件系统移动的进度
+}
+
+// PurgeOptions 永久清除回收站条目的选项，IDs、All 和 Before 至少指定一个
+type PurgeOptions struct {
+	IDs      []string  // 要清除的条目
+	All      bool      // 清空回收站
+	Before   time.Time // 清除在此时间之前删除的条目
+	Progress *Progress // 可选，用于报告进度
+}
+
+// PurgeResult 清除结果
+type PurgeResult struct {
+	Purged int      `json:"purged"`           // 清除的条目数
+	Bytes  int64    `json:"bytes"`            // 释放的字节数
+	Failed int      `json:"failed"`           // 清除失败的条目数
+	Errors []string `json:"errors,omitempty"` // 失败原因，最多 maxReportedErrors 条
+}
+
+// addError 记录一个失败的条目
+func (r *PurgeResult) addError(err error) {
+	r.Failed++
+	if len(r.Errors) < maxReportedErrors {
+		r.Errors = append(r.Errors, err.Error())
+	}
+}
+
+// trash 兼容 freedesktop.org Trash 规范的回收站：
+// files/ 保存被删除的条目，info/ 保存同名的 .trashinfo 文件记录原路径和删除时间，
+// directorysizes 缓存目录条目的大小
+type trash struct {
+	dir string
+	mu  sync.Mutex // 保护 directorysizes 的读写
+}
+
+// newTrash 根据配置确定回收站目录：未指定时使用根目录下的 .Trash-<uid>，未设置根目录时使用 data/trash
+func newTrash(cfg *config.Config) *trash {
+	dir := cfg.Trash.Dir
+	if dir == "" {
+		if cfg.File.RootPath != "" {
+			dir = filepath.Join(cfg.File.RootPath, fmt.Sprintf(".Trash-%d", os.Getuid()))
+		} else {
+			dir = filepath.Join("data", "trash")
+		}
+	}
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	return &trash{dir: dir}
+}
+
+func (t *trash) filesDir() string          { return filepath.Join(t.dir, "files") }
+func (t *trash) infoDir() string           { return filepath.Join(t.dir, "info") }
+func (t *trash) filePath(id string) string { return filepath.Join(t.filesDir(), id) }
+func (t *trash) infoPath(id string) string { return filepath.Join(t.infoDir(), id+trashInfoExt) }
+func (t *trash) sizesPath() string         { return filepath.Join(t.dir, "directorysizes") }
+func (t *trash) topDir() string            { return filepath.Dir(t.dir) }
+func (t *trash) contains(path string) bool { return path == t.dir || isSubPath(t.dir, path) }
+func (t *trash) within(path string) bool   { return isSubPath(path, t.dir) }
+
+// validTrashID 检查条目名称，防止通过 ID 访问回收站之外的路径
+func validTrashID(id string) bool {
+	return id != "" && id != "." && id != ".." && !strings.ContainsAny(id, `/\`) && !strings.ContainsRune(id, 0)
+}
+
+// encodeTrashPath 按规范编码原路径，位于回收站所在目录之下时使用相对路径
+func (t *trash) encodeTrashPath(path string) string {
+	if rel, err := filepath.Rel(t.topDir(), path); err == nil && isSubPath(t.topDir(), path) {
+		path = rel
+	}
+	return (&url.URL{Path: filepath.ToSlash(path)}).EscapedPath()
+}
+
+// decodeTrashPath 解码原路径，相对路径相对于回收站所在目录
+func (t *trash) decodeTrashPath(value string) (string, error) {
+	path, err := url.PathUnescape(value)
+	if err != nil {
+		return "", err
+	}
+	path = filepath.FromSlash(path)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(t.topDir(), path)
+	}
+	return filepath.Clean(path), nil
+}
+
+// reserve 以 O_EXCL 创建信息文件占用一个不冲突的名称，返回条目 ID
+func (t *trash) reserve(path string, deletedAt time.Time) (string, error) {
+	if err := os.MkdirAll(t.filesDir(), 0700); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(t.infoDir(), 0700); err != nil {
+		return "", err
+	}
+
+	content := fmt.Sprintf("[Trash Info]\nPath=%s\nDeletionDate=%s\n", t.encodeTrashPath(path), deletedAt.Format(trashDateLayout))
+	base := filepath.Base(path)
+	ext := filepath.Ext(base)
+	name := strings.TrimSuffix(base, ext)
+	for i := 1; i < 10000; i++ {
+		id := base
+		if i > 1 {
+			id = fmt.Sprintf("%s.%d%s", name, i, ext)
+		}
+		if _, err := os.Lstat(t.filePath(id)); err == nil {
+			continue
+		}
+		f, err := os.OpenFile(t.infoPath(id), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		_, err = f.WriteString(content)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(t.infoPath(id))
+			return "", err
+		}
+		return id, nil
+	}
+	return "", fmt.Errorf("unable to find a free name in trash for: %s", path)
+}
+
+// readInfo 解析条目的信息文件
+func (t *trash) readInfo(id string) (string, time.Time, error) {
+	f, err := os.Open(t.infoPath(id))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	defer f.Close()
+
+	var path, date string
+	scanner := bufio.NewScanner(f)
+	for first := true; scanner.Scan(); first = false {
+		line := strings.TrimSpace(scanner.Text())
+		if first && line != "[Trash Info]" {
+			return "", time.Time{}, fmt.Errorf("invalid trash info: %s", id)
+		}
+		if key, value, ok := strings.Cut(line, "="); ok {
+			switch key {
+			case "Path":
+				path = value
+			case "DeletionDate":
+				date = value
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", time.Time{}, err
+	}
+	if path == "" {
+		return "", time.Time{}, fmt.Errorf("invalid trash info: %s", id)
+	}
+
+	originalPath, err := t.decodeTrashPath(path)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("invalid trash info: %s", id)
+	}
+	deletedAt, _ := time.ParseInLocation(trashDateLayout, date, time.Local)
+	return originalPath, deletedAt, nil
+}
+
+// dirSize 目录条目的缓存大小
+type dirSize struct {
+	size  int64
+	mtime int64 // 缓存时信息文件的修改时间（秒）
+}
+
+// readSizes 读取 directorysizes，每行为 "大小 信息文件修改时间 编码后的条目名称"
+func (t *trash) readSizes() map[string]dirSize {
+	sizes := make(map[string]dirSize)
+	content, err := os.ReadFile(t.sizesPath())
+	if err != nil {
+		return sizes
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+		size, err1 := strconv.ParseInt(fields[0], 10, 64)
+		mtime, err2 := strconv.ParseInt(fields[1], 10, 64)
+		id, err3 := url.PathUnescape(fields[2])
+		if err1 == nil && err2 == nil && err3 == nil {
+			sizes[id] = dirSize{size: size, mtime: mtime}
+		}
+	}
+	return sizes
+}
+
+// writeSizes 原子地写入 directorysizes
+func (t *trash) writeSizes(sizes map[string]dirSize) error {
+	var b strings.Builder
+	for id, entry := range sizes {
+		fmt.Fprintf(&b, "%d %d %s\n", entry.size, entry.mtime, url.PathEscape(id))
+	}
+	tmp := tempSibling(t.sizesPath(), "tmp")
+	if err := os.WriteFile(tmp, []byte(b.String()), 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, t.sizesPath()); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// setSize 更新目录条目的缓存大小，size 为负数时删除缓存
+func (t *trash) setSize(id string, size int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	sizes := t.readSizes()
+	if size < 0 {
+		if _, ok := sizes[id]; !ok {
+			return
+		}
+		delete(sizes, id)
+	} else {
+		info, err := os.Stat(t.infoPath(id))
+		if err != nil {
+			return
+		}
+		sizes[id] = dirSize{size: size, mtime: info.ModTime().Unix()}
+	}
+	if err := t.writeSizes(sizes); err != nil {
+		logger.Error("Write trash directorysizes error: %v", err)
+	}
+}
+
+// treeSize 统计路径下所有普通文件的大小之和
+func treeSize(ctx context.Context, path string) int64 {
+	progress := &Progress{}
+	measureTree(ctx, path, false, progress)
+	return progress.Snapshot().BytesTotal
+}
+
+// item 读取单个条目，files/ 中缺少对应内容的信息文件视为无效
+func (t *trash) item(ctx context.Context, id string, sizes map[string]dirSize) (TrashItem, bool, error) {
+	originalPath, deletedAt, err := t.readInfo(id)
+	if err != nil {
+		return TrashItem{}, false, err
+	}
+	info, err := os.Lstat(t.filePath(id))
+	if err != nil {
+		return TrashItem{}, false, err
+	}
+
+	item := TrashItem{
+		ID:           id,
+		Name:         filepath.Base(originalPath),
+		OriginalPath: originalPath,
+		DeletedAt:    deletedAt,
+		IsDir:        info.IsDir(),
+		Size:         info.Size(),
+	}
+	if !info.IsDir() {
+		return item, false, nil
+	}
+
+	infoStat, err := os.Stat(t.infoPath(id))
+	if cached, ok := sizes[id]; ok && err == nil && cached.mtime == infoStat.ModTime().Unix() {
+		item.Size = cached.size
+		return item, false, nil
+	}
+	item.Size = treeSize(ctx, t.filePath(id))
+	return item, true, nil
+}
+
+// list 读取回收站中的所有有效条目，最近删除的在前，同时补全 directorysizes 中缺少的目录大小
+func (t *trash) list(ctx context.Context) ([]TrashItem, error) {
+	entries, err := readDir(ctx, t.infoDir())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	sizes := t.readSizes()
+	t.mu.Unlock()
+
+	var items []TrashItem
+	var measured []TrashItem
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		id, ok := strings.CutSuffix(entry.Name(), trashInfoExt)
+		if !ok || !validTrashID(id) {
+			continue
+		}
+		item, fresh, err := t.item(ctx, id, sizes)
+		if err != nil {
+			// 正在移入回收站或已损坏的条目
+			continue
+		}
+		items = append(items, item)
+		if fresh {
+			measured = append(measured, item)
+		}
+	}
+	for _, item := range measured {
+		t.setSize(item.ID, item.Size)
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].DeletedAt.Equal(items[j].DeletedAt) {
+			return items[i].DeletedAt.After(items[j].DeletedAt)
+		}
+		return items[i].ID < items[j].ID
+	})
+	return items, nil
+}
+
+// moveToTrash 将处理后的路径移入回收站，先写入信息文件再移动，移动失败时删除信息文件
+func (s *service) moveToTrash(ctx context.Context, processedPath string, info os.FileInfo, progress *Progress) (*TrashItem, error) {
+	if s.trash.contains(processedPath) {
+		return nil, fmt.Errorf("path is inside the trash, use permanent deletion: %s", processedPath)
+	}
+	if s.trash.within(processedPath) {
+		return nil, fmt.Errorf("cannot move %s to trash: it contains the trash directory", processedPath)
+	}
+
+	deletedAt := time.Now()
+	id, err := s.trash.reserve(processedPath, deletedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to move to trash: %v", err)
+	}
+
+	size := info.Size()
+	if info.IsDir() {
+		size = treeSize(ctx, processedPath)
+	}
+	if err := moveTo(ctx, processedPath, s.trash.filePath(id), progress); err != nil {
+		os.Remove(s.trash.infoPath(id))
+		return nil, fmt.Errorf("failed to move to trash: %v", err)
+	}
+	if info.IsDir() {
+		s.trash.setSize(id, size)
+	}
+	s.notify(ChangeEvent{Op: ChangeRemove, Path: processedPath})
+
+	return &TrashItem{
+		ID:           id,
+		Name:         filepath.Base(processedPath),
+		OriginalPath: processedPath,
+		DeletedAt:    deletedAt.Truncate(time.Second),
+		IsDir:        info.IsDir(),
+		Size:         size,
+	}, nil
+}
+
+// ListTrash 实现 Service 接口的 ListTrash 方法
+func (s *service) ListTrash(ctx context.Context, opts TrashListOptions) (TrashList, error) {
+	var filter string
+	if opts.Path != "" {
+		processedPath, err := s.pathProcessor.ProcessPathNoFollow(opts.Path)
+		if err != nil {
+			return TrashList{}, err
+		}
+		filter = processedPath
+	}
+
+	items, err := s.trash.list(ctx)
+	if err != nil {
+		return TrashList{}, err
+	}
+	result := TrashList{Items: make([]TrashItem, 0, len(items))}
+	for _, item := range items {
+		if filter != "" && item.OriginalPath != filter && !isSubPath(filter, item.OriginalPath) {
+			continue
+		}
+		result.Items = append(result.Items, item)
+		result.Size += item.Size
+	}
+	result.Total = len(result.Items)
+	return result, nil
+}
+
+// RestoreTrash 实现 Service 接口的 RestoreTrash 方法
+// 原路径的上级目录不存在时自动创建；冲突策略为 skip 且目标已存在时条目保留在回收站中
+func (s *service) RestoreTrash(ctx context.Context, id string, opts RestoreOptions) (FileInfo, error) {
+	if !validTrashID(id) {
+		return FileInfo{}, fmt.Errorf("invalid trash item id: %s", id)
+	}
+	originalPath, _, err := s.trash.readInfo(id)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return FileInfo{}, fmt.Errorf("trash item does not exist: %s", id)
+		}
+		return FileInfo{}, err
+	}
+	src := s.trash.filePath(id)
+	if _, err := os.Lstat(src); err != nil {
+		return FileInfo{}, fmt.Errorf("trash item does not exist: %s", id)
+	}
+
+	dst := opts.Dst
+	if dst == "" {
+		dst = originalPath
+	}
+	processedDst, err := s.pathProcessor.ProcessPathNoFollow(dst)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	if s.trash.contains(processedDst) {
+		return FileInfo{}, fmt.Errorf("cannot restore into the trash: %s", dst)
+	}
+
+	target, exists, err := resolveConflict(processedDst, opts.Conflict)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	if exists && opts.Conflict == ConflictSkip {
+		info, err := os.Lstat(target)
+		if err != nil {
+			return FileInfo{}, err
+		}
+		return buildFileInfo(info, target, target), nil
+	}
+
+	if !exists {
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return FileInfo{}, fmt.Errorf("failed to create parent directory: %v", err)
+		}
+		err = moveTo(ctx, src, target, opts.Progress)
+	} else if err = s.saveVersion(ctx, target, VersionOverwrite); err == nil {
+		err = replace(ctx, src, target, opts.Progress)
+	}
+	if err != nil {
+		return FileInfo{}, err
+	}
+	os.Remove(s.trash.infoPath(id))
+	s.trash.setSize(id, -1)
+	s.notify(ChangeEvent{Op: ChangeWrite, Path: target})
+
+	info, err := os.Lstat(target)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	return buildFileInfo(info, target, target), nil
+}
+
+// purge 永久删除一个条目，先删除内容再删除信息文件
+func (t *trash) purge(ctx context.Context, item TrashItem, progress *Progress) error {
+	if err := removeTree(ctx, t.filePath(item.ID), progress); err != nil {
+		return err
+	}
+	if err := os.Remove(t.infoPath(item.ID)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if item.IsDir {
+		t.setSize(item.ID, -1)
+	}
+	return nil
+}
+
+// PurgeTrash 实现 Service 接口的 PurgeTrash 方法
+func (s *service) PurgeTrash(ctx context.Context, opts PurgeOptions) (PurgeResult, error) {
+	if len(opts.IDs) == 0 && !opts.All && opts.Before.IsZero() {
+		return PurgeResult{}, fmt.Errorf("no trash items specified")
+	}
+	wanted := make(map[string]bool, len(opts.IDs))
+	for _, id := range opts.IDs {
+		if !validTrashID(id) {
+			return PurgeResult{}, fmt.Errorf("invalid trash item id: %s", id)
+		}
+		wanted[id] = true
+	}
+
+	items, err := s.trash.list(ctx)
+	if err != nil {
+		return PurgeResult{}, err
+	}
+	var selected []TrashItem
+	var total int64
+	found := make(map[string]bool, len(items))
+	for _, item := range items {
+		found[item.ID] = true
+		if opts.All || wanted[item.ID] || (!opts.Before.IsZero() && item.DeletedAt.Before(opts.Before)) {
+			selected = append(selected, item)
+			total += item.Size
+		}
+	}
+	for _, id := range opts.IDs {
+		if !found[id] {
+			return PurgeResult{}, fmt.Errorf("trash item does not exist: %s", id)
+		}
+	}
+	return s.purgeItems(ctx, selected, total, opts.Progress)
+}
+
+// purgeItems 依次永久删除条目
+func (s *service) purgeItems(ctx context.Context, items []TrashItem, total int64, progress *Progress) (PurgeResult, error) {
+	progress.SetTotal(total, int64(len(items)))
+	var result PurgeResult
+	for _, item := range items {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		if err := s.trash.purge(ctx, item, nil); err != nil {
+			if ctx.Err() != nil {
+				return result, ctx.Err()
+			}
+			result.addError(fmt.Errorf("%s: %v", item.ID, err))
+			continue
+		}
+		result.Purged++
+		result.Bytes += item.Size
+		progress.AddBytes(item.Size)
+		progress.AddEntries(1)
+	}
+	return result, nil
+}
+
+// purgeExpired 清除超过保留时间的条目，并在总大小超出限制时从最早删除的条目开始清除
+func (s *service) purgeExpired(ctx context.Context) (PurgeResult, error) {
+	items, err := s.trash.list(ctx)
+	if err != nil {
+		return PurgeResult{}, err
+	}
+
+	var expired []TrashItem
+	var total, remaining int64
+	var cutoff time.Time
+	if s.config.Trash.MaxAge > 0 {
+		cutoff = time.Now().Add(-s.config.Trash.MaxAge)
+	}
+	for _, item := range items {
+		remaining += item.Size
+	}
+	// items 按删除时间倒序，从末尾开始即从最早删除的开始
+	for i := len(items) - 1; i >= 0; i-- {
+		item := items[i]
+		tooOld := !cutoff.IsZero() && item.DeletedAt.Before(cutoff)
+		tooLarge := s.config.Trash.MaxSize > 0 && remaining > s.config.Trash.MaxSize
+		if !tooOld && !tooLarge {
+			break
+		}
+		expired = append(expired, item)
+		total += item.Size
+		remaining -= item.Size
+	}
+	return s.purgeItems(ctx, expired, total, nil)
+}
+
+// StartTrashPurge 实现 Service 接口的 StartTrashPurge 方法
+func (s *service) StartTrashPurge(interval time.Duration, stop <-chan struct{}) {
+	if interval <= 0 || (s.config.Trash.MaxAge <= 0 && s.config.Trash.MaxSize <= 0) {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				result, err := s.purgeExpired(context.Background())
+				if err != nil {
+					logger.Error("Purge trash error: %v", err)
+				} else if result.Purged > 0 || result.Failed > 0 {
+					logger.Info("Purged %d trash items (%d bytes), %d failed", result.Purged, result.Bytes, result.Failed)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,158 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTrashTestService 创建启用回收站的测试服务
+func newTrashTestService(t *testing.T) (*service, string) {
+	t.Helper()
+	s, root := newVersionTestService(t, nil)
+	s.config.Trash.Enabled = true
+	return s, root
+}
+
+// addTrashItem 直接在回收站中写入一个条目，用于构造指定删除时间和大小的条目
+func addTrashItem(t *testing.T, s *service, id, path string, size int, deletedAt time.Time) {
+	t.Helper()
+	writeFile(t, s.trash.filePath(id), strings.Repeat("x", size))
+	writeFile(t, s.trash.infoPath(id), fmt.Sprintf("[Trash Info]\nPath=%s\nDeletionDate=%s\n", path, deletedAt.Format(trashDateLayout)))
+}
+
+// trashIDs 返回回收站中的条目 ID，按名称排序
+func trashIDs(t *testing.T, s *service) []string {
+	t.Helper()
+	items, err := s.trash.list(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := make([]string, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func TestRestoreTrashConflict(t *testing.T) {
+	tests := []struct {
+		conflict  ConflictPolicy
+		wantErr   bool
+		wantData  string // 原路径上的内容
+		wantKept  bool   // 条目是否仍留在回收站中
+		wantOther bool   // 是否恢复到了另一个名称
+	}{
+		{conflict: ConflictFail, wantErr: true, wantData: "new", wantKept: true},
+		{conflict: ConflictSkip, wantData: "new", wantKept: true},
+		{conflict: ConflictOverwrite, wantData: "old"},
+		{conflict: ConflictRename, wantData: "new", wantOther: true},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.conflict), func(t *testing.T) {
+			s, root := newTrashTestService(t)
+			ctx := context.Background()
+			path := filepath.Join(root, "a.txt")
+			writeFile(t, path, "old")
+			item, err := s.Delete(ctx, path, DeleteOptions{})
+			if err != nil || item == nil {
+				t.Fatalf("Delete() = %v, %v", item, err)
+			}
+			writeFile(t, path, "new")
+
+			info, err := s.RestoreTrash(ctx, item.ID, RestoreOptions{Conflict: tt.conflict})
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("RestoreTrash() error = %v, want error %v", err, tt.wantErr)
+			}
+			if data, _ := os.ReadFile(path); string(data) != tt.wantData {
+				t.Errorf("%s = %q, want %q", path, data, tt.wantData)
+			}
+			if kept := len(trashIDs(t, s)) == 1; kept != tt.wantKept {
+				t.Errorf("item kept in trash = %v, want %v", kept, tt.wantKept)
+			}
+			if tt.wantOther {
+				if info.Path == path {
+					t.Fatalf("RestoreTrash() restored onto %s, want a new name", path)
+				}
+				if data, _ := os.ReadFile(info.Path); string(data) != "old" {
+					t.Errorf("%s = %q, want old", info.Path, data)
+				}
+			}
+		})
+	}
+}
+
+func TestRestoreTrashOutsideRoot(t *testing.T) {
+	s, root := newTrashTestService(t)
+	outside := t.TempDir()
+	// 伪造的信息文件：绝对路径和相对于回收站所在目录向上逃逸的路径都指向根目录之外
+	forged := map[string]string{
+		"abs": filepath.ToSlash(filepath.Join(outside, "abs")),
+		"rel": "../../../../../../../../" + strings.TrimPrefix(filepath.ToSlash(filepath.Join(outside, "rel")), "/"),
+	}
+	for id, path := range forged {
+		addTrashItem(t, s, id, path, 1, time.Now())
+		if _, err := s.RestoreTrash(context.Background(), id, RestoreOptions{}); !errors.Is(err, ErrOutsideRoot) {
+			t.Errorf("RestoreTrash(%s) error = %v, want ErrOutsideRoot", id, err)
+		}
+		if _, err := os.Lstat(filepath.Join(outside, id)); !os.IsNotExist(err) {
+			t.Errorf("%s restored outside root", id)
+		}
+	}
+	if ids := trashIDs(t, s); len(ids) != 2 {
+		t.Errorf("trash items = %v, want both kept", ids)
+	}
+
+	// 指定根目录内的目标时仍可恢复
+	dst := filepath.Join(root, "restored")
+	if _, err := s.RestoreTrash(context.Background(), "abs", RestoreOptions{Dst: dst}); err != nil {
+		t.Errorf("RestoreTrash(abs, %s) error = %v", dst, err)
+	}
+}
+
+func TestPurgeExpired(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name    string
+		maxAge  time.Duration
+		maxSize int64
+		want    []string // 清除后剩余的条目
+	}{
+		{name: "age", maxAge: 24 * time.Hour, want: []string{"day", "hour"}},
+		// 总大小 40 超过 25，从最早删除的开始清除到不超过限制
+		{name: "size", maxSize: 25, want: []string{"day", "hour"}},
+		{name: "size exact", maxSize: 30, want: []string{"day", "hour", "week"}},
+		{name: "age and size", maxAge: 24 * time.Hour, maxSize: 15, want: []string{"hour"}},
+		{name: "unlimited", want: []string{"day", "hour", "month", "week"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, root := newTrashTestService(t)
+			s.config.Trash.MaxAge = tt.maxAge
+			s.config.Trash.MaxSize = tt.maxSize
+			addTrashItem(t, s, "month", filepath.Join(root, "month"), 10, now.Add(-30*24*time.Hour))
+			addTrashItem(t, s, "week", filepath.Join(root, "week"), 10, now.Add(-7*24*time.Hour))
+			addTrashItem(t, s, "day", filepath.Join(root, "day"), 10, now.Add(-23*time.Hour))
+			addTrashItem(t, s, "hour", filepath.Join(root, "hour"), 10, now.Add(-time.Hour))
+
+			result, err := s.purgeExpired(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := trashIDs(t, s)
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("remaining = %v, want %v", got, tt.want)
+			}
+			if purged := 4 - len(tt.want); result.Purged != purged || result.Bytes != int64(purged*10) {
+				t.Errorf("purgeExpired() = %+v, want %d purged", result, purged)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// TreeOptions 目录树选项
+type TreeOptions struct {
+	Depth      int      // 展开的层数，1 表示只展开根目录的直接子条目
+	MaxNodes   int      // 最多返回的节点数（不含根节点）
+	HideHidden bool     // 是否隐藏以 "." 开头的条目
+	DirsOnly   bool     // 是否只返回目录
+	Fields     FieldSet // 字段投影
+}
+
+// TreeNode 目录树节点
+type TreeNode struct {
+	FileInfo
+	Children   []*TreeNode `json:"children,omitempty"`  // 已展开的子节点
+	ChildCount int         `json:"childCount"`          // 直接子条目数（目录）
+	TotalSize  int64       `json:"totalSize"`           // 已遍历部分的文件总大小（目录）
+	Truncated  bool        `json:"truncated,omitempty"` // 子树因层数或节点数限制未完全展开，此时 TotalSize 为下限
+}
+
+// TreeResult 目录树结果
+type TreeResult struct {
+	Root      *TreeNode `json:"root"`      // 根节点
+	Nodes     int       `json:"nodes"`     // 返回的节点数（不含根节点）
+	Truncated bool      `json:"truncated"` // 是否因节点数限制提前结束
+}
+
+// treeItem 广度优先遍历队列中的目录
+type treeItem struct {
+	node  *TreeNode
+	path  string
+	depth int
+}
+
+// Tree 实现 Service 接口的 Tree 方法
+// 按广度优先展开目录，保证较浅的层级优先返回，节点数达到上限后停止展开
+func (s *service) Tree(ctx context.Context, path string, opts TreeOptions) (TreeResult, error) {
+	processedPath, err := s.pathProcessor.ProcessPath(path)
+	if err != nil {
+		return TreeResult{}, err
+	}
+
+	info, err := os.Lstat(processedPath)
+	if err != nil {
+		return TreeResult{}, err
+	}
+	if !info.IsDir() {
+		return TreeResult{}, fmt.Errorf("path is not a directory: %s", path)
+	}
+
+	if opts.Depth <= 0 {
+		opts.Depth = 1
+	}
+
+	root := &TreeNode{FileInfo: buildFileInfo(info, processedPath, processedPath)}
+	result := TreeResult{Root: root}
+	parents := make(map[*TreeNode]*TreeNode)
+
+	queue := []treeItem{{node: root, path: processedPath, depth: 0}}
+	for len(queue) > 0 {
+		if err := ctx.Err(); err != nil {
+			return TreeResult{}, err
+		}
+
+		item := queue[0]
+		queue = queue[1:]
+
+		entries, err := readDir(ctx, item.path)
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return TreeResult{}, ctxErr
+			}
+			// 无权限读取的目录作为未展开的节点返回
+			item.node.Truncated = true
+			continue
+		}
+
+		visible := make([]os.DirEntry, 0, len(entries))
+		for _, entry := range entries {
+			if opts.HideHidden && strings.HasPrefix(entry.Name(), ".") {
+				continue
+			}
+			if opts.DirsOnly && !entry.IsDir() {
+				continue
+			}
+			if s.pathProcessor.Reserved(filepath.Join(item.path, entry.Name())) {
+				continue
+			}
+			visible = append(visible, entry)
+		}
+		item.node.ChildCount = len(visible)
+
+		// 已到达层数限制或节点预算耗尽：只统计子条目数，不展开
+		if item.depth >= opts.Depth || result.Truncated {
+			item.node.Truncated = item.node.ChildCount > 0
+			continue
+		}
+
+		for _, entry := range visible {
+			if opts.MaxNodes > 0 && result.Nodes >= opts.MaxNodes {
+				result.Truncated = true
+				item.node.Truncated = true
+				break
+			}
+
+			fileInfo, err := getFileInfo(entry, item.path, opts.Fields)
+			if err != nil {
+				continue
+			}
+			child := &TreeNode{FileInfo: fileInfo}
+			item.node.Children = append(item.node.Children, child)
+			parents[child] = item.node
+			result.Nodes++
+
+			if entry.IsDir() {
+				queue = append(queue, treeItem{node: child, path: filepath.Join(item.path, entry.Name()), depth: item.depth + 1})
+			} else if entry.Type().IsRegular() {
+				// 文件大小逐级累加到所有祖先目录
+				for ancestor := item.node; ancestor != nil; ancestor = parents[ancestor] {
+					ancestor.TotalSize += fileInfo.Size
+				}
+			}
+		}
+	}
+
+	// 子树未完全展开时，祖先的统计也不完整
+	markTruncated(root)
+	return result, nil
+}
+
+// markTruncated 自底向上传播 Truncated 标记
+func markTruncated(node *TreeNode) bool {
+	for _, child := range node.Children {
+		if markTruncated(child) {
+			node.Truncated = true
+		}
+	}
+	return node.Truncated
+}
@@ -0,0 +1,390 @@
+package file
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultUsageTop 默认返回的最大文件、目录和子条目数
+	DefaultUsageTop = 10
+	// MaxUsageTop 允许返回的最大文件、目录和子条目数，缓存中保存这么多条
+	MaxUsageTop = 1000
+)
+
+// DiskUsageOptions 磁盘用量统计选项
+type DiskUsageOptions struct {
+	Top      int       // 返回的最大文件、目录和子条目数，0 表示使用 DefaultUsageTop
+	Refresh  bool      // 是否忽略缓存重新统计
+	Progress *Progress // 进度，可为 nil
+}
+
+// UsageEntry 文件或目录的用量，目录为其子树的合计
+type UsageEntry struct {
+	Path      string `json:"path"`            // 路径
+	IsDir     bool   `json:"isDir"`           // 是否为目录
+	Size      int64  `json:"size"`            // 表观大小：文件内容的字节数之和，不含目录本身
+	Allocated int64  `json:"allocated"`       // 实际占用的磁盘空间，包含目录本身占用的块
+	Files     int64  `json:"files,omitempty"` // 子树中的文件数（含符号链接等非目录条目）
+	Dirs      int64  `json:"dirs,omitempty"`  // 子树中的目录数
+}
+
+// FilesystemUsage 文件系统的容量和 inode 使用情况
+type FilesystemUsage struct {
+	Total      uint64 `json:"total"`      // 总容量
+	Free       uint64 `json:"free"`       // 剩余空间，含只有 root 可用的保留空间
+	Available  uint64 `json:"available"`  // 普通用户可用的空间
+	Used       uint64 `json:"used"`       // 已用空间
+	Inodes     uint64 `json:"inodes"`     // inode 总数
+	InodesFree uint64 `json:"inodesFree"` // 剩余 inode 数
+	InodesUsed uint64 `json:"inodesUsed"` // 已用 inode 数
+}
+
+// DiskUsage 目录的磁盘用量
+type DiskUsage struct {
+	Path         string           `json:"path"`                 // 目录路径
+	Size         int64            `json:"size"`                 // 表观大小
+	Allocated    int64            `json:"allocated"`            // 实际占用的磁盘空间
+	Files        int64            `json:"files"`                // 文件数
+	Dirs         int64            `json:"dirs"`                 // 子目录数
+	Errors       int              `json:"errors"`               // 无法读取而未计入的条目数
+	Children     []UsageEntry     `json:"children"`             // 最大的直接子条目
+	LargestFiles []UsageEntry     `json:"largestFiles"`         // 子树中最大的文件
+	LargestDirs  []UsageEntry     `json:"largestDirs"`          // 子树中最大的目录，不含目录本身
+	Filesystem   *FilesystemUsage `json:"filesystem,omitempty"` // 目录所在文件系统的用量，总是实时获取
+	ScannedAt    time.Time        `json:"scannedAt"`            // 统计开始的时间
+	Cached       bool             `json:"cached"`               // 是否来自缓存
+	Stale        bool             `json:"stale"`                // 缓存是否已过期或目录已被修改
+	Refreshing   bool             `json:"refreshing"`           // 是否正在后台重新统计
+}
+
+// limit 返回各列表最多保留 n 项的副本
+func (u DiskUsage) limit(n int) DiskUsage {
+	if n <= 0 {
+		n = DefaultUsageTop
+	}
+	if n > MaxUsageTop {
+		n = MaxUsageTop
+	}
+	truncate := func(entries []UsageEntry) []UsageEntry {
+		if len(entries) > n {
+			entries = entries[:n]
+		}
+		return append([]UsageEntry{}, entries...)
+	}
+	u.Children = truncate(u.Children)
+	u.LargestFiles = truncate(u.LargestFiles)
+	u.LargestDirs = truncate(u.LargestDirs)
+	return u
+}
+
+// usageHeap 按 Size 排序的小顶堆
+type usageHeap []UsageEntry
+
+func (h usageHeap) Len() int            { return len(h) }
+func (h usageHeap) Less(i, j int) bool  { return h[i].Size < h[j].Size }
+func (h usageHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *usageHeap) Push(x interface{}) { *h = append(*h, x.(UsageEntry)) }
+func (h *usageHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// usageTop 保留 Size 最大的 n 项
+type usageTop struct {
+	n     int
+	items usageHeap
+}
+
+// add 加入一项，超出数量时淘汰最小的一项
+func (t *usageTop) add(entry UsageEntry) {
+	if len(t.items) < t.n {
+		heap.Push(&t.items, entry)
+		return
+	}
+	if entry.Size > t.items[0].Size {
+		t.items[0] = entry
+		heap.Fix(&t.items, 0)
+	}
+}
+
+// sorted 按 Size 从大到小返回，大小相同时按路径排序
+func (t *usageTop) sorted() []UsageEntry {
+	entries := append([]UsageEntry{}, t.items...)
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Size != entries[j].Size {
+			return entries[i].Size > entries[j].Size
+		}
+		return entries[i].Path < entries[j].Path
+	})
+	return entries
+}
+
+// usageScan 一次磁盘用量统计
+type usageScan struct {
+	ctx      context.Context
+	progress *Progress
+	reserved func(path string) bool // 返回 true 的路径不统计，用于跳过服务内部使用的目录
+	seen     map[fileIdentity]bool  // 已统计的多链接文件，同一 inode 只计算一次
+	files    usageTop
+	dirs     usageTop
+	children usageTop
+	errors   int
+}
+
+// dir 递归统计目录，depth 为 0 时记录直接子条目；符号链接不会被跟随
+func (u *usageScan) dir(path string, info os.FileInfo, depth int) (UsageEntry, error) {
+	total := UsageEntry{Path: path, IsDir: true, Allocated: fileAllocated(info)}
+
+	entries, err := readDir(u.ctx, path)
+	if err != nil {
+		if ctxErr := u.ctx.Err(); ctxErr != nil {
+			return total, ctxErr
+		}
+		u.errors++
+		return total, nil
+	}
+
+	for _, entry := range entries {
+		if err := u.ctx.Err(); err != nil {
+			return total, err
+		}
+		fullPath := filepath.Join(path, entry.Name())
+		if u.reserved(fullPath) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			u.errors++
+			continue
+		}
+		u.progress.AddEntries(1)
+
+		var usage UsageEntry
+		if info.IsDir() {
+			if usage, err = u.dir(fullPath, info, depth+1); err != nil {
+				return total, err
+			}
+			total.Dirs += usage.Dirs + 1
+			u.dirs.add(usage)
+		} else {
+			usage = UsageEntry{Path: fullPath, Size: info.Size(), Allocated: fileAllocated(info)}
+			if fileLinks(info) > 1 {
+				id := newDupCandidate(fullPath, info).id
+				if u.seen[id] {
+					usage.Size, usage.Allocated = 0, 0
+				}
+				u.seen[id] = true
+			}
+			total.Files++
+			u.progress.AddBytes(usage.Size)
+			if usage.Size > 0 {
+				u.files.add(usage)
+			}
+		}
+		total.Size += usage.Size
+		total.Allocated += usage.Allocated
+		total.Files += usage.Files
+		if depth == 0 {
+			u.children.add(usage)
+		}
+	}
+	return total, nil
+}
+
+// scanUsage 统计目录的磁盘用量，各列表保留 MaxUsageTop 项以便缓存后按需截取
+func (s *service) scanUsage(ctx context.Context, processedPath string, info os.FileInfo, progress *Progress) (DiskUsage, error) {
+	scannedAt := time.Now()
+	scan := &usageScan{
+		ctx:      ctx,
+		progress: progress,
+		reserved: s.pathProcessor.Reserved,
+		seen:     make(map[fileIdentity]bool),
+		files:    usageTop{n: MaxUsageTop},
+		dirs:     usageTop{n: MaxUsageTop},
+		children: usageTop{n: MaxUsageTop},
+	}
+	total, err := scan.dir(processedPath, info, 0)
+	if err != nil {
+		return DiskUsage{}, err
+	}
+	return DiskUsage{
+		Path:         processedPath,
+		Size:         total.Size,
+		Allocated:    total.Allocated,
+		Files:        total.Files,
+		Dirs:         total.Dirs,
+		Errors:       scan.errors,
+		Children:     scan.children.sorted(),
+		LargestFiles: scan.files.sorted(),
+		LargestDirs:  scan.dirs.sorted(),
+		ScannedAt:    scannedAt,
+	}, nil
+}
+
+// refreshUsage 在后台重新统计并更新缓存，失败时保留旧结果
+func (s *service) refreshUsage(processedPath string) {
+	ctx := context.Background()
+	if timeout := s.config.Timeout.Usage; timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	info, err := os.Stat(processedPath)
+	if err != nil {
+		s.usage.remove(processedPath)
+		return
+	}
+	usage, err := s.scanUsage(ctx, processedPath, info, nil)
+	if err != nil {
+		s.usage.done(processedPath)
+		return
+	}
+	s.usage.put(processedPath, usage)
+}
+
+// DiskUsage 实现 Service 接口的 DiskUsage 方法
+// 缓存过期或目录被修改后仍先返回旧结果（Stale 为 true），同时在后台重新统计
+func (s *service) DiskUsage(ctx context.Context, path string, opts DiskUsageOptions) (DiskUsage, error) {
+	processedPath, err := s.pathProcessor.ProcessPath(path)
+	if err != nil {
+		return DiskUsage{}, err
+	}
+	info, err := os.Stat(processedPath)
+	if err != nil {
+		return DiskUsage{}, err
+	}
+	if !info.IsDir() {
+		return DiskUsage{}, fmt.Errorf("path is not a directory: %s", path)
+	}
+
+	ttl := s.config.Usage.CacheTTL
+	usage, cached, refresh := DiskUsage{}, false, false
+	if ttl > 0 && !opts.Refresh {
+		usage, cached, refresh = s.usage.lookup(processedPath, ttl)
+	}
+	if refresh {
+		go s.refreshUsage(processedPath)
+	}
+	if !cached {
+		if usage, err = s.scanUsage(ctx, processedPath, info, opts.Progress); err != nil {
+			return DiskUsage{}, err
+		}
+		if ttl > 0 {
+			s.usage.put(processedPath, usage)
+		}
+	}
+
+	usage.Cached = cached
+	if fs, err := filesystemUsage(processedPath); err == nil {
+		usage.Filesystem = fs
+	}
+	return usage.limit(opts.Top), nil
+}
+
+// usageCache 磁盘用量缓存，以目录的处理后路径为键
+type usageCache struct {
+	mu      sync.Mutex
+	max     int
+	entries map[string]*usageCacheEntry
+}
+
+// usageCacheEntry 缓存条目
+type usageCacheEntry struct {
+	usage         DiskUsage
+	invalidatedAt time.Time // 最近一次通过 Service 修改子树的时间
+	refreshing    bool
+}
+
+// newUsageCache 创建磁盘用量缓存
+func newUsageCache(max int) *usageCache {
+	return &usageCache{max: max, entries: make(map[string]*usageCacheEntry)}
+}
+
+// lookup 查找缓存，结果过期且没有正在进行的后台统计时返回 refresh 为 true，调用方负责启动后台统计
+func (c *usageCache) lookup(path string, ttl time.Duration) (usage DiskUsage, ok, refresh bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[path]
+	if !ok {
+		return DiskUsage{}, false, false
+	}
+	usage = entry.usage
+	usage.Stale = time.Since(usage.ScannedAt) > ttl || entry.invalidatedAt.After(usage.ScannedAt)
+	if usage.Stale && !entry.refreshing {
+		entry.refreshing = true
+		refresh = true
+	}
+	usage.Refreshing = entry.refreshing
+	return usage, true, refresh
+}
+
+// put 保存统计结果，缓存已满时淘汰统计时间最早的条目
+func (c *usageCache) put(path string, usage DiskUsage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry, ok := c.entries[path]; ok {
+		entry.usage = usage
+		entry.refreshing = false
+		return
+	}
+	c.entries[path] = &usageCacheEntry{usage: usage}
+	for len(c.entries) > c.max {
+		var oldest string
+		for key, entry := range c.entries {
+			if oldest == "" || entry.usage.ScannedAt.Before(c.entries[oldest].usage.ScannedAt) {
+				oldest = key
+			}
+		}
+		delete(c.entries, oldest)
+	}
+}
+
+// done 结束后台统计而不更新结果
+func (c *usageCache) done(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry, ok := c.entries[path]; ok {
+		entry.refreshing = false
+	}
+}
+
+// remove 删除缓存条目
+func (c *usageCache) remove(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, path)
+}
+
+// invalidate 将包含变更路径的目录标记为过期，作为 Service 的变更监听注册
+func (c *usageCache) invalidate(event ChangeEvent) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for path, entry := range c.entries {
+		if pathRelated(path, event.Path) || (event.OldPath != "" && pathRelated(path, event.OldPath)) {
+			entry.invalidatedAt = now
+		}
+	}
+}
+
+// pathRelated 判断两个路径是否相同或互为祖先
+func pathRelated(a, b string) bool {
+	sep := string(filepath.Separator)
+	return a == b ||
+		strings.HasPrefix(b, strings.TrimSuffix(a, sep)+sep) ||
+		strings.HasPrefix(a, strings.TrimSuffix(b, sep)+sep)
+}
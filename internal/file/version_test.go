@@ -0,0 +1,308 @@
+package file
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"jia-file/internal/config"
+)
+
+// newVersionTestService 创建启用版本历史的文件服务，默认保留 20 个版本、720 小时，版本和回收站目录位于根目录之外
+func newVersionTestService(t *testing.T, retention *config.VersionRetentionConfig) (*service, string) {
+	t.Helper()
+	root := t.TempDir()
+	s := newTestService(t, root)
+	s.config.Version.Enabled = true
+	s.config.Version.Dir = t.TempDir()
+	s.config.Version.MaxCount = 20
+	s.config.Version.MaxAge = 720 * time.Hour
+	s.config.Trash.Dir = t.TempDir()
+	s.trash = newTrash(s.config)
+	s.versions = newVersionStore(s.config, retention)
+	return s, root
+}
+
+// writeVersioned 通过服务覆盖写入文件，覆盖前保存旧内容
+func writeVersioned(t *testing.T, s *service, path, content string) {
+	t.Helper()
+	opts := WriteOptions{Conflict: ConflictOverwrite, MaxSize: -1}
+	if _, err := s.WriteFile(context.Background(), path, strings.NewReader(content), opts); err != nil {
+		t.Fatalf("WriteFile(%s) error = %v", path, err)
+	}
+}
+
+// versionContents 返回路径所有版本的内容，最新的在前
+func versionContents(t *testing.T, s *service, path string) []string {
+	t.Helper()
+	history, err := s.ListVersions(context.Background(), path)
+	if err != nil {
+		t.Fatalf("ListVersions(%s) error = %v", path, err)
+	}
+	contents := []string{}
+	for _, v := range history.Versions {
+		f, _, err := s.OpenVersion(context.Background(), path, v.ID)
+		if err != nil {
+			t.Fatalf("OpenVersion(%s, %d) error = %v", path, v.ID, err)
+		}
+		data, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		contents = append(contents, string(data))
+	}
+	return contents
+}
+
+// countObjects 返回版本目录中内容对象的数量
+func countObjects(t *testing.T, s *service) int {
+	t.Helper()
+	count := 0
+	filepath.WalkDir(s.versions.objectsDir(), func(path string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && !strings.HasPrefix(d.Name(), ".") {
+			count++
+		}
+		return nil
+	})
+	return count
+}
+
+func TestRetentionPrune(t *testing.T) {
+	now := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)
+	record := func(ages ...time.Duration) versionRecord {
+		rec := versionRecord{Path: "/f"}
+		for i, age := range ages {
+			rec.Versions = append(rec.Versions, Version{ID: i + 1, SavedAt: now.Add(-age)})
+		}
+		return rec
+	}
+	day := 24 * time.Hour
+
+	tests := []struct {
+		name    string
+		rule    retentionRule
+		rec     versionRecord
+		wantIDs []int
+	}{
+		{"unlimited", retentionRule{}, record(3*day, 2*day, day), []int{1, 2, 3}},
+		{"max count", retentionRule{maxCount: 2}, record(3*day, 2*day, day), []int{2, 3}},
+		{"max count not reached", retentionRule{maxCount: 5}, record(3*day, 2*day), []int{1, 2}},
+		{"max age", retentionRule{maxAge: 2*day + time.Hour}, record(3*day, 2*day, day), []int{2, 3}},
+		{"max age removes all", retentionRule{maxAge: time.Hour}, record(3*day, 2*day), []int{}},
+		{"max age then count", retentionRule{maxCount: 1, maxAge: 2*day + time.Hour}, record(3*day, 2*day, day), []int{3}},
+		{"empty record", retentionRule{maxCount: 1, maxAge: day}, record(), []int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := tt.rec
+			before := len(rec.Versions)
+			removed := tt.rule.prune(&rec, now)
+			ids := []int{}
+			for _, v := range rec.Versions {
+				ids = append(ids, v.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) || removed != before-len(tt.wantIDs) {
+				t.Errorf("prune() kept %v, removed %d; want %v", ids, removed, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func TestRetentionRuleMatching(t *testing.T) {
+	count := func(n int) *int { return &n }
+	age := "1h"
+	s, root := newVersionTestService(t, &config.VersionRetentionConfig{Rules: []config.VersionRetentionRule{
+		{Path: "etc", MaxCount: count(100)},
+		{Path: "etc/archive", MaxAge: &age},
+		{Path: "/elsewhere", MaxCount: count(0)},
+	}})
+
+	tests := []struct {
+		path string
+		want retentionRule
+	}{
+		{filepath.Join(root, "etc/app.conf"), retentionRule{path: filepath.Join(root, "etc"), maxCount: 100, maxAge: 720 * time.Hour}},
+		{filepath.Join(root, "etc/archive/old.conf"), retentionRule{path: filepath.Join(root, "etc/archive"), maxCount: 20, maxAge: time.Hour}},
+		{filepath.Join(root, "etcetera/x"), retentionRule{maxCount: 20, maxAge: 720 * time.Hour}},
+		{"/elsewhere/x", retentionRule{path: "/elsewhere", maxCount: 0, maxAge: 720 * time.Hour}},
+	}
+	for _, tt := range tests {
+		if got := s.versions.rule(tt.path); got != tt.want {
+			t.Errorf("rule(%s) = %+v, want %+v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestVersionStore(t *testing.T) {
+	s, root := newVersionTestService(t, nil)
+	ctx := context.Background()
+	path := filepath.Join(root, "doc.txt")
+
+	writeVersioned(t, s, path, "one\n")
+	writeVersioned(t, s, path, "two\n")
+	writeVersioned(t, s, path, "two\n")
+	writeVersioned(t, s, path, "three\n")
+	// 与最近一个版本内容相同时不重复保存
+	if got, want := versionContents(t, s, path), []string{"two\n", "one\n"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("versions = %q, want %q", got, want)
+	}
+	history, _ := s.ListVersions(ctx, path)
+	if history.Total != 2 || history.Versions[0].ID != 2 || history.Versions[0].Reason != VersionOverwrite {
+		t.Errorf("ListVersions() = %+v", history)
+	}
+
+	// 相同内容的不同文件共享内容对象
+	other := filepath.Join(root, "other.txt")
+	writeVersioned(t, s, other, "one\n")
+	writeVersioned(t, s, other, "changed\n")
+	if got := countObjects(t, s); got != 2 {
+		t.Errorf("objects = %d, want 2", got)
+	}
+
+	// 恢复时当前内容保存为新版本，并恢复保存时的权限
+	if err := os.Chmod(path, 0600); err != nil {
+		t.Fatal(err)
+	}
+	writeVersioned(t, s, path, "four\n")
+	if err := os.Chmod(path, 0640); err != nil {
+		t.Fatal(err)
+	}
+	history, _ = s.ListVersions(ctx, path)
+	restored := history.Versions[0]
+	if restored.Mode != "0600" {
+		t.Errorf("version mode = %q, want 0600", restored.Mode)
+	}
+	if _, err := s.RestoreVersion(ctx, path, restored.ID); err != nil {
+		t.Fatalf("RestoreVersion() error = %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	info, _ := os.Stat(path)
+	if string(data) != "three\n" || info.Mode().Perm() != 0600 {
+		t.Errorf("restored file = %q, mode %v; want \"three\\n\", 0600", data, info.Mode().Perm())
+	}
+	if got := versionContents(t, s, path)[0]; got != "four\n" {
+		t.Errorf("latest version = %q, want content replaced by restore", got)
+	}
+
+	// 文件被删除后仍可列出和恢复
+	if _, err := s.Delete(ctx, path, DeleteOptions{Permanent: true}); err != nil {
+		t.Fatal(err)
+	}
+	history, _ = s.ListVersions(ctx, path)
+	if latest := history.Versions[0]; latest.Reason != VersionDelete {
+		t.Errorf("latest version reason = %q, want delete", latest.Reason)
+	}
+	if _, err := s.RestoreVersion(ctx, path, 1); err != nil {
+		t.Fatalf("RestoreVersion() of deleted file error = %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "one\n" {
+		t.Errorf("restored deleted file = %q", data)
+	}
+
+	if _, _, err := s.OpenVersion(ctx, path, 99); err == nil {
+		t.Error("OpenVersion() of missing version succeeded")
+	}
+	if _, err := s.RestoreVersion(ctx, root, 1); err == nil {
+		t.Error("RestoreVersion() of a directory succeeded")
+	}
+}
+
+func TestVersionDelete(t *testing.T) {
+	s, root := newVersionTestService(t, nil)
+	ctx := context.Background()
+	dir := filepath.Join(root, "dir")
+	for name, content := range map[string]string{"a.txt": "a", "sub/b.txt": "b"} {
+		writeVersioned(t, s, filepath.Join(dir, name), content)
+	}
+	if err := os.Symlink("a.txt", filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	// 移入回收站时由回收站保留内容，不保存版本
+	s.config.Trash.Enabled = true
+	if _, err := s.Delete(ctx, filepath.Join(dir, "a.txt"), DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := versionContents(t, s, filepath.Join(dir, "a.txt")); len(got) != 0 {
+		t.Errorf("versions after moving to trash = %q, want none", got)
+	}
+	if got := countObjects(t, s); got != 0 {
+		t.Errorf("objects after moving to trash = %d, want 0", got)
+	}
+
+	// 永久删除目录时为其中的每个普通文件保存版本
+	writeVersioned(t, s, filepath.Join(dir, "a.txt"), "a2")
+	if _, err := s.Delete(ctx, dir, DeleteOptions{Permanent: true}); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string][]string{"a.txt": {"a2"}, "sub/b.txt": {"b"}, "link": {}} {
+		if got := versionContents(t, s, filepath.Join(dir, name)); !reflect.DeepEqual(got, want) {
+			t.Errorf("versions of %s = %q, want %q", name, got, want)
+		}
+	}
+
+	// 超过大小限制的文件不保存版本
+	s.versions.maxFileSize = 2
+	big := filepath.Join(root, "big.txt")
+	writeVersioned(t, s, big, "large")
+	writeVersioned(t, s, big, "x")
+	if got := versionContents(t, s, big); len(got) != 0 {
+		t.Errorf("versions of large file = %q, want none", got)
+	}
+}
+
+func TestVersionPruneAll(t *testing.T) {
+	s, root := newVersionTestService(t, nil)
+	ctx := context.Background()
+	path := filepath.Join(root, "doc.txt")
+	for _, content := range []string{"1", "2", "3", "4"} {
+		writeVersioned(t, s, path, content)
+	}
+	if got := countObjects(t, s); got != 3 {
+		t.Fatalf("objects = %d, want 3", got)
+	}
+
+	// 收紧保留策略后，清理多余的版本和不再被引用的内容
+	s.versions.defaultRule = retentionRule{maxCount: 1}
+	versions, objects, bytes, err := s.versions.pruneAll(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if versions != 2 || objects != 2 || bytes != 2 {
+		t.Errorf("pruneAll() = %d versions, %d objects, %d bytes; want 2, 2, 2", versions, objects, bytes)
+	}
+	if got := versionContents(t, s, path); !reflect.DeepEqual(got, []string{"3"}) {
+		t.Errorf("versions after prune = %q", got)
+	}
+
+	// 所有版本过期后删除历史文件
+	s.versions.defaultRule = retentionRule{maxAge: time.Nanosecond}
+	if _, _, _, err := s.versions.pruneAll(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(s.versions.historyPath(path)); !os.IsNotExist(err) {
+		t.Errorf("history file still exists after all versions expired: %v", err)
+	}
+	if got := countObjects(t, s); got != 0 {
+		t.Errorf("objects = %d after all versions expired, want 0", got)
+	}
+
+	// 历史文件损坏时不删除任何内容对象
+	writeVersioned(t, s, path, "5")
+	s.versions.defaultRule = retentionRule{}
+	if err := os.WriteFile(filepath.Join(s.versions.historyDir(), "corrupt.json"), []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := s.versions.pruneAll(ctx); err == nil {
+		t.Error("pruneAll() with corrupt history succeeded")
+	}
+	if got := countObjects(t, s); got != 1 {
+		t.Errorf("objects = %d after failed prune, want 1", got)
+	}
+}
@@ -0,0 +1,167 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ConflictPolicy 目标已存在时的处理策略
+type ConflictPolicy string
+
+const (
+	ConflictFail      ConflictPolicy = "fail"      // 目标已存在时返回错误
+	ConflictOverwrite ConflictPolicy = "overwrite" // 覆盖已存在的目标
+	ConflictRename    ConflictPolicy = "rename"    // 自动重命名为不冲突的名称
+	ConflictSkip      ConflictPolicy = "skip"      // 保留已存在的目标，跳过本次写入
+)
+
+// ErrTooLarge 写入内容超过大小限制
+var ErrTooLarge = errors.New("content exceeds maximum allowed size")
+
+// ParseConflictPolicy 解析冲突策略，空字符串表示默认的 fail
+func ParseConflictPolicy(value string) (ConflictPolicy, error) {
+	switch policy := ConflictPolicy(strings.ToLower(value)); policy {
+	case "":
+		return ConflictFail, nil
+	case ConflictFail, ConflictOverwrite, ConflictRename, ConflictSkip:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("invalid conflict policy: %s", value)
+	}
+}
+
+// WriteOptions 写入文件选项
+type WriteOptions struct {
+	Conflict ConflictPolicy // 冲突策略
+	MaxSize  int64          // 最大写入字节数，0 表示使用配置中的默认值，负数表示不限制
+}
+
+// uniquePath 为已存在的路径生成形如 "name (1).ext" 的不冲突路径
+func uniquePath(path string) (string, error) {
+	dir := filepath.Dir(path)
+	base := filepath.Base(path)
+	ext := filepath.Ext(base)
+	name := strings.TrimSuffix(base, ext)
+	for i := 1; i < 10000; i++ {
+		candidate := filepath.Join(dir, fmt.Sprintf("%s (%d)%s", name, i, ext))
+		if _, err := os.Lstat(candidate); os.IsNotExist(err) {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("unable to find a free name for: %s", path)
+}
+
+// resolveConflict 根据冲突策略确定最终写入路径
+// 返回的 exists 表示最终路径上已存在文件（仅在 overwrite 和 skip 策略下可能为 true）
+func resolveConflict(path string, policy ConflictPolicy) (target string, exists bool, err error) {
+	if _, err := os.Lstat(path); err != nil {
+		if os.IsNotExist(err) {
+			return path, false, nil
+		}
+		return "", false, err
+	}
+
+	switch policy {
+	case ConflictOverwrite, ConflictSkip:
+		return path, true, nil
+	case ConflictRename:
+		target, err := uniquePath(path)
+		return target, false, err
+	default:
+		return "", true, fmt.Errorf("file already exists: %s", path)
+	}
+}
+
+// writeStream 将内容流式写入同目录下的临时文件，设置权限为 perm 后原子地重命名到目标路径
+func writeStream(ctx context.Context, target string, content io.Reader, maxSize int64, overwrite bool, perm os.FileMode) error {
+	dir := filepath.Dir(target)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create parent directory: %v", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(target)+".upload-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	if content == nil {
+		content = strings.NewReader("")
+	}
+	if maxSize > 0 {
+		// 多读一个字节用于判断是否超限
+		content = io.LimitReader(content, maxSize+1)
+	}
+
+	written, err := copyContext(ctx, tmp, content, nil)
+	if err == nil && maxSize > 0 && written > maxSize {
+		err = ErrTooLarge
+	}
+	if err == nil {
+		err = tmp.Chmod(perm)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	if !overwrite {
+		if _, err := os.Lstat(target); err == nil {
+			return fmt.Errorf("file already exists: %s", target)
+		}
+	}
+	return os.Rename(tmpPath, target)
+}
+
+// WriteFile 实现 Service 接口的 WriteFile 方法
+func (s *service) WriteFile(ctx context.Context, path string, content io.Reader, opts WriteOptions) (FileInfo, error) {
+	processedPath, err := s.pathProcessor.ProcessPath(path)
+	if err != nil {
+		return FileInfo{}, err
+	}
+
+	if info, err := os.Stat(processedPath); err == nil && info.IsDir() {
+		return FileInfo{}, fmt.Errorf("path is a directory: %s", path)
+	}
+
+	target, exists, err := resolveConflict(processedPath, opts.Conflict)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	if exists && opts.Conflict == ConflictSkip {
+		info, err := os.Stat(target)
+		if err != nil {
+			return FileInfo{}, err
+		}
+		return buildFileInfo(info, target, target), nil
+	}
+
+	maxSize := opts.MaxSize
+	if maxSize == 0 {
+		maxSize = s.config.File.MaxUploadSize
+	}
+	if exists {
+		if err := s.saveVersion(ctx, target, VersionOverwrite); err != nil {
+			return FileInfo{}, err
+		}
+	}
+
+	if err := writeStream(ctx, target, content, maxSize, opts.Conflict == ConflictOverwrite, 0644); err != nil {
+		return FileInfo{}, err
+	}
+	s.notify(ChangeEvent{Op: ChangeWrite, Path: target})
+
+	info, err := os.Stat(target)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	return buildFileInfo(info, target, target), nil
+}
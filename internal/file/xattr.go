@@ -0,0 +1,176 @@
+package file
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxXattrSize 扩展属性值的最大字节数，与 Linux 的 XATTR_SIZE_MAX 一致
+const MaxXattrSize = 64 * 1024
+
+// ErrXattrUnsupported 平台或文件系统不支持扩展属性
+var ErrXattrUnsupported = errors.New("extended attributes are not supported")
+
+// errXattrNotFound 属性不存在
+var errXattrNotFound = errors.New("xattr not found")
+
+// Xattr 扩展属性
+type Xattr struct {
+	Name     string `json:"name"`               // 属性名，包含命名空间，如 user.tag
+	Value    string `json:"value"`              // 属性值
+	Encoding string `json:"encoding,omitempty"` // 值不是合法的 UTF-8 文本时为 base64，Value 为 base64 编码后的值
+}
+
+// newXattr 根据原始值构造扩展属性，二进制值使用 base64 编码
+func newXattr(name string, value []byte) Xattr {
+	if utf8.Valid(value) {
+		return Xattr{Name: name, Value: string(value)}
+	}
+	return Xattr{Name: name, Value: base64.StdEncoding.EncodeToString(value), Encoding: "base64"}
+}
+
+// DecodeXattrValue 按编码解析属性值，encoding 为空表示原始文本
+func DecodeXattrValue(value, encoding string) ([]byte, error) {
+	switch encoding {
+	case "":
+		return []byte(value), nil
+	case "base64":
+		return base64.StdEncoding.DecodeString(value)
+	default:
+		return nil, fmt.Errorf("unsupported encoding: %s", encoding)
+	}
+}
+
+// validateXattrName 检查属性名，属性名必须包含命名空间前缀
+func validateXattrName(name string) error {
+	if name == "" {
+		return fmt.Errorf("xattr name is empty")
+	}
+	if strings.IndexByte(name, 0) >= 0 {
+		return fmt.Errorf("invalid xattr name: %q", name)
+	}
+	if i := strings.IndexByte(name, '.'); i <= 0 || i == len(name)-1 {
+		return fmt.Errorf("xattr name must include a namespace, e.g. user.%s", name)
+	}
+	return nil
+}
+
+// readXattrs 读取路径上的全部扩展属性，按名称排序
+func readXattrs(path string) ([]Xattr, error) {
+	names, err := listXattrNames(path)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	xattrs := make([]Xattr, 0, len(names))
+	for _, name := range names {
+		value, err := getXattr(path, name)
+		if err != nil {
+			// 列出与读取之间属性可能已被删除
+			if errors.Is(err, errXattrNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		xattrs = append(xattrs, newXattr(name, value))
+	}
+	return xattrs, nil
+}
+
+// copyXattrs 将 src 的扩展属性复制到 dst
+// 目标不支持扩展属性或无权设置某个属性（如 trusted.*、security.*）时跳过，不视为错误
+func copyXattrs(src, dst string) error {
+	names, err := listXattrNames(src)
+	if err != nil {
+		if errors.Is(err, ErrXattrUnsupported) {
+			return nil
+		}
+		return err
+	}
+	for _, name := range names {
+		value, err := getXattr(src, name)
+		if err != nil {
+			if errors.Is(err, errXattrNotFound) {
+				continue
+			}
+			return err
+		}
+		if err := setXattr(dst, name, value); err != nil {
+			if errors.Is(err, ErrXattrUnsupported) || errors.Is(err, os.ErrPermission) {
+				continue
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// ListXattrs 实现 Service 接口的 ListXattrs 方法
+func (s *service) ListXattrs(ctx context.Context, path string) ([]Xattr, error) {
+	processedPath, err := s.pathProcessor.ProcessPath(path)
+	if err != nil {
+		return nil, err
+	}
+	return readXattrs(processedPath)
+}
+
+// GetXattr 实现 Service 接口的 GetXattr 方法
+func (s *service) GetXattr(ctx context.Context, path, name string) (Xattr, error) {
+	if err := validateXattrName(name); err != nil {
+		return Xattr{}, err
+	}
+	processedPath, err := s.pathProcessor.ProcessPath(path)
+	if err != nil {
+		return Xattr{}, err
+	}
+
+	value, err := getXattr(processedPath, name)
+	if err != nil {
+		return Xattr{}, err
+	}
+	return newXattr(name, value), nil
+}
+
+// SetXattr 实现 Service 接口的 SetXattr 方法
+func (s *service) SetXattr(ctx context.Context, path, name string, value []byte) error {
+	if err := validateXattrName(name); err != nil {
+		return err
+	}
+	if len(value) > MaxXattrSize {
+		return fmt.Errorf("xattr value exceeds %d bytes", MaxXattrSize)
+	}
+	processedPath, err := s.pathProcessor.ProcessPath(path)
+	if err != nil {
+		return err
+	}
+
+	if err := setXattr(processedPath, name, value); err != nil {
+		return err
+	}
+	s.notify(ChangeEvent{Op: ChangeWrite, Path: processedPath})
+	return nil
+}
+
+// RemoveXattr 实现 Service 接口的 RemoveXattr 方法
+func (s *service) RemoveXattr(ctx context.Context, path, name string) error {
+	if err := validateXattrName(name); err != nil {
+		return err
+	}
+	processedPath, err := s.pathProcessor.ProcessPath(path)
+	if err != nil {
+		return err
+	}
+
+	if err := removeXattr(processedPath, name); err != nil {
+		return err
+	}
+	s.notify(ChangeEvent{Op: ChangeWrite, Path: processedPath})
+	return nil
+}
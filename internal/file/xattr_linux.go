@@ -0,0 +1,89 @@
+//go:build linux
+
+package file
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// listXattrNames 列出路径上的扩展属性名，跟随符号链接
+func listXattrNames(path string) ([]string, error) {
+	buf, err := readXattrBuffer(func(dest []byte) (int, error) {
+		return unix.Listxattr(path, dest)
+	})
+	if err != nil {
+		return nil, xattrError(path, "", err)
+	}
+
+	var names []string
+	for _, name := range bytes.Split(buf, []byte{0}) {
+		if len(name) > 0 {
+			names = append(names, string(name))
+		}
+	}
+	return names, nil
+}
+
+// getXattr 读取扩展属性值，跟随符号链接
+func getXattr(path, name string) ([]byte, error) {
+	value, err := readXattrBuffer(func(dest []byte) (int, error) {
+		return unix.Getxattr(path, name, dest)
+	})
+	if err != nil {
+		return nil, xattrError(path, name, err)
+	}
+	return value, nil
+}
+
+// setXattr 设置扩展属性值，跟随符号链接
+func setXattr(path, name string, value []byte) error {
+	return xattrError(path, name, unix.Setxattr(path, name, value, 0))
+}
+
+// removeXattr 删除扩展属性，跟随符号链接
+func removeXattr(path, name string) error {
+	return xattrError(path, name, unix.Removexattr(path, name))
+}
+
+// readXattrBuffer 先查询所需长度再读取；两次调用之间属性变大时（ERANGE）重试
+func readXattrBuffer(read func(dest []byte) (int, error)) ([]byte, error) {
+	for {
+		size, err := read(nil)
+		if err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return nil, nil
+		}
+		buf := make([]byte, size)
+		n, err := read(buf)
+		if errors.Is(err, unix.ERANGE) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	}
+}
+
+// xattrError 将系统调用错误转换为带路径的错误
+func xattrError(path, name string, err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, unix.ENODATA):
+		return fmt.Errorf("%w: %s", errXattrNotFound, name)
+	case errors.Is(err, unix.ENOTSUP):
+		return fmt.Errorf("%w on %s", ErrXattrUnsupported, path)
+	case name == "":
+		return &os.PathError{Op: "listxattr", Path: path, Err: err}
+	default:
+		return &os.PathError{Op: "xattr " + name, Path: path, Err: err}
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !linux
+
+package file
+
+// 非 Linux 平台暂不支持扩展属性，所有操作返回 ErrXattrUnsupported
+
+// listXattrNames 列出路径上的扩展属性名
+func listXattrNames(path string) ([]string, error) { return nil, ErrXattrUnsupported }
+
+// getXattr 读取扩展属性值
+func getXattr(path, name string) ([]byte, error) { return nil, ErrXattrUnsupported }
+
+// setXattr 设置扩展属性值
+func setXattr(path, name string, value []byte) error { return ErrXattrUnsupported }
+
+// removeXattr 删除扩展属性
+func removeXattr(path, name string) error { return ErrXattrUnsupported }
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+
+	"jia-file/api"
+	"jia-file/internal/file"
+	"jia-file/internal/logger"
+)
+
+// Archive 将目录或多个路径打包为 zip、tar、tar.gz 或 tar.zst 并流式下载
+// 可以重复传入 path 参数；打包的文件数和跳过的条目数通过 HTTP trailer 返回
+func (h *Handler) Archive(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.writeResponse(w, api.CodeMethodNotAllow, "Method not allowed", nil)
+		return
+	}
+
+	query := r.URL.Query()
+	paths := query["path"]
+	if len(paths) == 0 || paths[0] == "" {
+		h.writeResponse(w, api.CodeParamMissing, "Missing path parameter", nil)
+		return
+	}
+	format, err := file.ParseArchiveFormat(query.Get("format"))
+	if err != nil {
+		h.writeResponse(w, api.CodeParamMissing, err.Error(), nil)
+		return
+	}
+	opts := file.ArchiveOptions{
+		Format:  format,
+		Include: splitList(query.Get("include")),
+		Exclude: splitList(query.Get("exclude")),
+	}
+
+	name := query.Get("name")
+	if name == "" {
+		name = "archive"
+		if len(paths) == 1 {
+			name = filepath.Base(paths[0])
+		}
+		name += format.Extension()
+	}
+
+	// 归档大小无法预知，不设置超时，客户端断开连接时停止打包
+	ctx, cancel := withTimeout(r, 0)
+	defer cancel()
+
+	sw := &startWriter{w: w, start: func() {
+		w.Header().Set("Content-Type", format.ContentType())
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+		w.Header().Set("Trailer", "X-Archive-Files, X-Archive-Skipped")
+		w.WriteHeader(http.StatusOK)
+	}}
+
+	summary, err := h.fileService.Archive(ctx, paths, opts, sw)
+	if err != nil && !sw.started {
+		logger.Error("Archive error: %v", err)
+		h.writeResponse(w, api.CodeOperationFail, err.Error(), nil)
+		return
+	}
+	if err != nil {
+		// 响应已开始，无法再返回错误响应，客户端会收到不完整的归档
+		logger.Error("Archive interrupted: %v", err)
+		return
+	}
+
+	sw.begin()
+	w.Header().Set("X-Archive-Files", strconv.Itoa(summary.Files))
+	w.Header().Set("X-Archive-Skipped", strconv.Itoa(summary.Skipped))
+	if summary.Skipped > 0 {
+		logger.Info("Archive of %v skipped %d entries", paths, summary.Skipped)
+	}
+}
+
+// startWriter 在第一次写入前调用 start，用于推迟写出响应头，使写入前发生的错误仍可以返回错误响应
+type startWriter struct {
+	w       http.ResponseWriter
+	start   func()
+	started bool
+}
+
+// begin 调用 start，只调用一次
+func (sw *startWriter) begin() {
+	if !sw.started {
+		sw.started = true
+		sw.start()
+	}
+}
+
+// Write 实现 io.Writer 接口
+func (sw *startWriter) Write(b []byte) (int, error) {
+	sw.begin()
+	return sw.w.Write(b)
+}
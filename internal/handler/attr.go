@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"jia-file/api"
+	"jia-file/internal/file"
+	"jia-file/internal/logger"
+)
+
+// Chmod 修改文件或目录的权限
+func (h *Handler) Chmod(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.writeResponse(w, api.CodeMethodNotAllow, "Method not allowed", nil)
+		return
+	}
+
+	query := r.URL.Query()
+	path := query.Get("path")
+	if path == "" || query.Get("mode") == "" {
+		h.writeResponse(w, api.CodeParamMissing, "Missing path or mode parameter", nil)
+		return
+	}
+	mode, err := file.ParseModeSpec(query.Get("mode"))
+	if err != nil {
+		h.writeResponse(w, api.CodeParamMissing, err.Error(), nil)
+		return
+	}
+
+	ctx, cancel := withTimeout(r, h.config.Timeout.Write)
+	defer cancel()
+
+	info, err := h.fileService.Chmod(ctx, path, file.ChmodOptions{
+		Mode:      mode,
+		Recursive: query.Get("recursive") == "true",
+	})
+	if err != nil {
+		logger.Error("Chmod error: %v", err)
+		h.writeResponse(w, api.CodeOperationFail, err.Error(), nil)
+		return
+	}
+
+	h.writeResponse(w, api.CodeSuccess, "Mode changed successfully", info)
+}
+
+// Chown 修改文件或目录的所有者和所属组
+func (h *Handler) Chown(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.writeResponse(w, api.CodeMethodNotAllow, "Method not allowed", nil)
+		return
+	}
+
+	query := r.URL.Query()
+	path := query.Get("path")
+	owner := query.Get("owner")
+	group := query.Get("group")
+	if path == "" || (owner == "" && group == "") {
+		h.writeResponse(w, api.CodeParamMissing, "Missing path parameter or both owner and group", nil)
+		return
+	}
+
+	ctx, cancel := withTimeout(r, h.config.Timeout.Write)
+	defer cancel()
+
+	info, err := h.fileService.Chown(ctx, path, file.ChownOptions{
+		Owner:     owner,
+		Group:     group,
+		Recursive: query.Get("recursive") == "true",
+	})
+	if err != nil {
+		logger.Error("Chown error: %v", err)
+		h.writeResponse(w, api.CodeOperationFail, err.Error(), nil)
+		return
+	}
+
+	h.writeResponse(w, api.CodeSuccess, "Owner changed successfully", info)
+}
+
+// Chtimes 修改文件或目录的访问时间和修改时间
+func (h *Handler) Chtimes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.writeResponse(w, api.CodeMethodNotAllow, "Method not allowed", nil)
+		return
+	}
+
+	query := r.URL.Query()
+	path := query.Get("path")
+	if path == "" || (query.Get("atime") == "" && query.Get("mtime") == "") {
+		h.writeResponse(w, api.CodeParamMissing, "Missing path parameter or both atime and mtime", nil)
+		return
+	}
+
+	var opts file.ChtimesOptions
+	var err error
+	if opts.AccessTime, err = parseTimeParam(query.Get("atime")); err != nil {
+		h.writeResponse(w, api.CodeParamMissing, "Invalid atime: "+err.Error(), nil)
+		return
+	}
+	if opts.ModTime, err = parseTimeParam(query.Get("mtime")); err != nil {
+		h.writeResponse(w, api.CodeParamMissing, "Invalid mtime: "+err.Error(), nil)
+		return
+	}
+
+	ctx, cancel := withTimeout(r, h.config.Timeout.Write)
+	defer cancel()
+
+	info, err := h.fileService.Chtimes(ctx, path, opts)
+	if err != nil {
+		logger.Error("Chtimes error: %v", err)
+		h.writeResponse(w, api.CodeOperationFail, err.Error(), nil)
+		return
+	}
+
+	h.writeResponse(w, api.CodeSuccess, "Times changed successfully", info)
+}
+
+// parseTimeParam 解析时间参数：RFC 3339、Unix 时间戳（秒）或 now，空字符串返回零值
+func parseTimeParam(value string) (time.Time, error) {
+	switch value {
+	case "":
+		return time.Time{}, nil
+	case "now":
+		return time.Now(), nil
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected RFC 3339 time, Unix seconds or now")
+	}
+	return t, nil
+}
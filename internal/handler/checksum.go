@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+
+	"jia-file/api"
+	"jia-file/internal/file"
+	"jia-file/internal/logger"
+)
+
+// Checksum 计算文件校验和；path 为目录时以 sha256sum 兼容的格式流式返回目录清单
+func (h *Handler) Checksum(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.writeResponse(w, api.CodeMethodNotAllow, "Method not allowed", nil)
+		return
+	}
+
+	query := r.URL.Query()
+	path := query.Get("path")
+	if path == "" {
+		h.writeResponse(w, api.CodeParamMissing, "Missing path parameter", nil)
+		return
+	}
+	algorithms, err := file.ParseHashAlgorithms(query.Get("algorithms"))
+	if err != nil {
+		h.writeResponse(w, api.CodeParamMissing, err.Error(), nil)
+		return
+	}
+
+	ctx, cancel := withTimeout(r, h.config.Timeout.Checksum)
+	defer cancel()
+
+	info, err := h.fileService.GetInfo(ctx, path)
+	if err != nil {
+		logger.Error("Checksum error: %v", err)
+		h.writeResponse(w, api.CodeOperationFail, err.Error(), nil)
+		return
+	}
+	if info.IsDir || (info.Target != nil && info.Target.IsDir) {
+		h.writeManifest(w, r, path, algorithms)
+		return
+	}
+
+	result, err := h.fileService.Checksum(ctx, path, algorithms)
+	if err != nil {
+		logger.Error("Checksum error: %v", err)
+		h.writeResponse(w, api.CodeOperationFail, err.Error(), nil)
+		return
+	}
+
+	h.writeResponse(w, api.CodeSuccess, "success", result)
+}
+
+// writeManifest 流式写出目录清单，每行为“校验和  相对路径”，可直接用于 sha256sum -c 等命令校验
+// 写入的文件数和跳过的文件数通过 HTTP trailer 返回
+func (h *Handler) writeManifest(w http.ResponseWriter, r *http.Request, path string, algorithms []file.HashAlgorithm) {
+	if len(algorithms) != 1 || algorithms[0] == file.HashCRC32 {
+		h.writeResponse(w, api.CodeParamMissing, "Directory manifest requires exactly one of md5, sha1, sha256, sha512, blake2b", nil)
+		return
+	}
+
+	ctx, cancel := withTimeout(r, h.config.Timeout.Checksum)
+	defer cancel()
+
+	flusher, _ := w.(http.Flusher)
+	started := false
+	start := func() {
+		if started {
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("X-Checksum-Algorithm", string(algorithms[0]))
+		w.Header().Set("Trailer", "X-Manifest-Files, X-Manifest-Failed")
+		w.WriteHeader(http.StatusOK)
+		started = true
+	}
+
+	summary, err := h.fileService.ChecksumManifest(ctx, path, algorithms[0], func(entry file.ManifestEntry) error {
+		start()
+		if _, err := io.WriteString(w, file.FormatManifestLine(entry)); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err != nil && !started {
+		logger.Error("Checksum manifest error: %v", err)
+		h.writeResponse(w, api.CodeOperationFail, err.Error(), nil)
+		return
+	}
+	if err != nil {
+		// 响应已开始，无法再返回错误响应，客户端通过 trailer 中缺失或不完整的统计得知清单不完整
+		logger.Error("Checksum manifest interrupted: %v", err)
+		return
+	}
+
+	start()
+	w.Header().Set("X-Manifest-Files", strconv.Itoa(summary.Files))
+	w.Header().Set("X-Manifest-Failed", strconv.Itoa(summary.Failed))
+	if summary.Failed > 0 {
+		logger.Info("Checksum manifest of %s skipped %d unreadable files", path, summary.Failed)
+	}
+}
@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"jia-file/api"
+	"jia-file/internal/file"
+	"jia-file/internal/logger"
+)
+
+// Duplicates 查找或处理重复文件
+// GET 返回重复文件组，async=true 时作为后台任务执行；
+// POST 按 action 将多余的副本替换为硬链接或删除，总是作为可取消的后台任务执行
+func (h *Handler) Duplicates(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		h.writeResponse(w, api.CodeMethodNotAllow, "Method not allowed", nil)
+		return
+	}
+
+	query := r.URL.Query()
+	path := query.Get("path")
+	if path == "" {
+		h.writeResponse(w, api.CodeParamMissing, "Missing path parameter", nil)
+		return
+	}
+	opts, err := parseDuplicateOptions(r)
+	if err != nil {
+		h.writeResponse(w, api.CodeParamMissing, err.Error(), nil)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		dedupe := file.DedupeOptions{DuplicateOptions: opts}
+		if dedupe.Action, err = file.ParseDedupeAction(query.Get("action")); err != nil {
+			h.writeResponse(w, api.CodeParamMissing, err.Error(), nil)
+			return
+		}
+		if dedupe.Keep, err = file.ParseKeepPolicy(query.Get("keep")); err != nil {
+			h.writeResponse(w, api.CodeParamMissing, err.Error(), nil)
+			return
+		}
+		params := map[string]string{"path": path, "action": string(dedupe.Action), "keep": string(dedupe.Keep)}
+		h.submitJob(w, "dedupe", params, func(ctx context.Context, progress *file.Progress) (interface{}, error) {
+			dedupe.Progress = progress
+			return h.fileService.Deduplicate(ctx, path, dedupe)
+		})
+		return
+	}
+
+	if isAsync(r) {
+		h.submitJob(w, "duplicates", map[string]string{"path": path}, func(ctx context.Context, progress *file.Progress) (interface{}, error) {
+			opts.Progress = progress
+			return h.fileService.FindDuplicates(ctx, path, opts)
+		})
+		return
+	}
+
+	ctx, cancel := withTimeout(r, h.config.Timeout.Checksum)
+	defer cancel()
+
+	report, err := h.fileService.FindDuplicates(ctx, path, opts)
+	if err != nil {
+		logger.Error("Find duplicates error: %v", err)
+		h.writeResponse(w, api.CodeOperationFail, err.Error(), nil)
+		return
+	}
+
+	h.writeResponse(w, api.CodeSuccess, "success", report)
+}
+
+// parseDuplicateOptions 解析重复文件查找参数
+func parseDuplicateOptions(r *http.Request) (file.DuplicateOptions, error) {
+	query := r.URL.Query()
+	opts := file.DuplicateOptions{
+		Include:    splitList(query.Get("include")),
+		Exclude:    splitList(query.Get("exclude")),
+		HideHidden: query.Get("hidden") == "false",
+	}
+
+	var err error
+	if opts.MinSize, err = parseInt64Param(query.Get("minSize")); err != nil || opts.MinSize < 0 {
+		return opts, fmt.Errorf("invalid minSize")
+	}
+	if opts.MaxDepth, err = parseIntParam(query.Get("maxDepth")); err != nil || opts.MaxDepth < 0 {
+		return opts, fmt.Errorf("invalid maxDepth")
+	}
+	if opts.MaxGroups, err = parseIntParam(query.Get("maxGroups")); err != nil || opts.MaxGroups < 0 {
+		return opts, fmt.Errorf("invalid maxGroups")
+	}
+	return opts, nil
+}
@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"jia-file/api"
+	"jia-file/internal/file"
+	"jia-file/internal/logger"
+)
+
+// Extract 将服务器上的 zip、tar、tar.gz、tar.bz2 或 tar.zst 归档解压到目标目录
+// dst 为空时解压到归档所在目录；超出条目数、总大小或压缩比限制时不会在目标目录中留下任何内容
+func (h *Handler) Extract(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.writeResponse(w, api.CodeMethodNotAllow, "Method not allowed", nil)
+		return
+	}
+
+	query := r.URL.Query()
+	path := query.Get("path")
+	dst := query.Get("dst")
+	if path == "" {
+		h.writeResponse(w, api.CodeParamMissing, "Missing path parameter", nil)
+		return
+	}
+
+	var opts file.ExtractOptions
+	var err error
+	if query.Get("format") != "" {
+		if opts.Format, err = file.ParseArchiveFormat(query.Get("format")); err != nil {
+			h.writeResponse(w, api.CodeParamMissing, err.Error(), nil)
+			return
+		}
+	}
+	if opts.Conflict, err = file.ParseConflictPolicy(query.Get("conflict")); err != nil {
+		h.writeResponse(w, api.CodeParamMissing, err.Error(), nil)
+		return
+	}
+
+	if isAsync(r) {
+		params := map[string]string{"path": path, "dst": dst, "conflict": string(opts.Conflict)}
+		h.submitJob(w, "extract", params, func(ctx context.Context, progress *file.Progress) (interface{}, error) {
+			opts.Progress = progress
+			return h.fileService.Extract(ctx, path, dst, opts)
+		})
+		return
+	}
+
+	ctx, cancel := withTimeout(r, h.config.Timeout.Archive)
+	defer cancel()
+
+	result, err := h.fileService.Extract(ctx, path, dst, opts)
+	if err != nil {
+		logger.Error("Extract error: %v", err)
+		h.writeResponse(w, api.CodeOperationFail, err.Error(), result)
+		return
+	}
+
+	h.writeResponse(w, api.CodeSuccess, "Archive extracted successfully", result)
+}
+
+// Compress 将目录或多个路径打包为服务器上的归档文件
+// 可以重复传入 path 参数；format 为空时根据 dst 的扩展名判断，无法判断时使用 zip
+func (h *Handler) Compress(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.writeResponse(w, api.CodeMethodNotAllow, "Method not allowed", nil)
+		return
+	}
+
+	query := r.URL.Query()
+	paths := query["path"]
+	dst := query.Get("dst")
+	if len(paths) == 0 || paths[0] == "" || dst == "" {
+		h.writeResponse(w, api.CodeParamMissing, "Missing path or dst parameter", nil)
+		return
+	}
+
+	opts := file.CompressOptions{
+		ArchiveOptions: file.ArchiveOptions{
+			Include: splitList(query.Get("include")),
+			Exclude: splitList(query.Get("exclude")),
+		},
+	}
+	var err error
+	if query.Get("format") != "" {
+		if opts.Format, err = file.ParseArchiveFormat(query.Get("format")); err != nil {
+			h.writeResponse(w, api.CodeParamMissing, err.Error(), nil)
+			return
+		}
+	}
+	if opts.Conflict, err = file.ParseConflictPolicy(query.Get("conflict")); err != nil {
+		h.writeResponse(w, api.CodeParamMissing, err.Error(), nil)
+		return
+	}
+
+	if isAsync(r) {
+		params := map[string]string{"path": strings.Join(paths, ","), "dst": dst, "conflict": string(opts.Conflict)}
+		h.submitJob(w, "compress", params, func(ctx context.Context, progress *file.Progress) (interface{}, error) {
+			opts.Progress = progress
+			return h.fileService.Compress(ctx, paths, dst, opts)
+		})
+		return
+	}
+
+	ctx, cancel := withTimeout(r, h.config.Timeout.Archive)
+	defer cancel()
+
+	info, err := h.fileService.Compress(ctx, paths, dst, opts)
+	if err != nil {
+		logger.Error("Compress error: %v", err)
+		h.writeResponse(w, api.CodeOperationFail, err.Error(), nil)
+		return
+	}
+
+	h.writeResponse(w, api.CodeSuccess, "Archive created successfully", info)
+}
@@ -1,26 +1,53 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"jia-file/api"
+	"jia-file/internal/config"
 	"jia-file/internal/file"
+	"jia-file/internal/index"
+	"jia-file/internal/job"
 	"jia-file/internal/logger"
+	"mime"
 	"net/http"
+	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // Handler HTTP处理器
 type Handler struct {
+	config      *config.Config
 	fileService file.Service
+	jobs        *job.Manager
+	index       *index.Index
 }
 
-// NewHandler 创建HTTP处理器实例
-func NewHandler(fileService file.Service) *Handler {
+// NewHandler 创建HTTP处理器实例，未启用全文索引时 idx 为 nil
+func NewHandler(cfg *config.Config, fileService file.Service, jobs *job.Manager, idx *index.Index) *Handler {
 	return &Handler{
+		config:      cfg,
 		fileService: fileService,
+		jobs:        jobs,
+		index:       idx,
 	}
 }
 
+// withTimeout 基于请求的 context 创建带超时的 context
+// 客户端断开连接时 context 会被取消；timeout 为 0 时不设置超时
+func withTimeout(r *http.Request, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(r.Context())
+	}
+	return context.WithTimeout(r.Context(), timeout)
+}
+
 // writeResponse 写入统一格式的响应
 func (h *Handler) writeResponse(w http.ResponseWriter, code int, message string, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
@@ -40,14 +67,98 @@ func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	files, err := h.fileService.List(path)
+	opts, err := parseListOptions(r)
+	if err != nil {
+		h.writeResponse(w, api.CodeParamMissing, err.Error(), nil)
+		return
+	}
+
+	ctx, cancel := withTimeout(r, h.config.Timeout.List)
+	defer cancel()
+
+	result, err := h.fileService.List(ctx, path, opts)
 	if err != nil {
 		logger.Error("List error: %v", err)
 		h.writeResponse(w, api.CodeOperationFail, err.Error(), nil)
 		return
 	}
 
-	h.writeResponse(w, api.CodeSuccess, "success", files)
+	items := make([]interface{}, 0, len(result.Items))
+	for _, item := range result.Items {
+		projected, err := opts.Fields.Project(item)
+		if err != nil {
+			h.writeResponse(w, api.CodeOperationFail, err.Error(), nil)
+			return
+		}
+		items = append(items, projected)
+	}
+
+	h.writeResponse(w, api.CodeSuccess, "success", map[string]interface{}{
+		"items":      items,
+		"nextCursor": result.NextCursor,
+		"total":      result.Total,
+	})
+}
+
+// parseListOptions 解析列出目录的分页、排序、过滤和投影参数
+func parseListOptions(r *http.Request) (file.ListOptions, error) {
+	query := r.URL.Query()
+	opts := file.ListOptions{
+		Cursor:     query.Get("cursor"),
+		Desc:       query.Get("order") == "desc",
+		DirsFirst:  query.Get("dirsFirst") == "true",
+		Glob:       query.Get("glob"),
+		HideHidden: query.Get("hidden") == "false",
+		Type:       query.Get("type"),
+	}
+
+	var err error
+	if opts.Sort, err = file.ParseSortField(query.Get("sort")); err != nil {
+		return opts, err
+	}
+	if order := query.Get("order"); order != "" && order != "asc" && order != "desc" {
+		return opts, fmt.Errorf("invalid order: %s", order)
+	}
+	if opts.Type != "" && opts.Type != "file" && opts.Type != "dir" && opts.Type != "symlink" {
+		return opts, fmt.Errorf("invalid type: %s", opts.Type)
+	}
+	if ext := query.Get("ext"); ext != "" {
+		opts.Extensions = strings.Split(ext, ",")
+	}
+	if opts.Limit, err = parseIntParam(query.Get("limit")); err != nil {
+		return opts, fmt.Errorf("invalid limit: %v", err)
+	}
+	if opts.MinSize, err = parseInt64Param(query.Get("minSize")); err != nil {
+		return opts, fmt.Errorf("invalid minSize: %v", err)
+	}
+	if opts.MaxSize, err = parseInt64Param(query.Get("maxSize")); err != nil {
+		return opts, fmt.Errorf("invalid maxSize: %v", err)
+	}
+	if since := query.Get("modifiedSince"); since != "" {
+		if opts.ModifiedSince, err = time.Parse(time.RFC3339, since); err != nil {
+			return opts, fmt.Errorf("invalid modifiedSince, expected RFC3339: %v", err)
+		}
+	}
+	if opts.Fields, err = file.ParseFields(query.Get("fields")); err != nil {
+		return opts, err
+	}
+	return opts, nil
+}
+
+// parseIntParam 解析整数参数，空字符串返回 0
+func parseIntParam(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(value)
+}
+
+// parseInt64Param 解析 int64 参数，空字符串返回 0
+func parseInt64Param(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(value, 10, 64)
 }
 
 // CreateDir 创建目录
@@ -63,7 +174,10 @@ func (h *Handler) CreateDir(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.fileService.CreateDir(path); err != nil {
+	ctx, cancel := withTimeout(r, h.config.Timeout.Info)
+	defer cancel()
+
+	if err := h.fileService.CreateDir(ctx, path); err != nil {
 		logger.Error("CreateDir error: %v", err)
 		h.writeResponse(w, api.CodeOperationFail, err.Error(), nil)
 		return
@@ -88,7 +202,10 @@ func (h *Handler) CreateFile(w http.ResponseWriter, r *http.Request) {
 	content := r.Body
 	defer content.Close()
 
-	if err := h.fileService.CreateFile(path, nil); err != nil {
+	ctx, cancel := withTimeout(r, h.config.Timeout.Write)
+	defer cancel()
+
+	if err := h.fileService.CreateFile(ctx, path, content); err != nil {
 		logger.Error("CreateFile error: %v", err)
 		h.writeResponse(w, api.CodeOperationFail, err.Error(), nil)
 		return
@@ -97,7 +214,96 @@ func (h *Handler) CreateFile(w http.ResponseWriter, r *http.Request) {
 	h.writeResponse(w, api.CodeSuccess, "File created successfully", nil)
 }
 
-// Delete 删除文件或目录
+// Upload 上传文件
+//   - PUT: 将请求体直接写入 path 指定的文件
+//   - POST multipart/form-data: 将所有文件字段写入 path 指定的目录，支持一次上传多个文件
+//
+// 可通过 conflict 参数指定目标已存在时的策略：fail（默认）、overwrite、rename
+func (h *Handler) Upload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut && r.Method != http.MethodPost {
+		h.writeResponse(w, api.CodeMethodNotAllow, "Method not allowed", nil)
+		return
+	}
+
+	path := r.URL.Query().Get("path")
+	if path == "" {
+		h.writeResponse(w, api.CodeParamMissing, "Missing path parameter", nil)
+		return
+	}
+
+	conflict, err := file.ParseConflictPolicy(r.URL.Query().Get("conflict"))
+	if err != nil {
+		h.writeResponse(w, api.CodeParamMissing, err.Error(), nil)
+		return
+	}
+	opts := file.WriteOptions{Conflict: conflict}
+	defer r.Body.Close()
+
+	ctx, cancel := withTimeout(r, h.config.Timeout.Write)
+	defer cancel()
+
+	if r.Method == http.MethodPut {
+		info, err := h.fileService.WriteFile(ctx, path, r.Body, opts)
+		if err != nil {
+			logger.Error("Upload error: %v", err)
+			h.writeResponse(w, api.CodeOperationFail, err.Error(), nil)
+			return
+		}
+		h.writeResponse(w, api.CodeSuccess, "File uploaded successfully", []file.FileInfo{info})
+		return
+	}
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		h.writeResponse(w, api.CodeParamMissing, "Expected multipart/form-data body", nil)
+		return
+	}
+
+	files := make([]file.FileInfo, 0)
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			logger.Error("Upload error: %v", err)
+			h.writeResponse(w, api.CodeOperationFail, err.Error(), files)
+			return
+		}
+
+		// 忽略非文件字段
+		if part.FileName() == "" {
+			part.Close()
+			continue
+		}
+
+		name := filepath.Base(part.FileName())
+		if name == "." || name == ".." || name == string(filepath.Separator) {
+			part.Close()
+			h.writeResponse(w, api.CodeParamMissing, "Invalid file name: "+part.FileName(), files)
+			return
+		}
+
+		info, err := h.fileService.WriteFile(ctx, filepath.Join(path, name), part, opts)
+		part.Close()
+		if err != nil {
+			logger.Error("Upload error: %v", err)
+			h.writeResponse(w, api.CodeOperationFail, err.Error(), files)
+			return
+		}
+		files = append(files, info)
+	}
+
+	if len(files) == 0 {
+		h.writeResponse(w, api.CodeParamMissing, "No file found in request", nil)
+		return
+	}
+
+	h.writeResponse(w, api.CodeSuccess, "Files uploaded successfully", files)
+}
+
+// Delete 删除文件或目录，默认移入回收站
+// permanent=true 时永久删除，需要管理员通过 TRASH_ALLOW_PERMANENT 开启
 func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodDelete {
 		h.writeResponse(w, api.CodeMethodNotAllow, "Method not allowed", nil)
@@ -110,12 +316,35 @@ func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.fileService.Delete(path); err != nil {
+	opts := file.DeleteOptions{Permanent: r.URL.Query().Get("permanent") == "true"}
+	if opts.Permanent && !h.config.Trash.AllowPermanent {
+		h.writeResponse(w, api.CodeOperationFail, "Permanent delete is disabled", nil)
+		return
+	}
+
+	if isAsync(r) {
+		params := map[string]string{"path": path, "permanent": fmt.Sprint(opts.Permanent)}
+		h.submitJob(w, "delete", params, func(ctx context.Context, progress *file.Progress) (interface{}, error) {
+			opts.Progress = progress
+			return h.fileService.Delete(ctx, path, opts)
+		})
+		return
+	}
+
+	ctx, cancel := withTimeout(r, h.config.Timeout.Delete)
+	defer cancel()
+
+	item, err := h.fileService.Delete(ctx, path, opts)
+	if err != nil {
 		logger.Error("Delete error: %v", err)
 		h.writeResponse(w, api.CodeOperationFail, err.Error(), nil)
 		return
 	}
 
+	if item != nil {
+		h.writeResponse(w, api.CodeSuccess, "File or directory moved to trash", item)
+		return
+	}
 	h.writeResponse(w, api.CodeSuccess, "File or directory deleted successfully", nil)
 }
 
@@ -133,13 +362,31 @@ func (h *Handler) Move(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.fileService.Move(src, dst); err != nil {
+	conflict, err := file.ParseConflictPolicy(r.URL.Query().Get("conflict"))
+	if err != nil {
+		h.writeResponse(w, api.CodeParamMissing, err.Error(), nil)
+		return
+	}
+
+	if isAsync(r) {
+		params := map[string]string{"src": src, "dst": dst, "conflict": string(conflict)}
+		h.submitJob(w, "move", params, func(ctx context.Context, progress *file.Progress) (interface{}, error) {
+			return h.fileService.Move(ctx, src, dst, file.MoveOptions{Conflict: conflict, Progress: progress})
+		})
+		return
+	}
+
+	ctx, cancel := withTimeout(r, h.config.Timeout.Move)
+	defer cancel()
+
+	info, err := h.fileService.Move(ctx, src, dst, file.MoveOptions{Conflict: conflict})
+	if err != nil {
 		logger.Error("Move error: %v", err)
 		h.writeResponse(w, api.CodeOperationFail, err.Error(), nil)
 		return
 	}
 
-	h.writeResponse(w, api.CodeSuccess, "File or directory moved successfully", nil)
+	h.writeResponse(w, api.CodeSuccess, "File or directory moved successfully", info)
 }
 
 // Copy 复制文件或目录
@@ -156,13 +403,36 @@ func (h *Handler) Copy(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.fileService.Copy(src, dst); err != nil {
+	conflict, err := file.ParseConflictPolicy(r.URL.Query().Get("conflict"))
+	if err != nil {
+		h.writeResponse(w, api.CodeParamMissing, err.Error(), nil)
+		return
+	}
+	opts := file.CopyOptions{
+		Conflict:       conflict,
+		FollowSymlinks: r.URL.Query().Get("followSymlinks") == "true",
+	}
+
+	if isAsync(r) {
+		params := map[string]string{"src": src, "dst": dst, "conflict": string(conflict), "followSymlinks": fmt.Sprint(opts.FollowSymlinks)}
+		h.submitJob(w, "copy", params, func(ctx context.Context, progress *file.Progress) (interface{}, error) {
+			opts.Progress = progress
+			return h.fileService.Copy(ctx, src, dst, opts)
+		})
+		return
+	}
+
+	ctx, cancel := withTimeout(r, h.config.Timeout.Copy)
+	defer cancel()
+
+	result, err := h.fileService.Copy(ctx, src, dst, opts)
+	if err != nil {
 		logger.Error("Copy error: %v", err)
-		h.writeResponse(w, api.CodeOperationFail, err.Error(), nil)
+		h.writeResponse(w, api.CodeOperationFail, err.Error(), result)
 		return
 	}
 
-	h.writeResponse(w, api.CodeSuccess, "File or directory copied successfully", nil)
+	h.writeResponse(w, api.CodeSuccess, "File or directory copied successfully", result)
 }
 
 // GetInfo 获取文件信息
@@ -173,14 +443,83 @@ func (h *Handler) GetInfo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	info, err := h.fileService.GetInfo(path)
+	fields, err := file.ParseFields(r.URL.Query().Get("fields"))
+	if err != nil {
+		h.writeResponse(w, api.CodeParamMissing, err.Error(), nil)
+		return
+	}
+
+	ctx, cancel := withTimeout(r, h.config.Timeout.Info)
+	defer cancel()
+
+	info, err := h.fileService.GetInfo(ctx, path)
 	if err != nil {
 		logger.Error("GetInfo error: %v", err)
 		h.writeResponse(w, api.CodeOperationFail, err.Error(), nil)
 		return
 	}
+	if fields.Requested("xattrs") && !info.IsSymlink {
+		if info.Xattrs, err = h.fileService.ListXattrs(ctx, path); err != nil {
+			logger.Error("ListXattrs error: %v", err)
+			h.writeResponse(w, api.CodeOperationFail, err.Error(), nil)
+			return
+		}
+	}
+
+	projected, err := fields.Project(info)
+	if err != nil {
+		h.writeResponse(w, api.CodeOperationFail, err.Error(), nil)
+		return
+	}
+	h.writeResponse(w, api.CodeSuccess, "success", projected)
+}
+
+// Download 下载文件内容
+// 支持 Range 分段请求以及 If-None-Match / If-Modified-Since 条件请求
+func (h *Handler) Download(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		h.writeResponse(w, api.CodeMethodNotAllow, "Method not allowed", nil)
+		return
+	}
+
+	path := r.URL.Query().Get("path")
+	if path == "" {
+		h.writeResponse(w, api.CodeParamMissing, "Missing path parameter", nil)
+		return
+	}
+
+	// 归档成员的读取器在传输过程中仍使用 ctx，超时需要覆盖整个传输
+	ctx, cancel := withTimeout(r, h.config.Timeout.Download)
+	defer cancel()
+
+	reader, info, err := h.fileService.Open(ctx, path)
+	if err != nil {
+		logger.Error("Download error: %v", err)
+		if errors.Is(err, os.ErrNotExist) {
+			h.writeResponse(w, api.CodePathNotExist, err.Error(), nil)
+			return
+		}
+		h.writeResponse(w, api.CodeOperationFail, err.Error(), nil)
+		return
+	}
+	defer reader.Close()
+
+	w.Header().Set("Content-Type", info.MimeType)
+	w.Header().Set("ETag", h.fileETag(ctx, path, info))
+	if r.URL.Query().Get("attachment") == "true" {
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": info.Name}))
+	}
 
-	h.writeResponse(w, api.CodeSuccess, "success", info)
+	// ServeContent 负责处理 Range、If-Range、If-None-Match 和 If-Modified-Since
+	http.ServeContent(w, r, info.Name, info.ModTime, reader)
+}
+
+// fileETag 生成 ETag：已缓存与当前文件状态一致的 SHA-256 时使用内容哈希，否则根据文件大小和修改时间生成
+func (h *Handler) fileETag(ctx context.Context, path string, info file.FileInfo) string {
+	if sum, ok := h.fileService.CachedChecksum(ctx, path, info, file.HashSHA256); ok {
+		return "\"sha256-" + sum + "\""
+	}
+	return fmt.Sprintf("\"%x-%x\"", info.ModTime.UnixNano(), info.Size)
 }
 
 // CreateDocumentRequest 创建文档请求
@@ -217,7 +556,10 @@ func (h *Handler) CreateDocument(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 创建文档
-	if err := h.fileService.CreateDocument(req.Path, req.Type, req.Content); err != nil {
+	ctx, cancel := withTimeout(r, h.config.Timeout.Write)
+	defer cancel()
+
+	if err := h.fileService.CreateDocument(ctx, req.Path, req.Type, req.Content); err != nil {
 		logger.Error("CreateDocument error: %v", err)
 		h.writeResponse(w, api.CodeOperationFail, err.Error(), nil)
 		return
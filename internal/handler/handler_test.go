@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"jia-file/api"
+	"jia-file/internal/config"
+	"jia-file/internal/file"
+)
+
+// deleteRecorder 只实现 Delete 的文件服务，记录收到的删除选项
+type deleteRecorder struct {
+	file.Service
+	calls []file.DeleteOptions
+}
+
+func (d *deleteRecorder) Delete(ctx context.Context, path string, opts file.DeleteOptions) (*file.TrashItem, error) {
+	d.calls = append(d.calls, opts)
+	return nil, nil
+}
+
+func TestDeletePermanent(t *testing.T) {
+	tests := []struct {
+		allow     bool
+		query     string
+		wantCode  int
+		wantCalls int
+	}{
+		{allow: false, query: "permanent=true", wantCode: api.CodeOperationFail},
+		{allow: false, query: "permanent=false", wantCode: api.CodeSuccess, wantCalls: 1},
+		{allow: true, query: "permanent=true", wantCode: api.CodeSuccess, wantCalls: 1},
+	}
+	for _, tt := range tests {
+		cfg := &config.Config{}
+		cfg.Trash.AllowPermanent = tt.allow
+		service := &deleteRecorder{}
+		h := NewHandler(cfg, service, nil, nil)
+
+		w := httptest.NewRecorder()
+		h.Delete(w, httptest.NewRequest(http.MethodDelete, "/delete?path=/data/a.txt&"+tt.query, nil))
+
+		var resp api.Response
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		if resp.Code != tt.wantCode || len(service.calls) != tt.wantCalls {
+			t.Errorf("allow %v %s: code = %d (%s), calls = %d; want %d, %d",
+				tt.allow, tt.query, resp.Code, resp.Message, len(service.calls), tt.wantCode, tt.wantCalls)
+		}
+		// 允许时永久删除选项应传给服务
+		if tt.allow && len(service.calls) == 1 && !service.calls[0].Permanent {
+			t.Errorf("allow %v %s: Delete() called without Permanent", tt.allow, tt.query)
+		}
+	}
+}
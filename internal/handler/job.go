@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"errors"
+	"jia-file/api"
+	"jia-file/internal/job"
+	"jia-file/internal/logger"
+	"net/http"
+)
+
+// isAsync 判断请求是否要求以后台任务方式执行
+func isAsync(r *http.Request) bool {
+	return r.URL.Query().Get("async") == "true"
+}
+
+// submitJob 提交后台任务并返回任务信息，任何处理器都可以通过 async=true 参数使用
+func (h *Handler) submitJob(w http.ResponseWriter, jobType string, params map[string]string, fn job.Func) {
+	submitted, err := h.jobs.Submit(jobType, params, fn)
+	if err != nil {
+		logger.Error("Submit job error: %v", err)
+		h.writeResponse(w, api.CodeOperationFail, err.Error(), nil)
+		return
+	}
+
+	h.writeResponse(w, api.CodeSuccess, "Job submitted", submitted)
+}
+
+// ListJobs 列出后台任务，可通过 status 参数过滤
+func (h *Handler) ListJobs(w http.ResponseWriter, r *http.Request) {
+	status := job.Status(r.URL.Query().Get("status"))
+	h.writeResponse(w, api.CodeSuccess, "success", h.jobs.List(status))
+}
+
+// GetJob 获取后台任务的状态和进度
+func (h *Handler) GetJob(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		h.writeResponse(w, api.CodeParamMissing, "Missing id parameter", nil)
+		return
+	}
+
+	info, err := h.jobs.Get(id)
+	if err != nil {
+		if errors.Is(err, job.ErrNotFound) {
+			h.writeResponse(w, api.CodePathNotExist, err.Error(), nil)
+			return
+		}
+		h.writeResponse(w, api.CodeOperationFail, err.Error(), nil)
+		return
+	}
+
+	h.writeResponse(w, api.CodeSuccess, "success", info)
+}
+
+// CancelJob 取消后台任务
+func (h *Handler) CancelJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.writeResponse(w, api.CodeMethodNotAllow, "Method not allowed", nil)
+		return
+	}
+
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		h.writeResponse(w, api.CodeParamMissing, "Missing id parameter", nil)
+		return
+	}
+
+	info, err := h.jobs.Cancel(id)
+	if err != nil {
+		logger.Error("CancelJob error: %v", err)
+		if errors.Is(err, job.ErrNotFound) {
+			h.writeResponse(w, api.CodePathNotExist, err.Error(), nil)
+			return
+		}
+		h.writeResponse(w, api.CodeOperationFail, err.Error(), info)
+		return
+	}
+
+	h.writeResponse(w, api.CodeSuccess, "Job cancellation requested", info)
+}
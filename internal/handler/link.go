@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"jia-file/api"
+	"jia-file/internal/file"
+	"jia-file/internal/logger"
+)
+
+// CreateSymlink 创建符号链接
+func (h *Handler) CreateSymlink(w http.ResponseWriter, r *http.Request) {
+	h.createLink(w, r, "symlink", h.fileService.CreateSymlink)
+}
+
+// CreateHardLink 创建硬链接
+func (h *Handler) CreateHardLink(w http.ResponseWriter, r *http.Request) {
+	h.createLink(w, r, "hard link", h.fileService.CreateHardLink)
+}
+
+// createLink 解析 path（链接路径）、target（链接目标）和 conflict 参数后调用 create
+func (h *Handler) createLink(w http.ResponseWriter, r *http.Request, kind string,
+	create func(ctx context.Context, target, link string, opts file.LinkOptions) (file.FileInfo, error)) {
+	if r.Method != http.MethodPost {
+		h.writeResponse(w, api.CodeMethodNotAllow, "Method not allowed", nil)
+		return
+	}
+
+	path := r.URL.Query().Get("path")
+	target := r.URL.Query().Get("target")
+	if path == "" || target == "" {
+		h.writeResponse(w, api.CodeParamMissing, "Missing path or target parameter", nil)
+		return
+	}
+
+	conflict, err := file.ParseConflictPolicy(r.URL.Query().Get("conflict"))
+	if err != nil {
+		h.writeResponse(w, api.CodeParamMissing, err.Error(), nil)
+		return
+	}
+
+	ctx, cancel := withTimeout(r, h.config.Timeout.Write)
+	defer cancel()
+
+	info, err := create(ctx, target, path, file.LinkOptions{Conflict: conflict})
+	if err != nil {
+		logger.Error("Create %s error: %v", kind, err)
+		h.writeResponse(w, api.CodeOperationFail, err.Error(), nil)
+		return
+	}
+
+	h.writeResponse(w, api.CodeSuccess, "Link created successfully", info)
+}
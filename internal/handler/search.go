@@ -0,0 +1,238 @@
+package handler
+
+import (
+	"fmt"
+	"jia-file/api"
+	"jia-file/internal/file"
+	"jia-file/internal/index"
+	"jia-file/internal/logger"
+	"net/http"
+	"strings"
+)
+
+const (
+	// defaultFullTextLimit 全文搜索未指定 limit 时返回的结果数
+	defaultFullTextLimit = 20
+	// maxFullTextLimit 全文搜索单次最多返回的结果数
+	maxFullTextLimit = 100
+)
+
+// Search 按名称搜索文件
+// 结果以 NDJSON 格式流式返回：每个匹配项一行 {"type":"match"}，结束时一行 {"type":"done"} 汇总，
+// 遍历中途出错时以 {"type":"error"} 结束
+func (h *Handler) Search(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	path := query.Get("path")
+	if path == "" {
+		h.writeResponse(w, api.CodeParamMissing, "Missing path parameter", nil)
+		return
+	}
+
+	opts, err := parseSearchOptions(r)
+	if err != nil {
+		h.writeResponse(w, api.CodeParamMissing, err.Error(), nil)
+		return
+	}
+
+	ctx, cancel := withTimeout(r, h.config.Timeout.Search)
+	defer cancel()
+
+	stream := newNDJSONStream(w)
+	summary, err := h.fileService.Search(ctx, path, opts, func(info file.FileInfo) error {
+		projected, err := opts.Fields.Project(info)
+		if err != nil {
+			return err
+		}
+		return stream.write(streamEvent{Type: "match", Data: projected})
+	})
+	if err != nil {
+		logger.Error("Search error: %v", err)
+		if !stream.started {
+			h.writeResponse(w, api.CodeOperationFail, err.Error(), nil)
+			return
+		}
+		stream.write(streamEvent{Type: "error", Message: err.Error()})
+		return
+	}
+
+	stream.write(streamEvent{Type: "done", Data: summary})
+}
+
+// parseSearchOptions 解析搜索参数
+func parseSearchOptions(r *http.Request) (file.SearchOptions, error) {
+	query := r.URL.Query()
+	opts := file.SearchOptions{
+		Pattern:    query.Get("pattern"),
+		IgnoreCase: query.Get("ignoreCase") == "true",
+		Include:    splitList(query.Get("include")),
+		Exclude:    splitList(query.Get("exclude")),
+		HideHidden: query.Get("hidden") == "false",
+	}
+
+	var err error
+	if opts.Mode, err = file.ParseMatchMode(query.Get("mode")); err != nil {
+		return opts, err
+	}
+	if opts.MaxResults, err = parseIntParam(query.Get("maxResults")); err != nil || opts.MaxResults < 0 {
+		return opts, fmt.Errorf("invalid maxResults")
+	}
+	if opts.MaxDepth, err = parseIntParam(query.Get("maxDepth")); err != nil || opts.MaxDepth < 0 {
+		return opts, fmt.Errorf("invalid maxDepth")
+	}
+	if opts.Fields, err = file.ParseFields(query.Get("fields")); err != nil {
+		return opts, err
+	}
+	return opts, nil
+}
+
+// splitList 解析逗号分隔的列表，忽略空项
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Grep 搜索文件内容
+// 结果以 NDJSON 格式流式返回，格式同 Search，每个匹配行一行 {"type":"match"}
+func (h *Handler) Grep(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	path := query.Get("path")
+	if path == "" {
+		h.writeResponse(w, api.CodeParamMissing, "Missing path parameter", nil)
+		return
+	}
+
+	opts, err := parseGrepOptions(r)
+	if err != nil {
+		h.writeResponse(w, api.CodeParamMissing, err.Error(), nil)
+		return
+	}
+	opts.Workers = h.config.Grep.Workers
+
+	ctx, cancel := withTimeout(r, h.config.Timeout.Search)
+	defer cancel()
+
+	stream := newNDJSONStream(w)
+	summary, err := h.fileService.Grep(ctx, path, opts, func(match file.GrepMatch) error {
+		return stream.write(streamEvent{Type: "match", Data: match})
+	})
+	if err != nil {
+		logger.Error("Grep error: %v", err)
+		if !stream.started {
+			h.writeResponse(w, api.CodeOperationFail, err.Error(), nil)
+			return
+		}
+		stream.write(streamEvent{Type: "error", Message: err.Error()})
+		return
+	}
+
+	stream.write(streamEvent{Type: "done", Data: summary})
+}
+
+// parseGrepOptions 解析内容搜索参数
+func parseGrepOptions(r *http.Request) (file.GrepOptions, error) {
+	query := r.URL.Query()
+	opts := file.GrepOptions{
+		Pattern:    query.Get("pattern"),
+		Regex:      query.Get("regex") == "true",
+		IgnoreCase: query.Get("ignoreCase") == "true",
+		Include:    splitList(query.Get("include")),
+		Exclude:    splitList(query.Get("exclude")),
+		HideHidden: query.Get("hidden") == "false",
+	}
+	if opts.Pattern == "" {
+		return opts, fmt.Errorf("Missing pattern parameter")
+	}
+
+	var err error
+	if lines := query.Get("context"); lines != "" {
+		if opts.Before, err = parseIntParam(lines); err != nil || opts.Before < 0 {
+			return opts, fmt.Errorf("invalid context")
+		}
+		opts.After = opts.Before
+	}
+	if before := query.Get("before"); before != "" {
+		if opts.Before, err = parseIntParam(before); err != nil || opts.Before < 0 {
+			return opts, fmt.Errorf("invalid before")
+		}
+	}
+	if after := query.Get("after"); after != "" {
+		if opts.After, err = parseIntParam(after); err != nil || opts.After < 0 {
+			return opts, fmt.Errorf("invalid after")
+		}
+	}
+	if opts.MaxPerFile, err = parseIntParam(query.Get("maxPerFile")); err != nil || opts.MaxPerFile < 0 {
+		return opts, fmt.Errorf("invalid maxPerFile")
+	}
+	if opts.MaxMatches, err = parseIntParam(query.Get("maxMatches")); err != nil || opts.MaxMatches < 0 {
+		return opts, fmt.Errorf("invalid maxMatches")
+	}
+	if opts.MaxFileSize, err = parseInt64Param(query.Get("maxFileSize")); err != nil || opts.MaxFileSize < 0 {
+		return opts, fmt.Errorf("invalid maxFileSize")
+	}
+	if opts.MaxDepth, err = parseIntParam(query.Get("maxDepth")); err != nil || opts.MaxDepth < 0 {
+		return opts, fmt.Errorf("invalid maxDepth")
+	}
+	return opts, nil
+}
+
+// FullTextSearch 基于全文索引搜索文件内容
+// q 支持普通词项、前缀词项（foo*）和短语（"foo bar"），多个子句之间为 AND 关系
+func (h *Handler) FullTextSearch(w http.ResponseWriter, r *http.Request) {
+	if h.index == nil {
+		h.writeResponse(w, api.CodeOperationFail, "Full-text index is disabled", nil)
+		return
+	}
+
+	query := r.URL.Query()
+	q := query.Get("q")
+	if q == "" {
+		h.writeResponse(w, api.CodeParamMissing, "Missing q parameter", nil)
+		return
+	}
+
+	limit, err := parseIntParam(query.Get("limit"))
+	if err != nil || limit < 0 {
+		h.writeResponse(w, api.CodeParamMissing, "Invalid limit parameter", nil)
+		return
+	}
+	if limit == 0 {
+		limit = defaultFullTextLimit
+	}
+	if limit > maxFullTextLimit {
+		limit = maxFullTextLimit
+	}
+	offset, err := parseIntParam(query.Get("offset"))
+	if err != nil || offset < 0 {
+		h.writeResponse(w, api.CodeParamMissing, "Invalid offset parameter", nil)
+		return
+	}
+
+	searchPath := ""
+	if path := query.Get("path"); path != "" {
+		if searchPath, err = file.NewPathProcessor(h.config.File.RootPath).ProcessPath(path); err != nil {
+			h.writeResponse(w, api.CodeOperationFail, err.Error(), nil)
+			return
+		}
+	}
+
+	ctx, cancel := withTimeout(r, h.config.Timeout.Search)
+	defer cancel()
+
+	result, err := h.index.Search(ctx, index.Query{Text: q, Path: searchPath, Limit: limit, Offset: offset})
+	if err != nil {
+		logger.Error("Full-text search error: %v", err)
+		h.writeResponse(w, api.CodeOperationFail, err.Error(), nil)
+		return
+	}
+
+	h.writeResponse(w, api.CodeSuccess, "success", map[string]interface{}{
+		"items": result.Hits,
+		"total": result.Total,
+		"index": h.index.Stats(),
+	})
+}
@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// streamEvent 流式响应中的一行
+type streamEvent struct {
+	Type    string      `json:"type"`              // 事件类型：match、done、error
+	Data    interface{} `json:"data,omitempty"`    // 事件数据
+	Message string      `json:"message,omitempty"` // 错误信息
+}
+
+// ndjsonStream 以 NDJSON（每行一个 JSON 对象）格式流式写出结果
+// 响应头在写出第一行时才发送，因此在此之前发生的错误仍可以使用统一的 JSON 响应格式返回
+type ndjsonStream struct {
+	w       http.ResponseWriter
+	encoder *json.Encoder
+	flusher http.Flusher
+	started bool
+}
+
+// newNDJSONStream 创建 NDJSON 流
+func newNDJSONStream(w http.ResponseWriter) *ndjsonStream {
+	flusher, _ := w.(http.Flusher)
+	return &ndjsonStream{w: w, encoder: json.NewEncoder(w), flusher: flusher}
+}
+
+// write 写出一行并立即刷新，使客户端在遍历过程中就能收到结果
+func (s *ndjsonStream) write(event streamEvent) error {
+	if !s.started {
+		s.w.Header().Set("Content-Type", "application/x-ndjson")
+		s.w.Header().Set("Cache-Control", "no-cache")
+		s.w.Header().Set("X-Content-Type-Options", "nosniff")
+		s.started = true
+	}
+	if err := s.encoder.Encode(event); err != nil {
+		return err
+	}
+	if s.flusher != nil {
+		s.flusher.Flush()
+	}
+	return nil
+}
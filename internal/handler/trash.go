@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"jia-file/api"
+	"jia-file/internal/file"
+	"jia-file/internal/logger"
+)
+
+// ListTrash 列出回收站中的条目，path 参数只返回原路径位于该路径之下的条目
+func (h *Handler) ListTrash(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.writeResponse(w, api.CodeMethodNotAllow, "Method not allowed", nil)
+		return
+	}
+
+	ctx, cancel := withTimeout(r, h.config.Timeout.List)
+	defer cancel()
+
+	result, err := h.fileService.ListTrash(ctx, file.TrashListOptions{Path: r.URL.Query().Get("path")})
+	if err != nil {
+		logger.Error("List trash error: %v", err)
+		h.writeResponse(w, api.CodeOperationFail, err.Error(), nil)
+		return
+	}
+
+	h.writeResponse(w, api.CodeSuccess, "success", result)
+}
+
+// RestoreTrash 将回收站条目恢复到原路径，dst 参数可以指定其他路径
+func (h *Handler) RestoreTrash(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.writeResponse(w, api.CodeMethodNotAllow, "Method not allowed", nil)
+		return
+	}
+
+	query := r.URL.Query()
+	id := query.Get("id")
+	if id == "" {
+		h.writeResponse(w, api.CodeParamMissing, "Missing id parameter", nil)
+		return
+	}
+	conflict, err := file.ParseConflictPolicy(query.Get("conflict"))
+	if err != nil {
+		h.writeResponse(w, api.CodeParamMissing, err.Error(), nil)
+		return
+	}
+	opts := file.RestoreOptions{Dst: query.Get("dst"), Conflict: conflict}
+
+	if isAsync(r) {
+		params := map[string]string{"id": id, "dst": opts.Dst, "conflict": string(conflict)}
+		h.submitJob(w, "restore", params, func(ctx context.Context, progress *file.Progress) (interface{}, error) {
+			opts.Progress = progress
+			return h.fileService.RestoreTrash(ctx, id, opts)
+		})
+		return
+	}
+
+	ctx, cancel := withTimeout(r, h.config.Timeout.Move)
+	defer cancel()
+
+	info, err := h.fileService.RestoreTrash(ctx, id, opts)
+	if err != nil {
+		logger.Error("Restore trash error: %v", err)
+		h.writeResponse(w, api.CodeOperationFail, err.Error(), nil)
+		return
+	}
+
+	h.writeResponse(w, api.CodeSuccess, "Trash item restored successfully", info)
+}
+
+// PurgeTrash 永久删除回收站条目
+// 可以重复传入 id 参数，all=true 清空回收站，before 清除在此时间之前删除的条目
+func (h *Handler) PurgeTrash(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.writeResponse(w, api.CodeMethodNotAllow, "Method not allowed", nil)
+		return
+	}
+
+	query := r.URL.Query()
+	opts := file.PurgeOptions{All: query.Get("all") == "true"}
+	for _, id := range query["id"] {
+		if id != "" {
+			opts.IDs = append(opts.IDs, id)
+		}
+	}
+	if before := query.Get("before"); before != "" {
+		var err error
+		if opts.Before, err = time.Parse(time.RFC3339, before); err != nil {
+			h.writeResponse(w, api.CodeParamMissing, fmt.Sprintf("invalid before, expected RFC3339: %v", err), nil)
+			return
+		}
+	}
+	if len(opts.IDs) == 0 && !opts.All && opts.Before.IsZero() {
+		h.writeResponse(w, api.CodeParamMissing, "Missing id, all or before parameter", nil)
+		return
+	}
+
+	if isAsync(r) {
+		params := map[string]string{"id": strings.Join(opts.IDs, ","), "all": fmt.Sprint(opts.All), "before": query.Get("before")}
+		h.submitJob(w, "purge", params, func(ctx context.Context, progress *file.Progress) (interface{}, error) {
+			opts.Progress = progress
+			return h.fileService.PurgeTrash(ctx, opts)
+		})
+		return
+	}
+
+	ctx, cancel := withTimeout(r, h.config.Timeout.Delete)
+	defer cancel()
+
+	result, err := h.fileService.PurgeTrash(ctx, opts)
+	if err != nil {
+		logger.Error("Purge trash error: %v", err)
+		h.writeResponse(w, api.CodeOperationFail, err.Error(), result)
+		return
+	}
+
+	h.writeResponse(w, api.CodeSuccess, "success", result)
+}
@@ -0,0 +1,177 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"jia-file/api"
+	"jia-file/internal/file"
+	"jia-file/internal/logger"
+	"net/http"
+	"path/filepath"
+)
+
+// treeNodeView 目录树节点的响应格式，文件信息字段按投影展开
+type treeNodeView map[string]interface{}
+
+// Tree 获取目录树
+// depth 指定展开层数，maxNodes 指定节点数上限，二者都不能超过配置的最大值；
+// flat=true 时返回带 parent 引用的扁平列表
+func (h *Handler) Tree(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	path := query.Get("path")
+	if path == "" {
+		h.writeResponse(w, api.CodeParamMissing, "Missing path parameter", nil)
+		return
+	}
+
+	depth, err := parseIntParam(query.Get("depth"))
+	if err != nil || depth < 0 {
+		h.writeResponse(w, api.CodeParamMissing, "Invalid depth parameter", nil)
+		return
+	}
+	if depth == 0 {
+		depth = 1
+	}
+	if depth > h.config.Tree.MaxDepth {
+		h.writeResponse(w, api.CodeParamMissing, fmt.Sprintf("depth exceeds maximum of %d", h.config.Tree.MaxDepth), nil)
+		return
+	}
+
+	maxNodes, err := parseIntParam(query.Get("maxNodes"))
+	if err != nil || maxNodes < 0 {
+		h.writeResponse(w, api.CodeParamMissing, "Invalid maxNodes parameter", nil)
+		return
+	}
+	if maxNodes == 0 || maxNodes > h.config.Tree.MaxNodes {
+		maxNodes = h.config.Tree.MaxNodes
+	}
+
+	fields, err := file.ParseFields(query.Get("fields"))
+	if err != nil {
+		h.writeResponse(w, api.CodeParamMissing, err.Error(), nil)
+		return
+	}
+
+	opts := file.TreeOptions{
+		Depth:      depth,
+		MaxNodes:   maxNodes,
+		HideHidden: query.Get("hidden") == "false",
+		DirsOnly:   query.Get("dirsOnly") == "true",
+		Fields:     fields,
+	}
+
+	ctx, cancel := withTimeout(r, h.config.Timeout.List)
+	defer cancel()
+
+	result, err := h.fileService.Tree(ctx, path, opts)
+	if err != nil {
+		logger.Error("Tree error: %v", err)
+		h.writeResponse(w, api.CodeOperationFail, err.Error(), nil)
+		return
+	}
+
+	if query.Get("flat") == "true" {
+		nodes := make([]treeNodeView, 0, result.Nodes+1)
+		if err := flattenTree(result.Root, "", 0, fields, &nodes); err != nil {
+			h.writeResponse(w, api.CodeOperationFail, err.Error(), nil)
+			return
+		}
+		h.writeResponse(w, api.CodeSuccess, "success", map[string]interface{}{
+			"nodes":     nodes,
+			"truncated": result.Truncated,
+		})
+		return
+	}
+
+	root, err := projectTree(result.Root, fields)
+	if err != nil {
+		h.writeResponse(w, api.CodeOperationFail, err.Error(), nil)
+		return
+	}
+	h.writeResponse(w, api.CodeSuccess, "success", map[string]interface{}{
+		"root":      root,
+		"nodes":     result.Nodes,
+		"truncated": result.Truncated,
+	})
+}
+
+// nodeView 将节点的文件信息按投影转换为响应格式，并附加目录统计信息
+func nodeView(node *file.TreeNode, fields file.FieldSet) (treeNodeView, error) {
+	// 投影时强制保留 path，便于客户端定位节点
+	projectFields := fields
+	if len(fields) > 0 {
+		projectFields = make(file.FieldSet, len(fields)+1)
+		for field := range fields {
+			projectFields[field] = true
+		}
+		projectFields["path"] = true
+	}
+
+	projected, err := projectFields.Project(node.FileInfo)
+	if err != nil {
+		return nil, err
+	}
+	view, err := toMap(projected)
+	if err != nil {
+		return nil, err
+	}
+
+	if node.IsDir {
+		view["childCount"] = node.ChildCount
+		view["totalSize"] = node.TotalSize
+		view["truncated"] = node.Truncated
+	}
+	return view, nil
+}
+
+// projectTree 递归转换嵌套的目录树
+func projectTree(node *file.TreeNode, fields file.FieldSet) (treeNodeView, error) {
+	view, err := nodeView(node, fields)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(node.Children) > 0 {
+		children := make([]treeNodeView, 0, len(node.Children))
+		for _, child := range node.Children {
+			childView, err := projectTree(child, fields)
+			if err != nil {
+				return nil, err
+			}
+			children = append(children, childView)
+		}
+		view["children"] = children
+	}
+	return view, nil
+}
+
+// flattenTree 将目录树展开为扁平列表，每个节点带有父节点路径和层级
+func flattenTree(node *file.TreeNode, parent string, depth int, fields file.FieldSet, nodes *[]treeNodeView) error {
+	view, err := nodeView(node, fields)
+	if err != nil {
+		return err
+	}
+	view["parent"] = parent
+	view["depth"] = depth
+	*nodes = append(*nodes, view)
+
+	for _, child := range node.Children {
+		if err := flattenTree(child, filepath.Clean(node.Path), depth+1, fields, nodes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// toMap 将任意可序列化的值转换为 map
+func toMap(v interface{}) (treeNodeView, error) {
+	content, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	view := make(treeNodeView)
+	if err := json.Unmarshal(content, &view); err != nil {
+		return nil, err
+	}
+	return view, nil
+}
@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"jia-file/api"
+	"jia-file/internal/file"
+	"jia-file/internal/logger"
+)
+
+// DiskUsage 统计目录的磁盘用量
+// 结果按目录缓存，refresh=true 时重新统计；async=true 时以后台任务重新统计并更新缓存
+func (h *Handler) DiskUsage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.writeResponse(w, api.CodeMethodNotAllow, "Method not allowed", nil)
+		return
+	}
+
+	query := r.URL.Query()
+	path := query.Get("path")
+	if path == "" {
+		h.writeResponse(w, api.CodeParamMissing, "Missing path parameter", nil)
+		return
+	}
+	top, err := parseIntParam(query.Get("top"))
+	if err != nil || top < 0 {
+		h.writeResponse(w, api.CodeParamMissing, "Invalid top parameter", nil)
+		return
+	}
+	opts := file.DiskUsageOptions{Top: top, Refresh: query.Get("refresh") == "true"}
+
+	if isAsync(r) {
+		opts.Refresh = true
+		h.submitJob(w, "du", map[string]string{"path": path}, func(ctx context.Context, progress *file.Progress) (interface{}, error) {
+			opts.Progress = progress
+			return h.fileService.DiskUsage(ctx, path, opts)
+		})
+		return
+	}
+
+	ctx, cancel := withTimeout(r, h.config.Timeout.Usage)
+	defer cancel()
+
+	usage, err := h.fileService.DiskUsage(ctx, path, opts)
+	if err != nil {
+		logger.Error("Disk usage error: %v", err)
+		h.writeResponse(w, api.CodeOperationFail, err.Error(), nil)
+		return
+	}
+
+	h.writeResponse(w, api.CodeSuccess, "success", usage)
+}
@@ -0,0 +1,167 @@
+package handler
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+
+	"jia-file/api"
+	"jia-file/internal/file"
+	"jia-file/internal/logger"
+)
+
+// parseVersionID 解析版本号参数
+func parseVersionID(name, value string) (int, error) {
+	id, err := strconv.Atoi(value)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("invalid %s parameter: %q", name, value)
+	}
+	return id, nil
+}
+
+// ListVersions 列出文件的历史版本，文件已被删除时仍可列出
+func (h *Handler) ListVersions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.writeResponse(w, api.CodeMethodNotAllow, "Method not allowed", nil)
+		return
+	}
+
+	path := r.URL.Query().Get("path")
+	if path == "" {
+		h.writeResponse(w, api.CodeParamMissing, "Missing path parameter", nil)
+		return
+	}
+
+	ctx, cancel := withTimeout(r, h.config.Timeout.Info)
+	defer cancel()
+
+	history, err := h.fileService.ListVersions(ctx, path)
+	if err != nil {
+		logger.Error("List versions error: %v", err)
+		h.writeResponse(w, api.CodeOperationFail, err.Error(), nil)
+		return
+	}
+
+	h.writeResponse(w, api.CodeSuccess, "success", history)
+}
+
+// DownloadVersion 下载文件的历史版本，支持 Range 和条件请求
+func (h *Handler) DownloadVersion(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		h.writeResponse(w, api.CodeMethodNotAllow, "Method not allowed", nil)
+		return
+	}
+
+	query := r.URL.Query()
+	path := query.Get("path")
+	if path == "" || query.Get("id") == "" {
+		h.writeResponse(w, api.CodeParamMissing, "Missing path or id parameter", nil)
+		return
+	}
+	id, err := parseVersionID("id", query.Get("id"))
+	if err != nil {
+		h.writeResponse(w, api.CodeParamMissing, err.Error(), nil)
+		return
+	}
+
+	ctx, cancel := withTimeout(r, h.config.Timeout.Download)
+	defer cancel()
+
+	reader, version, err := h.fileService.OpenVersion(ctx, path, id)
+	if err != nil {
+		logger.Error("Download version error: %v", err)
+		h.writeResponse(w, api.CodeOperationFail, err.Error(), nil)
+		return
+	}
+	defer reader.Close()
+
+	name := filepath.Base(path)
+	// 版本内容不会改变，直接使用内容哈希作为 ETag
+	w.Header().Set("ETag", "\"sha256-"+version.Hash+"\"")
+	if query.Get("attachment") == "true" {
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	}
+
+	// 未设置 Content-Type 时 ServeContent 根据扩展名或内容判断
+	http.ServeContent(w, r, name, version.ModTime, reader)
+}
+
+// DiffVersions 比较文本文件的两个历史版本，to 为空时与当前内容比较
+func (h *Handler) DiffVersions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.writeResponse(w, api.CodeMethodNotAllow, "Method not allowed", nil)
+		return
+	}
+
+	query := r.URL.Query()
+	path := query.Get("path")
+	if path == "" || query.Get("from") == "" {
+		h.writeResponse(w, api.CodeParamMissing, "Missing path or from parameter", nil)
+		return
+	}
+
+	opts := file.DiffOptions{Context: file.DefaultDiffContext}
+	var err error
+	if opts.From, err = parseVersionID("from", query.Get("from")); err != nil {
+		h.writeResponse(w, api.CodeParamMissing, err.Error(), nil)
+		return
+	}
+	if to := query.Get("to"); to != "" {
+		if opts.To, err = parseVersionID("to", to); err != nil {
+			h.writeResponse(w, api.CodeParamMissing, err.Error(), nil)
+			return
+		}
+	}
+	if value := query.Get("context"); value != "" {
+		if opts.Context, err = strconv.Atoi(value); err != nil || opts.Context < 0 {
+			h.writeResponse(w, api.CodeParamMissing, fmt.Sprintf("invalid context parameter: %q", value), nil)
+			return
+		}
+	}
+
+	ctx, cancel := withTimeout(r, h.config.Timeout.Info)
+	defer cancel()
+
+	diff, err := h.fileService.DiffVersions(ctx, path, opts)
+	if err != nil {
+		logger.Error("Diff versions error: %v", err)
+		h.writeResponse(w, api.CodeOperationFail, err.Error(), nil)
+		return
+	}
+
+	h.writeResponse(w, api.CodeSuccess, "success", diff)
+}
+
+// RestoreVersion 将文件恢复为指定历史版本的内容
+func (h *Handler) RestoreVersion(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.writeResponse(w, api.CodeMethodNotAllow, "Method not allowed", nil)
+		return
+	}
+
+	query := r.URL.Query()
+	path := query.Get("path")
+	if path == "" || query.Get("id") == "" {
+		h.writeResponse(w, api.CodeParamMissing, "Missing path or id parameter", nil)
+		return
+	}
+	id, err := parseVersionID("id", query.Get("id"))
+	if err != nil {
+		h.writeResponse(w, api.CodeParamMissing, err.Error(), nil)
+		return
+	}
+
+	ctx, cancel := withTimeout(r, h.config.Timeout.Write)
+	defer cancel()
+
+	info, err := h.fileService.RestoreVersion(ctx, path, id)
+	if err != nil {
+		logger.Error("Restore version error: %v", err)
+		h.writeResponse(w, api.CodeOperationFail, err.Error(), nil)
+		return
+	}
+
+	h.writeResponse(w, api.CodeSuccess, "Version restored successfully", info)
+}
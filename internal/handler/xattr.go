@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"jia-file/api"
+	"jia-file/internal/file"
+	"jia-file/internal/logger"
+)
+
+// ListXattrs 列出扩展属性及其值
+func (h *Handler) ListXattrs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.writeResponse(w, api.CodeMethodNotAllow, "Method not allowed", nil)
+		return
+	}
+
+	path := r.URL.Query().Get("path")
+	if path == "" {
+		h.writeResponse(w, api.CodeParamMissing, "Missing path parameter", nil)
+		return
+	}
+
+	ctx, cancel := withTimeout(r, h.config.Timeout.Info)
+	defer cancel()
+
+	xattrs, err := h.fileService.ListXattrs(ctx, path)
+	if err != nil {
+		logger.Error("ListXattrs error: %v", err)
+		h.writeResponse(w, api.CodeOperationFail, err.Error(), nil)
+		return
+	}
+
+	h.writeResponse(w, api.CodeSuccess, "success", map[string]interface{}{"items": xattrs})
+}
+
+// Xattr 读取（GET）、设置（PUT/POST）或删除（DELETE）单个扩展属性
+func (h *Handler) Xattr(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	path := query.Get("path")
+	name := query.Get("name")
+	if path == "" || name == "" {
+		h.writeResponse(w, api.CodeParamMissing, "Missing path or name parameter", nil)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		ctx, cancel := withTimeout(r, h.config.Timeout.Info)
+		defer cancel()
+
+		xattr, err := h.fileService.GetXattr(ctx, path, name)
+		if err != nil {
+			logger.Error("GetXattr error: %v", err)
+			h.writeResponse(w, api.CodeOperationFail, err.Error(), nil)
+			return
+		}
+		h.writeResponse(w, api.CodeSuccess, "success", xattr)
+
+	case http.MethodPut, http.MethodPost:
+		value, err := readXattrValue(r)
+		if err != nil {
+			h.writeResponse(w, api.CodeParamMissing, err.Error(), nil)
+			return
+		}
+
+		ctx, cancel := withTimeout(r, h.config.Timeout.Write)
+		defer cancel()
+
+		if err := h.fileService.SetXattr(ctx, path, name, value); err != nil {
+			logger.Error("SetXattr error: %v", err)
+			h.writeResponse(w, api.CodeOperationFail, err.Error(), nil)
+			return
+		}
+		h.writeResponse(w, api.CodeSuccess, "Xattr set successfully", nil)
+
+	case http.MethodDelete:
+		ctx, cancel := withTimeout(r, h.config.Timeout.Write)
+		defer cancel()
+
+		if err := h.fileService.RemoveXattr(ctx, path, name); err != nil {
+			logger.Error("RemoveXattr error: %v", err)
+			h.writeResponse(w, api.CodeOperationFail, err.Error(), nil)
+			return
+		}
+		h.writeResponse(w, api.CodeSuccess, "Xattr removed successfully", nil)
+
+	default:
+		h.writeResponse(w, api.CodeMethodNotAllow, "Method not allowed", nil)
+	}
+}
+
+// readXattrValue 读取要设置的属性值：优先使用 value 参数，否则使用请求体
+// encoding=base64 时对值进行 base64 解码
+func readXattrValue(r *http.Request) ([]byte, error) {
+	query := r.URL.Query()
+	value := query.Get("value")
+	if !query.Has("value") {
+		body, err := io.ReadAll(io.LimitReader(r.Body, file.MaxXattrSize*2))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %v", err)
+		}
+		value = string(body)
+	}
+
+	decoded, err := file.DecodeXattrValue(value, query.Get("encoding"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid value: %v", err)
+	}
+	return decoded, nil
+}
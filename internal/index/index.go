@@ -0,0 +1,609 @@
+// Package index 提供文件内容的全文索引
+// 索引在内存中维护倒排表，定期持久化到磁盘；通过 file.Service 的变更事件增量更新，并定期全量校对
+package index
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"io/fs"
+	"jia-file/internal/file"
+	"jia-file/internal/logger"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// indexFileName 索引持久化文件名
+	indexFileName = "fulltext.gob"
+	// queueSize 等待处理的变更路径队列长度，队列满时变更由下一次全量校对处理
+	queueSize = 10000
+	// saveInterval 索引有变化时持久化的间隔
+	saveInterval = time.Minute
+	// sniffSize 判断是否为文本文件时读取的字节数
+	sniffSize = 512
+)
+
+// Options 索引选项
+type Options struct {
+	Root           string        // 建立索引的根目录
+	Dir            string        // 索引存储目录
+	Extensions     []string      // 建立索引的文件扩展名，如 ".md"
+	MaxFileSize    int64         // 大于此大小的文件不建立索引
+	RescanInterval time.Duration // 全量校对的间隔，0 表示只在启动时校对
+	Exclude        []string      // 不建立索引的目录，如断点续传的暂存目录
+}
+
+// document 已建立索引的文件
+type document struct {
+	Path    string
+	Size    int64
+	ModTime int64
+	Length  int      // 词项总数
+	Terms   []string // 不重复的词项，删除文档时用于定位倒排表
+}
+
+// snapshot 持久化格式
+type snapshot struct {
+	NextID   uint32
+	Docs     map[uint32]*document
+	Postings map[string]map[uint32][]uint32
+}
+
+// Stats 索引统计
+type Stats struct {
+	Documents int        `json:"documents"`          // 已建立索引的文件数
+	Terms     int        `json:"terms"`              // 不重复的词项数
+	Scanning  bool       `json:"scanning"`           // 是否正在全量校对
+	LastScan  *time.Time `json:"lastScan,omitempty"` // 上次全量校对完成的时间
+}
+
+// Index 全文索引
+type Index struct {
+	opts       Options
+	extensions map[string]bool
+
+	mu       sync.RWMutex
+	nextID   uint32
+	docs     map[uint32]*document
+	byPath   map[string]uint32
+	postings map[string]map[uint32][]uint32 // 词项 -> 文档 -> 词项在文档中的位置（递增）
+	totalLen int64
+	version  uint64 // 每次修改递增，与 saved 不同时需要持久化
+	saved    uint64
+	scanning bool
+	lastScan *time.Time
+
+	// sortedTerms 排序后的词项，用于前缀查询，为 nil 时在下次查询时重建
+	termsMu     sync.Mutex
+	sortedTerms []string
+
+	queue chan string
+	stop  chan struct{}
+	wg    sync.WaitGroup
+}
+
+// New 创建全文索引并加载已持久化的数据
+func New(opts Options) (*Index, error) {
+	root, err := filepath.Abs(opts.Root)
+	if err != nil {
+		return nil, fmt.Errorf("invalid index root: %v", err)
+	}
+	opts.Root = root
+	if opts.Dir, err = filepath.Abs(opts.Dir); err != nil {
+		return nil, fmt.Errorf("invalid index directory: %v", err)
+	}
+	if err := os.MkdirAll(opts.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create index directory: %v", err)
+	}
+	// 索引存储目录位于根目录下时同样不建立索引
+	exclude := []string{opts.Dir}
+	for _, dir := range opts.Exclude {
+		if dir, err = filepath.Abs(dir); err != nil {
+			return nil, fmt.Errorf("invalid excluded directory: %v", err)
+		}
+		exclude = append(exclude, dir)
+	}
+	opts.Exclude = exclude
+
+	x := &Index{
+		opts:       opts,
+		extensions: make(map[string]bool),
+		docs:       make(map[uint32]*document),
+		byPath:     make(map[string]uint32),
+		postings:   make(map[string]map[uint32][]uint32),
+		queue:      make(chan string, queueSize),
+		stop:       make(chan struct{}),
+	}
+	for _, ext := range opts.Extensions {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		x.extensions[ext] = true
+	}
+
+	if err := x.load(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// Start 启动后台协程：处理变更队列、定期全量校对和持久化
+// 启动后立即进行一次全量校对，使索引与服务停止期间发生的变化保持一致
+func (x *Index) Start() {
+	x.wg.Add(2)
+	go x.processQueue()
+	go x.maintain()
+}
+
+// Stop 停止后台协程并持久化索引
+func (x *Index) Stop() {
+	close(x.stop)
+	x.wg.Wait()
+	x.save()
+}
+
+// OnChange 处理 file.Service 的变更事件，可直接传给 Service.Subscribe
+// 移动操作直接修改已有文档的路径，其余变更放入队列异步处理
+func (x *Index) OnChange(event file.ChangeEvent) {
+	if event.Op == file.ChangeMove {
+		x.rename(event.OldPath, event.Path)
+	}
+
+	select {
+	case x.queue <- event.Path:
+	default:
+		logger.Error("Full-text index queue is full, %s will be indexed by the next rescan", event.Path)
+	}
+}
+
+// Stats 返回索引统计
+func (x *Index) Stats() Stats {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return Stats{
+		Documents: len(x.docs),
+		Terms:     len(x.postings),
+		Scanning:  x.scanning,
+		LastScan:  x.lastScan,
+	}
+}
+
+// processQueue 逐个处理变更路径
+func (x *Index) processQueue() {
+	defer x.wg.Done()
+	for {
+		select {
+		case <-x.stop:
+			return
+		case path := <-x.queue:
+			x.reconcile(path)
+		}
+	}
+}
+
+// maintain 定期全量校对并持久化
+func (x *Index) maintain() {
+	defer x.wg.Done()
+
+	x.rescan()
+
+	var rescanTick <-chan time.Time
+	if x.opts.RescanInterval > 0 {
+		ticker := time.NewTicker(x.opts.RescanInterval)
+		defer ticker.Stop()
+		rescanTick = ticker.C
+	}
+	saveTicker := time.NewTicker(saveInterval)
+	defer saveTicker.Stop()
+
+	for {
+		select {
+		case <-x.stop:
+			return
+		case <-rescanTick:
+			x.rescan()
+		case <-saveTicker.C:
+			x.save()
+		}
+	}
+}
+
+// rescan 全量校对根目录
+func (x *Index) rescan() {
+	x.mu.Lock()
+	x.scanning = true
+	x.mu.Unlock()
+
+	start := time.Now()
+	x.reconcileDir(x.opts.Root)
+
+	now := time.Now()
+	x.mu.Lock()
+	x.scanning = false
+	x.lastScan = &now
+	docs := len(x.docs)
+	x.mu.Unlock()
+	x.save()
+
+	logger.Info("Full-text index rescan finished in %v, %d documents", now.Sub(start).Round(time.Millisecond), docs)
+}
+
+// stopped 判断是否已停止
+func (x *Index) stopped() bool {
+	select {
+	case <-x.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// internal 判断路径是否位于索引存储目录或 Exclude 中的目录内
+func (x *Index) internal(path string) bool {
+	for _, dir := range x.opts.Exclude {
+		if isUnder(dir, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// excluded 判断路径是否不应建立索引：位于根目录外、位于索引目录或排除的目录内，或任一路径段以 "." 开头
+func (x *Index) excluded(path string) bool {
+	if x.internal(path) {
+		return true
+	}
+	rel, err := filepath.Rel(x.opts.Root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return true
+	}
+	if rel == "." {
+		return false
+	}
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		if strings.HasPrefix(part, ".") {
+			return true
+		}
+	}
+	return false
+}
+
+// eligible 判断文件是否需要建立索引
+func (x *Index) eligible(path string, info os.FileInfo) bool {
+	if !info.Mode().IsRegular() || x.excluded(path) {
+		return false
+	}
+	if x.opts.MaxFileSize > 0 && info.Size() > x.opts.MaxFileSize {
+		return false
+	}
+	return x.extensions[strings.ToLower(filepath.Ext(path))]
+}
+
+// reconcile 使路径（文件或目录子树）的索引与磁盘一致
+func (x *Index) reconcile(path string) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			x.removeUnder(path, nil)
+		}
+		return
+	}
+	if info.IsDir() {
+		x.reconcileDir(path)
+		return
+	}
+	x.indexFile(path, info)
+}
+
+// reconcileDir 遍历目录，更新有变化的文件，删除已不存在的文件
+func (x *Index) reconcileDir(dir string) {
+	if x.excluded(dir) {
+		x.removeUnder(dir, nil)
+		return
+	}
+
+	seen := make(map[string]bool)
+	complete := true
+	filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if x.stopped() {
+			complete = false
+			return filepath.SkipAll
+		}
+		if err != nil {
+			// 无法读取的目录保留原有索引
+			if entry != nil && entry.IsDir() {
+				x.keepUnder(path, seen)
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if (path != dir && strings.HasPrefix(entry.Name(), ".")) || x.internal(path) {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil
+		}
+		if x.indexFile(path, info) {
+			seen[path] = true
+		}
+		return nil
+	})
+
+	if complete {
+		x.removeUnder(dir, seen)
+	}
+}
+
+// keepUnder 将目录下已建立索引的文件标记为已见，避免因暂时无法读取而被删除
+func (x *Index) keepUnder(dir string, seen map[string]bool) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	for path := range x.byPath {
+		if isUnder(dir, path) {
+			seen[path] = true
+		}
+	}
+}
+
+// indexFile 为文件建立索引，返回文件是否在索引中
+// 大小和修改时间未变化的文件不会重新读取
+func (x *Index) indexFile(path string, info os.FileInfo) bool {
+	if !x.eligible(path, info) {
+		x.removeFile(path)
+		return false
+	}
+
+	x.mu.RLock()
+	id, ok := x.byPath[path]
+	unchanged := ok && x.docs[id].Size == info.Size() && x.docs[id].ModTime == info.ModTime().UnixNano()
+	x.mu.RUnlock()
+	if unchanged {
+		return true
+	}
+
+	content, err := readText(path, x.opts.MaxFileSize)
+	if err != nil {
+		x.removeFile(path)
+		return false
+	}
+
+	positions := make(map[string][]uint32)
+	tokens := terms(content)
+	for i, term := range tokens {
+		positions[term] = append(positions[term], uint32(i))
+	}
+
+	doc := &document{
+		Path:    path,
+		Size:    info.Size(),
+		ModTime: info.ModTime().UnixNano(),
+		Length:  len(tokens),
+		Terms:   make([]string, 0, len(positions)),
+	}
+	for term := range positions {
+		doc.Terms = append(doc.Terms, term)
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if id, ok := x.byPath[path]; ok {
+		x.removeDoc(id)
+	}
+	id = x.nextID
+	x.nextID++
+	x.docs[id] = doc
+	x.byPath[path] = id
+	x.totalLen += int64(doc.Length)
+	for term, list := range positions {
+		docs := x.postings[term]
+		if docs == nil {
+			docs = make(map[uint32][]uint32)
+			x.postings[term] = docs
+		}
+		docs[id] = list
+	}
+	x.version++
+	x.invalidateTerms()
+	return true
+}
+
+// readText 读取文本文件内容，二进制文件返回错误
+func readText(path string, maxSize int64) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	var reader io.Reader = f
+	if maxSize > 0 {
+		reader = io.LimitReader(f, maxSize)
+	}
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return "", err
+	}
+
+	head := content
+	if len(head) > sniffSize {
+		head = head[:sniffSize]
+	}
+	if !file.IsTextContent(head) {
+		return "", fmt.Errorf("not a text file: %s", path)
+	}
+	return string(content), nil
+}
+
+// removeDoc 删除文档及其倒排表，调用方需持有写锁
+func (x *Index) removeDoc(id uint32) {
+	doc, ok := x.docs[id]
+	if !ok {
+		return
+	}
+	for _, term := range doc.Terms {
+		if docs := x.postings[term]; docs != nil {
+			delete(docs, id)
+			if len(docs) == 0 {
+				delete(x.postings, term)
+			}
+		}
+	}
+	delete(x.docs, id)
+	delete(x.byPath, doc.Path)
+	x.totalLen -= int64(doc.Length)
+	x.version++
+	x.invalidateTerms()
+}
+
+// removeFile 删除单个文件的文档，文件不在索引中时不获取写锁
+// 全量校对时大部分文件不需要建立索引，不能对每个文件都遍历全部文档
+func (x *Index) removeFile(path string) {
+	x.mu.RLock()
+	_, ok := x.byPath[path]
+	x.mu.RUnlock()
+	if !ok {
+		return
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if id, ok := x.byPath[path]; ok {
+		x.removeDoc(id)
+	}
+}
+
+// removeUnder 删除 path 本身及其下所有不在 keep 中的文档，只用于目录或已不存在的路径
+func (x *Index) removeUnder(path string, keep map[string]bool) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	for docPath, id := range x.byPath {
+		if isUnder(path, docPath) && !keep[docPath] {
+			x.removeDoc(id)
+		}
+	}
+}
+
+// rename 将 oldPath 及其下文档的路径改为 newPath 下的对应路径，无需重新读取文件
+func (x *Index) rename(oldPath, newPath string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	for docPath, id := range x.byPath {
+		if !isUnder(oldPath, docPath) {
+			continue
+		}
+		moved := newPath + strings.TrimPrefix(docPath, oldPath)
+		if existing, ok := x.byPath[moved]; ok {
+			x.removeDoc(existing)
+		}
+		delete(x.byPath, docPath)
+		x.docs[id].Path = moved
+		x.byPath[moved] = id
+		x.version++
+	}
+}
+
+// invalidateTerms 使排序后的词项缓存失效，调用方需持有写锁
+func (x *Index) invalidateTerms() {
+	x.termsMu.Lock()
+	x.sortedTerms = nil
+	x.termsMu.Unlock()
+}
+
+// termList 返回排序后的词项，调用方需持有读锁
+func (x *Index) termList() []string {
+	x.termsMu.Lock()
+	defer x.termsMu.Unlock()
+	if x.sortedTerms == nil {
+		x.sortedTerms = make([]string, 0, len(x.postings))
+		for term := range x.postings {
+			x.sortedTerms = append(x.sortedTerms, term)
+		}
+		sort.Strings(x.sortedTerms)
+	}
+	return x.sortedTerms
+}
+
+// isUnder 判断 path 是否等于 parent 或位于 parent 之下
+func isUnder(parent, path string) bool {
+	return path == parent || strings.HasPrefix(path, strings.TrimSuffix(parent, string(filepath.Separator))+string(filepath.Separator))
+}
+
+// load 加载持久化的索引，文件损坏时从空索引开始重建
+func (x *Index) load() error {
+	content, err := os.ReadFile(filepath.Join(x.opts.Dir, indexFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read full-text index: %v", err)
+	}
+
+	var snap snapshot
+	if err := gob.NewDecoder(bytes.NewReader(content)).Decode(&snap); err != nil {
+		logger.Error("Full-text index is corrupted and will be rebuilt: %v", err)
+		return nil
+	}
+
+	x.nextID = snap.NextID
+	if snap.Docs != nil {
+		x.docs = snap.Docs
+	}
+	if snap.Postings != nil {
+		x.postings = snap.Postings
+	}
+	for id, doc := range x.docs {
+		x.byPath[doc.Path] = id
+		x.totalLen += int64(doc.Length)
+	}
+	return nil
+}
+
+// save 索引有变化时写入磁盘，先写临时文件再重命名，避免中途失败损坏已有索引
+func (x *Index) save() {
+	x.mu.RLock()
+	version := x.version
+	if version == x.saved {
+		x.mu.RUnlock()
+		return
+	}
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(snapshot{NextID: x.nextID, Docs: x.docs, Postings: x.postings})
+	x.mu.RUnlock()
+	if err != nil {
+		logger.Error("Encode full-text index error: %v", err)
+		return
+	}
+
+	path := filepath.Join(x.opts.Dir, indexFileName)
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, buf.Bytes(), 0644); err != nil {
+		logger.Error("Save full-text index error: %v", err)
+		return
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		logger.Error("Save full-text index error: %v", err)
+		return
+	}
+
+	x.mu.Lock()
+	x.saved = version
+	x.mu.Unlock()
+}
@@ -0,0 +1,256 @@
+package index
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// newTestIndex 创建不启动后台协程的索引，files 为相对根目录的路径到内容的映射
+func newTestIndex(t *testing.T, files map[string]string) *Index {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		writeTestFile(t, filepath.Join(root, name), content)
+	}
+	x, err := New(Options{Root: root, Dir: t.TempDir(), Extensions: []string{".md", "TXT"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	x.reconcileDir(x.opts.Root)
+	return x
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// search 返回命中文件相对根目录的路径，按得分降序
+func search(t *testing.T, x *Index, text string) []string {
+	t.Helper()
+	result, err := x.Search(context.Background(), Query{Text: text})
+	if err != nil {
+		t.Fatalf("Search(%q) error = %v", text, err)
+	}
+	paths := []string{}
+	for _, hit := range result.Hits {
+		rel, _ := filepath.Rel(x.opts.Root, hit.Path)
+		paths = append(paths, filepath.ToSlash(rel))
+	}
+	return paths
+}
+
+func TestSearchRanking(t *testing.T) {
+	x := newTestIndex(t, map[string]string{
+		"short.md":  "apple banana",
+		"repeat.md": "apple apple banana cherry",
+		"long.md":   "apple banana cherry date elderberry fig grape",
+		"other.md":  "cherry",
+	})
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		// 词频高的排在前面，词频相同时较短的文档排在前面
+		{"apple", []string{"repeat.md", "short.md", "long.md"}},
+		{"APPLE cherry", []string{"repeat.md", "long.md"}},
+		{"cherry", []string{"other.md", "repeat.md", "long.md"}},
+		{"missing", []string{}},
+		{"apple missing", []string{}},
+	}
+	for _, tt := range tests {
+		if got := search(t, x, tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Search(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+
+	// 只出现在少数文档中的词项权重更高
+	result, _ := x.Search(context.Background(), Query{Text: "date"})
+	common, _ := x.Search(context.Background(), Query{Text: "banana"})
+	var bananaScore float64
+	for _, hit := range common.Hits {
+		if strings.HasSuffix(hit.Path, "long.md") {
+			bananaScore = hit.Score
+		}
+	}
+	if len(result.Hits) != 1 || result.Hits[0].Score <= bananaScore {
+		t.Errorf("rare term score %v should be above common term score %v", result.Hits, bananaScore)
+	}
+}
+
+func TestSearchPhraseAndPrefix(t *testing.T) {
+	x := newTestIndex(t, map[string]string{
+		"a.md":     "the root path of the server",
+		"b.md":     "path root, server configuration",
+		"c.txt":    "application servers",
+		"d.md":     "全文索引支持中文",
+		"e.md":     "引索",
+		"skip.go":  "root path server",
+		".hide.md": "root path server",
+	})
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{`"root path"`, []string{"a.md"}},
+		{`"path root"`, []string{"b.md"}},
+		{`"root path" server`, []string{"a.md"}},
+		{"serv*", []string{"a.md", "b.md", "c.txt"}},
+		{"app*", []string{"c.txt"}},
+		{"root-pa*", []string{"a.md"}},
+		{`"serv*"`, []string{}},
+		{"索引", []string{"d.md"}},
+		{`"中文"`, []string{"d.md"}},
+	}
+	for _, tt := range tests {
+		got := search(t, x, tt.query)
+		if tt.query == "serv*" {
+			// 三个文档的得分可能相同，只比较集合
+			sort.Strings(got)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Search(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+
+	result, err := x.Search(context.Background(), Query{Text: `"root path"`})
+	if err != nil || len(result.Hits) != 1 {
+		t.Fatalf("Search() = %+v, %v", result, err)
+	}
+	if want := "the <mark>root</mark> <mark>path</mark> of the server"; result.Hits[0].Snippet != want {
+		t.Errorf("snippet = %q, want %q", result.Hits[0].Snippet, want)
+	}
+}
+
+func TestSearchPathAndPaging(t *testing.T) {
+	x := newTestIndex(t, map[string]string{
+		"a.md":     "word",
+		"sub/b.md": "word",
+		"sub/c.md": "word",
+		"subx.md":  "word",
+	})
+
+	result, err := x.Search(context.Background(), Query{Text: "word", Path: filepath.Join(x.opts.Root, "sub")})
+	if err != nil || result.Total != 2 {
+		t.Fatalf("Search() under sub = %+v, %v; want 2 results", result, err)
+	}
+
+	// 得分相同时按路径排序，分页结果稳定
+	result, err = x.Search(context.Background(), Query{Text: "word", Offset: 1, Limit: 2})
+	if err != nil || result.Total != 4 || len(result.Hits) != 2 {
+		t.Fatalf("Search() page = %+v, %v", result, err)
+	}
+	if !strings.HasSuffix(result.Hits[0].Path, "sub/b.md") || !strings.HasSuffix(result.Hits[1].Path, "sub/c.md") {
+		t.Errorf("page hits = %+v", result.Hits)
+	}
+	result, _ = x.Search(context.Background(), Query{Text: "word", Offset: 10})
+	if result.Total != 4 || len(result.Hits) != 0 {
+		t.Errorf("Search() past end = %+v", result)
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	x := newTestIndex(t, map[string]string{
+		"a.md":     "alpha",
+		"b.md":     "beta",
+		"dir/c.md": "gamma",
+		"bin.md":   "binary\x00data",
+	})
+	if got := x.Stats().Documents; got != 3 {
+		t.Fatalf("documents = %d, want 3", got)
+	}
+
+	// 修改内容后重新建立索引
+	writeTestFile(t, filepath.Join(x.opts.Root, "a.md"), "alpha changed")
+	x.reconcile(filepath.Join(x.opts.Root, "a.md"))
+	if got := search(t, x, "changed"); !reflect.DeepEqual(got, []string{"a.md"}) {
+		t.Errorf("Search(changed) = %q", got)
+	}
+
+	// 超过大小限制的文件从索引中删除
+	x.opts.MaxFileSize = 8
+	writeTestFile(t, filepath.Join(x.opts.Root, "b.md"), "beta is now too large")
+	x.reconcile(filepath.Join(x.opts.Root, "b.md"))
+	if got := search(t, x, "beta"); len(got) != 0 {
+		t.Errorf("Search(beta) = %q after file exceeded size limit", got)
+	}
+	x.opts.MaxFileSize = 0
+
+	// 删除目录时删除其下所有文档
+	if err := os.RemoveAll(filepath.Join(x.opts.Root, "dir")); err != nil {
+		t.Fatal(err)
+	}
+	x.reconcile(filepath.Join(x.opts.Root, "dir"))
+	if got := search(t, x, "gamma"); len(got) != 0 {
+		t.Errorf("Search(gamma) = %q after directory removed", got)
+	}
+
+	// 移动后无需重新读取文件
+	x.rename(filepath.Join(x.opts.Root, "a.md"), filepath.Join(x.opts.Root, "moved.md"))
+	if got := search(t, x, "alpha"); !reflect.DeepEqual(got, []string{"moved.md"}) {
+		t.Errorf("Search(alpha) = %q after rename", got)
+	}
+
+	stats := x.Stats()
+	if stats.Documents != 1 || stats.Terms != 2 {
+		t.Errorf("Stats() = %+v, want 1 document and 2 terms", stats)
+	}
+	if len(x.byPath) != len(x.docs) || x.totalLen != 2 {
+		t.Errorf("byPath = %v, totalLen = %d", x.byPath, x.totalLen)
+	}
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	x := newTestIndex(t, map[string]string{
+		"a.md":     "the root path of the server",
+		"sub/b.md": "全文索引 root",
+	})
+	x.Stop()
+
+	y, err := New(x.opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(y.docs, x.docs) || !reflect.DeepEqual(y.postings, x.postings) {
+		t.Fatalf("loaded index differs from saved index")
+	}
+	if y.nextID != x.nextID || y.totalLen != x.totalLen || !reflect.DeepEqual(y.byPath, x.byPath) {
+		t.Errorf("loaded nextID = %d, totalLen = %d, byPath = %v; want %d, %d, %v",
+			y.nextID, y.totalLen, y.byPath, x.nextID, x.totalLen, x.byPath)
+	}
+	for _, query := range []string{`"root path"`, "索引", "ro*"} {
+		if got, want := search(t, y, query), search(t, x, query); !reflect.DeepEqual(got, want) {
+			t.Errorf("Search(%q) after reload = %q, want %q", query, got, want)
+		}
+	}
+
+	// 未变化的文件在重新校对时不会重新建立索引
+	y.reconcileDir(y.opts.Root)
+	if y.version != 0 {
+		t.Errorf("version = %d after reconciling unchanged files, want 0", y.version)
+	}
+
+	// 损坏的索引文件被忽略，从空索引开始重建
+	if err := os.WriteFile(filepath.Join(x.opts.Dir, indexFileName), []byte("corrupted"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	z, err := New(x.opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := z.Stats().Documents; got != 0 {
+		t.Errorf("documents = %d after loading corrupted index, want 0", got)
+	}
+}
@@ -0,0 +1,344 @@
+package index
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// maxPrefixExpansions 前缀词项最多展开的词项数
+	maxPrefixExpansions = 200
+	// snippetBefore 摘要中第一个命中词项之前保留的词项数
+	snippetBefore = 8
+	// snippetAfter 摘要中第一个命中词项之后保留的词项数
+	snippetAfter = 24
+
+	// BM25 参数
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// Query 全文搜索请求
+type Query struct {
+	Text   string // 查询语句，如 `config "root path" serv*`
+	Path   string // 只返回位于此目录下的文件，为空表示不限制
+	Limit  int    // 返回的结果数
+	Offset int    // 跳过的结果数
+}
+
+// Hit 搜索结果
+type Hit struct {
+	Path    string    `json:"path"`    // 文件路径
+	Score   float64   `json:"score"`   // 相关度得分，越大越相关
+	Size    int64     `json:"size"`    // 文件大小
+	ModTime time.Time `json:"modTime"` // 修改时间
+	Snippet string    `json:"snippet"` // 命中内容摘要，已做 HTML 转义，命中的词项用 <mark></mark> 包裹
+}
+
+// Result 搜索结果列表
+type Result struct {
+	Hits  []Hit `json:"items"` // 当前页的结果
+	Total int   `json:"total"` // 满足条件的结果总数
+}
+
+// clause 查询子句，多个子句之间为 AND 关系
+// 只有一个词项时为普通词项，多个词项时为短语，要求词项位置连续
+type clause struct {
+	terms  []string
+	prefix bool // 最后一个词项是否为前缀
+}
+
+// parseQuery 解析查询语句
+// 支持三种子句：普通词项 foo、前缀词项 foo*、短语 "foo bar"；被分词为多个词项的单词（如中文）按短语处理
+func parseQuery(text string) ([]clause, error) {
+	var clauses []clause
+	add := func(part string, quoted bool) {
+		prefix := !quoted && strings.HasSuffix(part, "*")
+		if prefix {
+			part = strings.TrimRight(part, "*")
+		}
+		if ts := terms(part); len(ts) > 0 {
+			clauses = append(clauses, clause{terms: ts, prefix: prefix})
+		}
+	}
+
+	for text = strings.TrimSpace(text); text != ""; text = strings.TrimSpace(text) {
+		if text[0] == '"' {
+			end := strings.IndexByte(text[1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated phrase in query")
+			}
+			add(text[1:end+1], true)
+			text = text[end+2:]
+			continue
+		}
+		end := strings.IndexAny(text, " \t\n\"")
+		if end < 0 {
+			end = len(text)
+		}
+		add(text[:end], false)
+		text = text[end:]
+	}
+
+	if len(clauses) == 0 {
+		return nil, fmt.Errorf("query contains no searchable terms")
+	}
+	return clauses, nil
+}
+
+// expand 返回以 prefix 开头的词项，调用方需持有读锁
+func (x *Index) expand(prefix string) []string {
+	list := x.termList()
+	start := sort.SearchStrings(list, prefix)
+	var expanded []string
+	for i := start; i < len(list) && strings.HasPrefix(list[i], prefix); i++ {
+		expanded = append(expanded, list[i])
+		if len(expanded) >= maxPrefixExpansions {
+			break
+		}
+	}
+	return expanded
+}
+
+// slotPostings 返回子句中第 i 个词项的倒排表，前缀词项合并所有展开词项的位置，调用方需持有读锁
+func (x *Index) slotPostings(c clause, i int) map[uint32][]uint32 {
+	if !c.prefix || i != len(c.terms)-1 {
+		return x.postings[c.terms[i]]
+	}
+
+	merged := make(map[uint32][]uint32)
+	for _, term := range x.expand(c.terms[i]) {
+		for id, positions := range x.postings[term] {
+			merged[id] = append(merged[id], positions...)
+		}
+	}
+	for id, positions := range merged {
+		sort.Slice(positions, func(a, b int) bool { return positions[a] < positions[b] })
+		merged[id] = positions
+	}
+	return merged
+}
+
+// evalClause 返回满足子句的文档及子句在文档中出现的次数，调用方需持有读锁
+func (x *Index) evalClause(c clause) map[uint32]int {
+	slots := make([]map[uint32][]uint32, len(c.terms))
+	for i := range c.terms {
+		slots[i] = x.slotPostings(c, i)
+		if len(slots[i]) == 0 {
+			return nil
+		}
+	}
+
+	freqs := make(map[uint32]int)
+	for id, first := range slots[0] {
+		count := 0
+		for _, pos := range first {
+			matched := true
+			for i := 1; i < len(slots) && matched; i++ {
+				matched = containsPosition(slots[i][id], pos+uint32(i))
+			}
+			if matched {
+				count++
+			}
+		}
+		if count > 0 {
+			freqs[id] = count
+		}
+	}
+	return freqs
+}
+
+// containsPosition 在递增的位置列表中查找
+func containsPosition(positions []uint32, pos uint32) bool {
+	i := sort.Search(len(positions), func(i int) bool { return positions[i] >= pos })
+	return i < len(positions) && positions[i] == pos
+}
+
+// Search 执行全文搜索，按 BM25 相关度降序返回
+func (x *Index) Search(ctx context.Context, q Query) (Result, error) {
+	clauses, err := parseQuery(q.Text)
+	if err != nil {
+		return Result{}, err
+	}
+
+	type scored struct {
+		id    uint32
+		doc   document
+		score float64
+	}
+
+	x.mu.RLock()
+	var matched map[uint32]float64
+	n := float64(len(x.docs))
+	avgLen := 1.0
+	if len(x.docs) > 0 && x.totalLen > 0 {
+		avgLen = float64(x.totalLen) / n
+	}
+	for _, c := range clauses {
+		if err := ctx.Err(); err != nil {
+			x.mu.RUnlock()
+			return Result{}, err
+		}
+
+		freqs := x.evalClause(c)
+		idf := math.Log(1 + (n-float64(len(freqs))+0.5)/(float64(len(freqs))+0.5))
+		next := make(map[uint32]float64)
+		for id, tf := range freqs {
+			if matched != nil {
+				if _, ok := matched[id]; !ok {
+					continue
+				}
+			}
+			length := float64(x.docs[id].Length)
+			f := float64(tf)
+			next[id] = matched[id] + idf*f*(bm25K1+1)/(f+bm25K1*(1-bm25B+bm25B*length/avgLen))
+		}
+		matched = next
+		if len(matched) == 0 {
+			break
+		}
+	}
+
+	results := make([]scored, 0, len(matched))
+	for id, score := range matched {
+		doc := x.docs[id]
+		if q.Path != "" && !isUnder(q.Path, doc.Path) {
+			continue
+		}
+		results = append(results, scored{id: id, doc: *doc, score: score})
+	}
+	x.mu.RUnlock()
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].score != results[j].score {
+			return results[i].score > results[j].score
+		}
+		return results[i].doc.Path < results[j].doc.Path
+	})
+
+	result := Result{Hits: []Hit{}, Total: len(results)}
+	if q.Offset >= len(results) {
+		return result, nil
+	}
+	results = results[q.Offset:]
+	if q.Limit > 0 && len(results) > q.Limit {
+		results = results[:q.Limit]
+	}
+
+	for _, r := range results {
+		if err := ctx.Err(); err != nil {
+			return Result{}, err
+		}
+		result.Hits = append(result.Hits, Hit{
+			Path:    r.doc.Path,
+			Score:   math.Round(r.score*1000) / 1000,
+			Size:    r.doc.Size,
+			ModTime: time.Unix(0, r.doc.ModTime),
+			Snippet: x.snippet(r.doc.Path, clauses),
+		})
+	}
+	return result, nil
+}
+
+// snippet 重新读取文件，截取第一处命中附近的内容并高亮与查询子句匹配的词项
+// 文件在建立索引后被修改或删除时返回空字符串
+func (x *Index) snippet(path string, clauses []clause) string {
+	content, err := readText(path, x.opts.MaxFileSize)
+	if err != nil {
+		return ""
+	}
+
+	// 标记所有与子句完整匹配的词项
+	tokens := tokenize(content)
+	marked := make([]bool, len(tokens))
+	first := -1
+	for i := range tokens {
+		for _, c := range clauses {
+			if !matchAt(tokens, i, c) {
+				continue
+			}
+			for j := range c.terms {
+				marked[i+j] = true
+			}
+			if first < 0 {
+				first = i
+			}
+		}
+	}
+	if first < 0 {
+		return ""
+	}
+
+	lo := first - snippetBefore
+	if lo < 0 {
+		lo = 0
+	}
+	hi := first + snippetAfter
+	if hi >= len(tokens) {
+		hi = len(tokens) - 1
+	}
+
+	var sb strings.Builder
+	if lo > 0 {
+		sb.WriteString("…")
+	}
+	offset := tokens[lo].start
+	for i := lo; i <= hi; i++ {
+		t := tokens[i]
+		sb.WriteString(html.EscapeString(collapseSpace(content[offset:t.start])))
+		if marked[i] {
+			sb.WriteString("<mark>")
+			sb.WriteString(html.EscapeString(content[t.start:t.end]))
+			sb.WriteString("</mark>")
+		} else {
+			sb.WriteString(html.EscapeString(content[t.start:t.end]))
+		}
+		offset = t.end
+	}
+	if hi < len(tokens)-1 {
+		sb.WriteString("…")
+	}
+	return sb.String()
+}
+
+// matchAt 判断从第 i 个词项开始的词项序列是否与子句匹配
+func matchAt(tokens []token, i int, c clause) bool {
+	if i+len(c.terms) > len(tokens) {
+		return false
+	}
+	for j, term := range c.terms {
+		if c.prefix && j == len(c.terms)-1 {
+			if !strings.HasPrefix(tokens[i+j].term, term) {
+				return false
+			}
+		} else if tokens[i+j].term != term {
+			return false
+		}
+	}
+	return true
+}
+
+// collapseSpace 将连续的空白（包括换行）合并为一个空格，使摘要保持单行
+func collapseSpace(s string) string {
+	const space = " \t\r\n"
+	if !strings.ContainsAny(s, space) {
+		return s
+	}
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return " "
+	}
+	result := strings.Join(fields, " ")
+	if strings.TrimLeft(s, space) != s {
+		result = " " + result
+	}
+	if strings.TrimRight(s, space) != s {
+		result += " "
+	}
+	return result
+}
@@ -0,0 +1,73 @@
+package index
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxTermLength 词项的最大字节数，更长的词项（如 base64、哈希）不建立索引
+const maxTermLength = 64
+
+// token 分词结果，start 和 end 为词项在原文中的字节偏移
+type token struct {
+	term  string
+	start int
+	end   int
+}
+
+// isCJK 判断字符是否为中日韩文字，这类文字没有空格分隔，每个字符作为一个词项
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) ||
+		unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) ||
+		unicode.Is(unicode.Hangul, r)
+}
+
+// isWordRune 判断字符是否属于单词
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+// tokenize 将文本切分为小写的词项
+// 字母数字连续的部分作为一个词项，中日韩文字按单字切分，短语查询依靠词项位置连续来匹配
+func tokenize(text string) []token {
+	var tokens []token
+	start := -1
+	flush := func(end int) {
+		if start >= 0 {
+			if end-start <= maxTermLength {
+				tokens = append(tokens, token{term: strings.ToLower(text[start:end]), start: start, end: end})
+			}
+			start = -1
+		}
+	}
+
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		switch {
+		case isCJK(r):
+			flush(i)
+			tokens = append(tokens, token{term: text[i : i+size], start: i, end: i + size})
+		case isWordRune(r):
+			if start < 0 {
+				start = i
+			}
+		default:
+			flush(i)
+		}
+		i += size
+	}
+	flush(len(text))
+	return tokens
+}
+
+// terms 返回文本的词项序列
+func terms(text string) []string {
+	tokens := tokenize(text)
+	result := make([]string, len(tokens))
+	for i, t := range tokens {
+		result[i] = t.term
+	}
+	return result
+}
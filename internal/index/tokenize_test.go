@@ -0,0 +1,66 @@
+package index
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"", []string{}},
+		{"Hello, World!", []string{"hello", "world"}},
+		{"snake_case v2.0", []string{"snake_case", "v2", "0"}},
+		{"全文索引", []string{"全", "文", "索", "引"}},
+		{"Go语言abc", []string{"go", "语", "言", "abc"}},
+		{"カタカナ한글", []string{"カ", "タ", "カ", "ナ", "한", "글"}},
+		{"Ünïcode ÄÖÜ", []string{"ünïcode", "äöü"}},
+		{strings.Repeat("x", maxTermLength) + " " + strings.Repeat("y", maxTermLength+1) + " z", []string{strings.Repeat("x", maxTermLength), "z"}},
+	}
+	for _, tt := range tests {
+		if got := terms(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("terms(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestTokenizeOffsets(t *testing.T) {
+	text := "Foo, 中文 bar"
+	for _, tok := range tokenize(text) {
+		if got := strings.ToLower(text[tok.start:tok.end]); got != tok.term {
+			t.Errorf("token %q has offsets [%d, %d) covering %q", tok.term, tok.start, tok.end, got)
+		}
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    []clause
+		wantErr bool
+	}{
+		{text: "foo", want: []clause{{terms: []string{"foo"}}}},
+		{text: "  Foo   BAR ", want: []clause{{terms: []string{"foo"}}, {terms: []string{"bar"}}}},
+		{text: "serv*", want: []clause{{terms: []string{"serv"}, prefix: true}}},
+		{text: `"root path" x`, want: []clause{{terms: []string{"root", "path"}}, {terms: []string{"x"}}}},
+		{text: `"ser*"`, want: []clause{{terms: []string{"ser"}}}},
+		{text: "索引", want: []clause{{terms: []string{"索", "引"}}}},
+		{text: "foo-bar*", want: []clause{{terms: []string{"foo", "bar"}, prefix: true}}},
+		{text: `"unterminated`, wantErr: true},
+		{text: "*** ,,", wantErr: true},
+		{text: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseQuery(tt.text)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseQuery(%q) error = %v, want error %v", tt.text, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseQuery(%q) = %+v, want %+v", tt.text, got, tt.want)
+		}
+	}
+}
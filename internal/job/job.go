@@ -0,0 +1,394 @@
+// Package job 提供后台任务管理，用于异步执行耗时的文件操作
+package job
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"jia-file/internal/file"
+	"jia-file/internal/logger"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Status 任务状态
+type Status string
+
+const (
+	StatusPending   Status = "pending"   // 等待执行
+	StatusRunning   Status = "running"   // 正在执行
+	StatusSucceeded Status = "succeeded" // 执行成功
+	StatusFailed    Status = "failed"    // 执行失败
+	StatusCancelled Status = "cancelled" // 已取消
+)
+
+// Finished 判断任务是否已结束
+func (s Status) Finished() bool {
+	return s == StatusSucceeded || s == StatusFailed || s == StatusCancelled
+}
+
+// ErrNotFound 任务不存在
+var ErrNotFound = errors.New("job not found")
+
+// ErrQueueFull 任务队列已满
+var ErrQueueFull = errors.New("job queue is full")
+
+// Func 任务执行函数，ctx 在任务被取消时结束，通过 progress 报告进度，返回值作为任务结果
+type Func func(ctx context.Context, progress *file.Progress) (interface{}, error)
+
+// Job 任务信息
+type Job struct {
+	ID         string                `json:"id"`                   // 任务ID
+	Type       string                `json:"type"`                 // 任务类型，如 copy、move、delete
+	Params     map[string]string     `json:"params"`               // 任务参数
+	Status     Status                `json:"status"`               // 任务状态
+	Progress   file.ProgressSnapshot `json:"progress"`             // 任务进度
+	Result     interface{}           `json:"result,omitempty"`     // 任务结果
+	Error      string                `json:"error,omitempty"`      // 失败原因
+	CreatedAt  time.Time             `json:"createdAt"`            // 创建时间
+	StartedAt  *time.Time            `json:"startedAt,omitempty"`  // 开始时间
+	FinishedAt *time.Time            `json:"finishedAt,omitempty"` // 结束时间
+}
+
+// entry 任务的运行时状态
+type entry struct {
+	job      Job
+	fn       Func
+	progress *file.Progress
+	ctx      context.Context
+	cancel   context.CancelFunc
+}
+
+// Manager 任务管理器
+// 任务由固定数量的工作协程执行，结束的任务会被持久化到历史文件中，服务重启后仍可查询
+type Manager struct {
+	workers     int
+	historyPath string
+	maxHistory  int
+
+	mu      sync.Mutex
+	saveMu  sync.Mutex
+	entries map[string]*entry
+	queue   chan *entry
+	stop    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewManager 创建任务管理器并加载历史任务
+// workers 为并发执行的任务数，queueSize 为等待队列长度，maxHistory 为保留的已结束任务数
+func NewManager(workers, queueSize, maxHistory int, historyPath string) (*Manager, error) {
+	if workers <= 0 {
+		workers = 1
+	}
+	if queueSize <= 0 {
+		queueSize = 1
+	}
+
+	m := &Manager{
+		workers:     workers,
+		historyPath: historyPath,
+		maxHistory:  maxHistory,
+		entries:     make(map[string]*entry),
+		queue:       make(chan *entry, queueSize),
+		stop:        make(chan struct{}),
+	}
+	if err := m.load(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Start 启动工作协程
+func (m *Manager) Start() {
+	for i := 0; i < m.workers; i++ {
+		m.wg.Add(1)
+		go m.worker()
+	}
+}
+
+// Stop 取消所有未结束的任务并等待工作协程退出
+func (m *Manager) Stop() {
+	m.mu.Lock()
+	for _, e := range m.entries {
+		if !e.job.Status.Finished() {
+			e.cancel()
+		}
+	}
+	m.mu.Unlock()
+
+	close(m.stop)
+	m.wg.Wait()
+}
+
+// Submit 提交任务，返回任务信息
+func (m *Manager) Submit(jobType string, params map[string]string, fn Func) (Job, error) {
+	id, err := newID()
+	if err != nil {
+		return Job{}, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	e := &entry{
+		ctx:    ctx,
+		cancel: cancel,
+		job: Job{
+			ID:        id,
+			Type:      jobType,
+			Params:    params,
+			Status:    StatusPending,
+			CreatedAt: time.Now(),
+		},
+		fn:       fn,
+		progress: &file.Progress{},
+	}
+
+	m.mu.Lock()
+	select {
+	case m.queue <- e:
+		m.entries[id] = e
+	default:
+		m.mu.Unlock()
+		cancel()
+		return Job{}, ErrQueueFull
+	}
+	job := e.job
+	m.mu.Unlock()
+	m.save()
+
+	logger.Info("Job %s (%s) submitted", id, jobType)
+	return job, nil
+}
+
+// Get 获取任务信息
+func (m *Manager) Get(id string) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.entries[id]
+	if !ok {
+		return Job{}, ErrNotFound
+	}
+	return e.snapshot(), nil
+}
+
+// List 按创建时间倒序列出任务，status 不为空时只返回该状态的任务
+func (m *Manager) List(status Status) []Job {
+	m.mu.Lock()
+	jobs := make([]Job, 0, len(m.entries))
+	for _, e := range m.entries {
+		if status == "" || e.job.Status == status {
+			jobs = append(jobs, e.snapshot())
+		}
+	}
+	m.mu.Unlock()
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.After(jobs[j].CreatedAt)
+	})
+	return jobs
+}
+
+// Cancel 取消任务
+// 等待中的任务直接标记为已取消；执行中的任务会在下一个检查点退出
+func (m *Manager) Cancel(id string) (Job, error) {
+	m.mu.Lock()
+	e, ok := m.entries[id]
+	if !ok {
+		m.mu.Unlock()
+		return Job{}, ErrNotFound
+	}
+	if e.job.Status.Finished() {
+		job := e.snapshot()
+		m.mu.Unlock()
+		return job, fmt.Errorf("job %s already %s", id, job.Status)
+	}
+
+	e.cancel()
+	if e.job.Status == StatusPending {
+		m.finish(e, nil, context.Canceled)
+	}
+	job := e.snapshot()
+	m.mu.Unlock()
+
+	m.save()
+	return job, nil
+}
+
+// worker 从队列中取出任务并执行
+func (m *Manager) worker() {
+	defer m.wg.Done()
+	for {
+		select {
+		case <-m.stop:
+			return
+		case e := <-m.queue:
+			m.run(e)
+		}
+	}
+}
+
+// run 执行单个任务
+func (m *Manager) run(e *entry) {
+	m.mu.Lock()
+	if e.job.Status != StatusPending {
+		// 等待期间已被取消
+		m.mu.Unlock()
+		return
+	}
+	now := time.Now()
+	e.job.Status = StatusRunning
+	e.job.StartedAt = &now
+	m.mu.Unlock()
+
+	result, err := m.call(e)
+
+	m.mu.Lock()
+	m.finish(e, result, err)
+	status := e.job.Status
+	m.mu.Unlock()
+	m.save()
+
+	logger.Info("Job %s (%s) %s", e.job.ID, e.job.Type, status)
+}
+
+// call 执行任务函数，捕获 panic 防止工作协程退出
+func (m *Manager) call(e *entry) (result interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("Job %s panic recovered: %v", e.job.ID, r)
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return e.fn(e.ctx, e.progress)
+}
+
+// finish 记录任务结束状态，调用方需持有锁
+func (m *Manager) finish(e *entry, result interface{}, err error) {
+	now := time.Now()
+	e.job.FinishedAt = &now
+	e.job.Progress = e.progress.Snapshot()
+	e.job.Result = result
+	e.cancel()
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		e.job.Status = StatusCancelled
+		e.job.Error = err.Error()
+	case err != nil:
+		e.job.Status = StatusFailed
+		e.job.Error = err.Error()
+	default:
+		e.job.Status = StatusSucceeded
+	}
+	m.prune()
+}
+
+// prune 超出历史数量限制时删除最早结束的任务，调用方需持有锁
+func (m *Manager) prune() {
+	if m.maxHistory <= 0 {
+		return
+	}
+
+	finished := make([]*entry, 0)
+	for _, e := range m.entries {
+		if e.job.Status.Finished() {
+			finished = append(finished, e)
+		}
+	}
+	if len(finished) <= m.maxHistory {
+		return
+	}
+
+	sort.Slice(finished, func(i, j int) bool {
+		return finished[i].job.FinishedAt.Before(*finished[j].job.FinishedAt)
+	})
+	for _, e := range finished[:len(finished)-m.maxHistory] {
+		delete(m.entries, e.job.ID)
+	}
+}
+
+// snapshot 返回任务信息副本，执行中的任务附带实时进度，调用方需持有锁
+func (e *entry) snapshot() Job {
+	job := e.job
+	if !job.Status.Finished() {
+		job.Progress = e.progress.Snapshot()
+	}
+	return job
+}
+
+// load 加载历史任务
+// 上次运行时未结束的任务已无法继续，标记为失败
+func (m *Manager) load() error {
+	if m.historyPath == "" {
+		return nil
+	}
+
+	content, err := os.ReadFile(m.historyPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read job history: %v", err)
+	}
+
+	var jobs []Job
+	if err := json.Unmarshal(content, &jobs); err != nil {
+		return fmt.Errorf("failed to parse job history: %v", err)
+	}
+
+	for _, job := range jobs {
+		if !job.Status.Finished() {
+			now := time.Now()
+			job.Status = StatusFailed
+			job.Error = "interrupted by server restart"
+			job.FinishedAt = &now
+		}
+		m.entries[job.ID] = &entry{job: job, progress: &file.Progress{}, cancel: func() {}}
+	}
+	return nil
+}
+
+// save 将所有任务写入历史文件
+func (m *Manager) save() {
+	if m.historyPath == "" {
+		return
+	}
+
+	m.saveMu.Lock()
+	defer m.saveMu.Unlock()
+
+	jobs := m.List("")
+	content, err := json.Marshal(jobs)
+	if err != nil {
+		logger.Error("Marshal job history error: %v", err)
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(m.historyPath), 0755); err != nil {
+		logger.Error("Save job history error: %v", err)
+		return
+	}
+	tmpPath := m.historyPath + ".tmp"
+	if err := os.WriteFile(tmpPath, content, 0644); err != nil {
+		logger.Error("Save job history error: %v", err)
+		return
+	}
+	if err := os.Rename(tmpPath, m.historyPath); err != nil {
+		logger.Error("Save job history error: %v", err)
+	}
+}
+
+// newID 生成随机任务ID
+func newID() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -46,10 +46,14 @@ func RecoveryMiddleware(next http.Handler) http.Handler {
 func CORSMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-
-		if r.Method == "OPTIONS" {
+		w.Header().Set("Access-Control-Allow-Methods", "GET, HEAD, POST, PUT, PATCH, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Range, If-None-Match, If-Modified-Since, If-Range, "+
+			"Tus-Resumable, Upload-Length, Upload-Offset, Upload-Metadata, Upload-Defer-Length")
+		w.Header().Set("Access-Control-Expose-Headers", "Content-Length, Content-Range, Content-Disposition, ETag, Last-Modified, Accept-Ranges, "+
+			"Location, Tus-Resumable, Tus-Version, Tus-Extension, Tus-Max-Size, Upload-Offset, Upload-Length, Upload-Expires")
+
+		// 只拦截跨域预检请求，普通 OPTIONS 请求（如 tus 能力发现）交给后续处理器
+		if r.Method == "OPTIONS" && r.Header.Get("Access-Control-Request-Method") != "" {
 			w.WriteHeader(http.StatusOK)
 			return
 		}
@@ -76,17 +80,18 @@ func MethodMiddleware(methods ...string) func(http.Handler) http.Handler {
 // PathValidationMiddleware 路径验证中间件
 func PathValidationMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// 检查路径参数
-		path := r.URL.Query().Get("path")
-		if path != "" && !isValidPath(path) {
-			w.Header().Set("Content-Type", "application/json")
-			response := api.Response{
-				Code:    api.CodeParamMissing,
-				Message: "Path must be an absolute path",
-				Data:    nil,
+		// 检查路径参数，/archive 等接口可以传入多个 path
+		for _, path := range r.URL.Query()["path"] {
+			if path != "" && !isValidPath(path) {
+				w.Header().Set("Content-Type", "application/json")
+				response := api.Response{
+					Code:    api.CodeParamMissing,
+					Message: "Path must be an absolute path",
+					Data:    nil,
+				}
+				json.NewEncoder(w).Encode(response)
+				return
 			}
-			json.NewEncoder(w).Encode(response)
-			return
 		}
 
 		// 检查源路径和目标路径
@@ -114,10 +119,17 @@ func isValidPath(path string) bool {
 		return false
 	}
 
-	// 检查是否包含 .. 或 .
-	if strings.Contains(path, "..") || strings.Contains(path, "./") {
-		return false
+	// 检查是否包含 .. 或 . 路径分量，..config 之类的文件名是合法的
+	for _, part := range strings.FieldsFunc(path, isSeparator) {
+		if part == "." || part == ".." {
+			return false
+		}
 	}
 
 	return true
-} 
\ No newline at end of file
+} 
+
+// isSeparator 判断字符是否为路径分隔符，Windows 上同时接受 / 和 \
+func isSeparator(c rune) bool {
+	return c == '/' || c == filepath.Separator
+}
\ No newline at end of file
@@ -0,0 +1,301 @@
+// Package tus 实现 tus 1.0 断点续传协议（core、creation、termination、expiration 扩展）
+package tus
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"jia-file/internal/file"
+	"jia-file/internal/logger"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Version 支持的 tus 协议版本
+	Version = "1.0.0"
+	// Extensions 支持的 tus 协议扩展
+	Extensions = "creation,termination,expiration"
+)
+
+// Handler tus 协议 HTTP 处理器
+type Handler struct {
+	basePath      string
+	store         *Store
+	fileService   file.Service
+	pathProcessor *file.PathProcessor
+	maxSize       int64
+}
+
+// NewHandler 创建 tus 处理器
+// basePath 为挂载路径（如 "/uploads/"），上传完成后通过 fileService 将文件移动到目标路径
+func NewHandler(basePath string, store *Store, fileService file.Service, pathProcessor *file.PathProcessor, maxSize int64) *Handler {
+	return &Handler{
+		basePath:      strings.TrimSuffix(basePath, "/") + "/",
+		store:         store,
+		fileService:   fileService,
+		pathProcessor: pathProcessor,
+		maxSize:       maxSize,
+	}
+}
+
+// ServeHTTP 实现 http.Handler 接口
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Tus-Resumable", Version)
+
+	if r.Method == http.MethodOptions {
+		h.options(w)
+		return
+	}
+
+	if r.Header.Get("Tus-Resumable") != Version {
+		w.Header().Set("Tus-Version", Version)
+		http.Error(w, "Unsupported tus version", http.StatusPreconditionFailed)
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, h.basePath)
+	if r.URL.Path+"/" == h.basePath {
+		id = ""
+	}
+
+	switch {
+	case id == "" && r.Method == http.MethodPost:
+		h.create(w, r)
+	case id != "" && r.Method == http.MethodHead:
+		h.head(w, id)
+	case id != "" && r.Method == http.MethodPatch:
+		h.patch(w, r, id)
+	case id != "" && r.Method == http.MethodDelete:
+		h.terminate(w, id)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// options 返回服务端支持的协议版本与扩展
+func (h *Handler) options(w http.ResponseWriter) {
+	w.Header().Set("Tus-Version", Version)
+	w.Header().Set("Tus-Extension", Extensions)
+	if h.maxSize > 0 {
+		w.Header().Set("Tus-Max-Size", strconv.FormatInt(h.maxSize, 10))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// create 创建新的上传
+// 目标路径通过 Upload-Metadata 中的 path 指定，或通过 path 查询参数指定目录并由 filename 元数据指定文件名；
+// 元数据 overwrite 为 true 时允许覆盖已存在的目标
+func (h *Handler) create(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Upload-Defer-Length") != "" {
+		http.Error(w, "Upload-Defer-Length is not supported", http.StatusBadRequest)
+		return
+	}
+
+	size, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || size < 0 {
+		http.Error(w, "Invalid Upload-Length header", http.StatusBadRequest)
+		return
+	}
+	if h.maxSize > 0 && size > h.maxSize {
+		http.Error(w, "Upload-Length exceeds Tus-Max-Size", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	metadata, err := parseMetadata(r.Header.Get("Upload-Metadata"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	target := metadata["path"]
+	if target == "" {
+		dir := r.URL.Query().Get("path")
+		name := filepath.Base(metadata["filename"])
+		if dir == "" || name == "." || name == ".." || name == string(filepath.Separator) {
+			http.Error(w, "Missing target path in Upload-Metadata", http.StatusBadRequest)
+			return
+		}
+		target = filepath.Join(dir, name)
+	}
+	if !filepath.IsAbs(target) {
+		http.Error(w, "Target path must be an absolute path", http.StatusBadRequest)
+		return
+	}
+	if _, err := h.pathProcessor.ProcessPath(target); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	overwrite := metadata["overwrite"] == "true"
+	if !overwrite {
+		if _, err := h.fileService.GetInfo(r.Context(), target); err == nil {
+			http.Error(w, "file already exists: "+target, http.StatusConflict)
+			return
+		}
+	}
+
+	upload, err := h.store.Create(size, target, overwrite, metadata)
+	if err != nil {
+		logger.Error("Create upload error: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// 空文件无需后续 PATCH，直接完成
+	if upload.Completed() {
+		if err := h.finish(r.Context(), upload); err != nil {
+			logger.Error("Finish upload %s error: %v", upload.ID, err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Location", h.basePath+upload.ID)
+	w.Header().Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	w.WriteHeader(http.StatusCreated)
+}
+
+// head 返回上传的当前偏移量
+func (h *Handler) head(w http.ResponseWriter, id string) {
+	upload, err := h.store.Get(id)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(upload.Size, 10))
+	w.Header().Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	w.WriteHeader(http.StatusOK)
+}
+
+// patch 追加上传数据，数据接收完整后将文件移动到目标路径
+func (h *Handler) patch(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Header.Get("Content-Type") != "application/offset+octet-stream" {
+		http.Error(w, "Invalid Content-Type", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		http.Error(w, "Invalid Upload-Offset header", http.StatusBadRequest)
+		return
+	}
+
+	unlock := h.store.Lock(id)
+	defer unlock()
+
+	upload, err := h.store.Get(id)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+	if time.Now().After(upload.ExpiresAt) {
+		h.writeError(w, ErrNotFound)
+		return
+	}
+
+	upload, err = h.store.Write(upload, offset, r.Body)
+	if err != nil {
+		logger.Error("Write upload %s error: %v", id, err)
+		h.writeError(w, err)
+		return
+	}
+
+	if upload.Completed() {
+		if err := h.finish(r.Context(), upload); err != nil {
+			logger.Error("Finish upload %s error: %v", id, err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	w.Header().Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// terminate 终止上传并删除已接收的数据
+func (h *Handler) terminate(w http.ResponseWriter, id string) {
+	unlock := h.store.Lock(id)
+	defer unlock()
+
+	if _, err := h.store.Get(id); err != nil {
+		h.writeError(w, err)
+		return
+	}
+	if err := h.store.Remove(id); err != nil {
+		h.writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// finish 将已完成的上传移动到目标路径并清理上传状态
+// 暂存目录是根目录下的保留目录，数据先由 store 重命名为目标旁的临时文件，再通过 fileService 重命名到目标，
+// 由 fileService 处理冲突策略、保存被覆盖文件的历史版本并发出变更事件
+func (h *Handler) finish(ctx context.Context, upload *Upload) error {
+	// 创建上传后目标路径途经的符号链接可能已经改变，完成时重新检查
+	target, err := h.pathProcessor.ProcessPath(upload.Target)
+	if err != nil {
+		return err
+	}
+
+	conflict := file.ConflictFail
+	if upload.Overwrite {
+		conflict = file.ConflictOverwrite
+	}
+
+	tmpPath, err := h.store.Place(upload.ID, target)
+	if err != nil {
+		return err
+	}
+	if _, err := h.fileService.Move(ctx, tmpPath, target, file.MoveOptions{Conflict: conflict}); err != nil {
+		// 保留已接收的数据，客户端可以重新发送最后的 PATCH 完成上传
+		if unplaceErr := h.store.Unplace(upload.ID, tmpPath); unplaceErr != nil {
+			logger.Error("Restore upload %s data error: %v", upload.ID, unplaceErr)
+		}
+		return err
+	}
+	return h.store.Remove(upload.ID)
+}
+
+// writeError 将存储层错误转换为 HTTP 状态码
+func (h *Handler) writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrOffsetMismatch):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// parseMetadata 解析 Upload-Metadata 头，格式为逗号分隔的 "key base64(value)" 键值对
+func parseMetadata(header string) (map[string]string, error) {
+	metadata := make(map[string]string)
+	if header == "" {
+		return metadata, nil
+	}
+
+	for _, pair := range strings.Split(header, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, encoded, _ := strings.Cut(pair, " ")
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Upload-Metadata value for key %s", key)
+		}
+		metadata[key] = string(value)
+	}
+	return metadata, nil
+}
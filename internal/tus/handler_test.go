@@ -0,0 +1,300 @@
+package tus
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"jia-file/internal/file"
+)
+
+// fakeService 只实现断点续传用到的 GetInfo 和 Move，调用其他方法会 panic
+type fakeService struct {
+	file.Service
+	moves [][2]string
+}
+
+func (f *fakeService) GetInfo(ctx context.Context, path string) (file.FileInfo, error) {
+	if _, err := os.Lstat(path); err != nil {
+		return file.FileInfo{}, err
+	}
+	return file.FileInfo{Path: path}, nil
+}
+
+func (f *fakeService) Move(ctx context.Context, src, dst string, opts file.MoveOptions) (file.FileInfo, error) {
+	f.moves = append(f.moves, [2]string{src, dst})
+	if _, err := os.Lstat(dst); err == nil && opts.Conflict != file.ConflictOverwrite {
+		return file.FileInfo{}, fmt.Errorf("file already exists: %s", dst)
+	}
+	if err := os.Rename(src, dst); err != nil {
+		return file.FileInfo{}, err
+	}
+	return file.FileInfo{Path: dst}, nil
+}
+
+type tusFixture struct {
+	root    string
+	store   *Store
+	service *fakeService
+	handler *Handler
+}
+
+func newTusFixture(t *testing.T, expiration time.Duration) *tusFixture {
+	t.Helper()
+	// 与服务启动时一致，分片暂存在根目录下的保留目录中
+	root := t.TempDir()
+	dir := filepath.Join(root, ".uploads")
+	store, err := NewStore(dir, expiration)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pathProcessor := file.NewPathProcessor(root)
+	pathProcessor.Reserve(dir)
+	service := &fakeService{}
+	return &tusFixture{
+		root:    root,
+		store:   store,
+		service: service,
+		handler: NewHandler("/uploads/", store, service, pathProcessor, 1<<20),
+	}
+}
+
+// rootEntries 返回根目录下除暂存目录之外的条目名称
+func (f *tusFixture) rootEntries() []string {
+	entries, _ := os.ReadDir(f.root)
+	var names []string
+	for _, entry := range entries {
+		if filepath.Join(f.root, entry.Name()) != f.store.Dir() {
+			names = append(names, entry.Name())
+		}
+	}
+	return names
+}
+
+// do 发送 tus 请求，headers 为键值交替的请求头
+func (f *tusFixture) do(method, path, body string, headers ...string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	r.Header.Set("Tus-Resumable", Version)
+	for i := 0; i+1 < len(headers); i += 2 {
+		r.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	f.handler.ServeHTTP(w, r)
+	return w
+}
+
+// create 创建上传并返回上传ID
+func (f *tusFixture) create(t *testing.T, target string, size int, extra string) string {
+	t.Helper()
+	metadata := "path " + b64(target)
+	if extra != "" {
+		metadata += "," + extra
+	}
+	w := f.do(http.MethodPost, "/uploads/", "", "Upload-Length", strconv.Itoa(size), "Upload-Metadata", metadata)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create status = %d, body = %s", w.Code, w.Body)
+	}
+	return strings.TrimPrefix(w.Header().Get("Location"), "/uploads/")
+}
+
+func (f *tusFixture) patch(id string, offset int, body string) *httptest.ResponseRecorder {
+	return f.do(http.MethodPatch, "/uploads/"+id, body,
+		"Content-Type", "application/offset+octet-stream", "Upload-Offset", strconv.Itoa(offset))
+}
+
+func (f *tusFixture) offset(t *testing.T, id string) string {
+	t.Helper()
+	w := f.do(http.MethodHead, "/uploads/"+id, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("HEAD status = %d", w.Code)
+	}
+	return w.Header().Get("Upload-Offset")
+}
+
+// stagedFiles 返回暂存目录中的文件数
+func (f *tusFixture) stagedFiles(t *testing.T) int {
+	t.Helper()
+	entries, err := os.ReadDir(f.store.Dir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(entries)
+}
+
+func b64(s string) string {
+	return base64.StdEncoding.EncodeToString([]byte(s))
+}
+
+func TestUpload(t *testing.T) {
+	f := newTusFixture(t, time.Hour)
+	target := filepath.Join(f.root, "dir", "a.txt")
+	id := f.create(t, target, 10, "")
+
+	if w := f.patch(id, 0, "hello"); w.Code != http.StatusNoContent || w.Header().Get("Upload-Offset") != "5" {
+		t.Fatalf("first PATCH status = %d, offset = %q", w.Code, w.Header().Get("Upload-Offset"))
+	}
+	if _, err := os.Stat(target); !os.IsNotExist(err) {
+		t.Fatalf("target exists before upload completed: %v", err)
+	}
+	// 未完成的上传不出现在根目录中
+	if entries := f.rootEntries(); len(entries) != 0 {
+		t.Errorf("root contains %v during upload", entries)
+	}
+
+	if w := f.patch(id, 5, "world"); w.Code != http.StatusNoContent {
+		t.Fatalf("second PATCH status = %d, body = %s", w.Code, w.Body)
+	}
+	if data, err := os.ReadFile(target); err != nil || string(data) != "helloworld" {
+		t.Fatalf("target = %q, %v", data, err)
+	}
+	if n := f.stagedFiles(t); n != 0 {
+		t.Errorf("staging directory has %d files after completion", n)
+	}
+	// 数据放到目标旁后在同一目录内移动，fileService 只接收根目录内的路径
+	if len(f.service.moves) != 1 || filepath.Dir(f.service.moves[0][0]) != filepath.Dir(target) || f.service.moves[0][1] != target {
+		t.Errorf("moves = %v", f.service.moves)
+	}
+	if w := f.do(http.MethodHead, "/uploads/"+id, ""); w.Code != http.StatusNotFound {
+		t.Errorf("HEAD after completion status = %d, want 404", w.Code)
+	}
+}
+
+func TestUploadOffsetMismatch(t *testing.T) {
+	f := newTusFixture(t, time.Hour)
+	id := f.create(t, filepath.Join(f.root, "a.txt"), 6, "")
+	f.patch(id, 0, "abc")
+
+	for _, offset := range []int{0, 2, 4} {
+		if w := f.patch(id, offset, "xyz"); w.Code != http.StatusConflict {
+			t.Errorf("PATCH at offset %d status = %d, want 409", offset, w.Code)
+		}
+	}
+	if got := f.offset(t, id); got != "3" {
+		t.Errorf("offset after mismatched PATCH = %s, want 3", got)
+	}
+	if w := f.patch(id, 3, "def"); w.Code != http.StatusNoContent {
+		t.Fatalf("PATCH status = %d", w.Code)
+	}
+	if data, _ := os.ReadFile(filepath.Join(f.root, "a.txt")); string(data) != "abcdef" {
+		t.Errorf("target = %q", data)
+	}
+}
+
+func TestUploadExpiration(t *testing.T) {
+	f := newTusFixture(t, -time.Second)
+	id := f.create(t, filepath.Join(f.root, "a.txt"), 6, "")
+
+	if w := f.patch(id, 0, "abc"); w.Code != http.StatusNotFound {
+		t.Errorf("PATCH to expired upload status = %d, want 404", w.Code)
+	}
+	removed, err := f.store.Cleanup()
+	if err != nil || removed != 1 {
+		t.Fatalf("Cleanup() = %d, %v; want 1", removed, err)
+	}
+	if n := f.stagedFiles(t); n != 0 {
+		t.Errorf("staging directory has %d files after cleanup", n)
+	}
+	if w := f.do(http.MethodHead, "/uploads/"+id, ""); w.Code != http.StatusNotFound {
+		t.Errorf("HEAD after cleanup status = %d, want 404", w.Code)
+	}
+}
+
+func TestUploadTermination(t *testing.T) {
+	f := newTusFixture(t, time.Hour)
+	id := f.create(t, filepath.Join(f.root, "a.txt"), 6, "")
+	f.patch(id, 0, "abc")
+
+	if w := f.do(http.MethodDelete, "/uploads/"+id, ""); w.Code != http.StatusNoContent {
+		t.Fatalf("DELETE status = %d", w.Code)
+	}
+	if n := f.stagedFiles(t); n != 0 {
+		t.Errorf("staging directory has %d files after termination", n)
+	}
+	if w := f.patch(id, 3, "def"); w.Code != http.StatusNotFound {
+		t.Errorf("PATCH after termination status = %d, want 404", w.Code)
+	}
+	if w := f.do(http.MethodDelete, "/uploads/"+id, ""); w.Code != http.StatusNotFound {
+		t.Errorf("second DELETE status = %d, want 404", w.Code)
+	}
+	if w := f.do(http.MethodDelete, "/uploads/../x", ""); w.Code != http.StatusNotFound {
+		t.Errorf("DELETE with invalid id status = %d, want 404", w.Code)
+	}
+}
+
+func TestUploadFinishConflict(t *testing.T) {
+	f := newTusFixture(t, time.Hour)
+	target := filepath.Join(f.root, "a.txt")
+	id := f.create(t, target, 3, "")
+
+	// 创建上传后目标被其他请求创建，完成时移动失败，已接收的数据保留
+	if err := os.WriteFile(target, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if w := f.patch(id, 0, "new"); w.Code != http.StatusInternalServerError {
+		t.Fatalf("PATCH status = %d, want 500", w.Code)
+	}
+	if got := f.offset(t, id); got != "3" {
+		t.Errorf("offset after failed finish = %s, want 3", got)
+	}
+	if entries := f.rootEntries(); len(entries) != 1 {
+		t.Errorf("root contains %v after failed finish, want only the target", entries)
+	}
+
+	// 删除冲突的文件后重新发送空的 PATCH 完成上传
+	os.Remove(target)
+	if w := f.patch(id, 3, ""); w.Code != http.StatusNoContent {
+		t.Fatalf("retried PATCH status = %d, body = %s", w.Code, w.Body)
+	}
+	if data, _ := os.ReadFile(target); string(data) != "new" {
+		t.Errorf("target = %q, want new", data)
+	}
+}
+
+func TestUploadCreate(t *testing.T) {
+	f := newTusFixture(t, time.Hour)
+	existing := filepath.Join(f.root, "exists.txt")
+	if err := os.WriteFile(existing, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		length   string
+		metadata string
+		want     int
+	}{
+		{"outside root", "1", "path " + b64("/etc/passwd"), http.StatusForbidden},
+		{"staging area", "1", "path " + b64(filepath.Join(f.store.Dir(), "x")), http.StatusForbidden},
+		{"relative path", "1", "path " + b64("a.txt"), http.StatusBadRequest},
+		{"missing target", "1", "", http.StatusBadRequest},
+		{"existing target", "1", "path " + b64(existing), http.StatusConflict},
+		{"too large", strconv.Itoa(2 << 20), "path " + b64(filepath.Join(f.root, "big")), http.StatusRequestEntityTooLarge},
+		{"invalid length", "-1", "path " + b64(filepath.Join(f.root, "b")), http.StatusBadRequest},
+		{"overwrite existing", "0", "path " + b64(existing) + ",overwrite " + b64("true"), http.StatusCreated},
+	}
+	for _, tt := range tests {
+		w := f.do(http.MethodPost, "/uploads/", "", "Upload-Length", tt.length, "Upload-Metadata", tt.metadata)
+		if w.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d (%s)", tt.name, w.Code, tt.want, w.Body)
+		}
+	}
+	// 长度为 0 的上传创建时直接完成
+	if data, err := os.ReadFile(existing); err != nil || len(data) != 0 {
+		t.Errorf("empty upload target = %q, %v", data, err)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/uploads/", nil)
+	w := httptest.NewRecorder()
+	f.handler.ServeHTTP(w, r)
+	if w.Code != http.StatusPreconditionFailed {
+		t.Errorf("request without Tus-Resumable status = %d, want 412", w.Code)
+	}
+}
@@ -0,0 +1,317 @@
+package tus
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"jia-file/internal/logger"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNotFound 上传不存在或已过期
+var ErrNotFound = errors.New("upload not found")
+
+// ErrOffsetMismatch 请求的偏移量与已接收的偏移量不一致
+var ErrOffsetMismatch = errors.New("upload offset mismatch")
+
+// Upload 断点续传上传状态
+type Upload struct {
+	ID        string            `json:"id"`        // 上传ID
+	Size      int64             `json:"size"`      // 文件总大小
+	Offset    int64             `json:"offset"`    // 已接收的字节数
+	Target    string            `json:"target"`    // 上传完成后的目标路径
+	Overwrite bool              `json:"overwrite"` // 目标已存在时是否覆盖
+	Metadata  map[string]string `json:"metadata"`  // Upload-Metadata 中携带的元数据
+	CreatedAt time.Time         `json:"createdAt"` // 创建时间
+	ExpiresAt time.Time         `json:"expiresAt"` // 过期时间
+}
+
+// Completed 判断上传是否已完成
+func (u *Upload) Completed() bool {
+	return u.Offset >= u.Size
+}
+
+// Store 基于磁盘的上传状态存储
+// 每个上传对应暂存目录下的两个文件：<id>.bin 保存已接收的数据，<id>.info 保存上传状态，
+// 因此服务重启后仍可继续上传
+type Store struct {
+	dir        string
+	expiration time.Duration
+
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+// NewStore 创建上传存储，dir 为暂存目录
+func NewStore(dir string, expiration time.Duration) (*Store, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create upload directory: %v", err)
+	}
+	return &Store{
+		dir:        dir,
+		expiration: expiration,
+		locks:      make(map[string]*sync.Mutex),
+	}, nil
+}
+
+// Dir 返回暂存目录
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// Lock 获取指定上传的互斥锁，防止同一上传被并发写入
+func (s *Store) Lock(id string) func() {
+	s.mu.Lock()
+	lock, ok := s.locks[id]
+	if !ok {
+		lock = &sync.Mutex{}
+		s.locks[id] = lock
+	}
+	s.mu.Unlock()
+
+	lock.Lock()
+	return lock.Unlock
+}
+
+// Create 创建新的上传
+func (s *Store) Create(size int64, target string, overwrite bool, metadata map[string]string) (*Upload, error) {
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	upload := &Upload{
+		ID:        id,
+		Size:      size,
+		Target:    target,
+		Overwrite: overwrite,
+		Metadata:  metadata,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.expiration),
+	}
+
+	data, err := os.OpenFile(s.dataPath(id), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := data.Close(); err != nil {
+		return nil, err
+	}
+
+	if err := s.save(upload); err != nil {
+		os.Remove(s.dataPath(id))
+		return nil, err
+	}
+	return upload, nil
+}
+
+// Get 读取上传状态
+// 如果数据文件因异常中断而短于记录的偏移量，以数据文件的实际长度为准
+func (s *Store) Get(id string) (*Upload, error) {
+	if !validID(id) {
+		return nil, ErrNotFound
+	}
+
+	content, err := os.ReadFile(s.infoPath(id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	var upload Upload
+	if err := json.Unmarshal(content, &upload); err != nil {
+		return nil, fmt.Errorf("corrupted upload info %s: %v", id, err)
+	}
+
+	stat, err := os.Stat(s.dataPath(id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if stat.Size() < upload.Offset {
+		upload.Offset = stat.Size()
+	}
+
+	return &upload, nil
+}
+
+// Write 从 offset 处追加数据，返回更新后的上传状态
+// 即使读取请求体中途出错，已写入的数据也会被保留并记录，客户端可从新的偏移量继续上传
+func (s *Store) Write(upload *Upload, offset int64, r io.Reader) (*Upload, error) {
+	if offset != upload.Offset {
+		return upload, ErrOffsetMismatch
+	}
+
+	data, err := os.OpenFile(s.dataPath(upload.ID), os.O_WRONLY, 0644)
+	if err != nil {
+		return upload, err
+	}
+	defer data.Close()
+
+	// 丢弃上次中断时可能残留的多余数据
+	if err := data.Truncate(offset); err != nil {
+		return upload, err
+	}
+	if _, err := data.Seek(offset, io.SeekStart); err != nil {
+		return upload, err
+	}
+
+	written, copyErr := io.Copy(data, io.LimitReader(r, upload.Size-offset))
+	if err := data.Sync(); err != nil && copyErr == nil {
+		copyErr = err
+	}
+
+	upload.Offset += written
+	upload.ExpiresAt = time.Now().Add(s.expiration)
+	if err := s.save(upload); err != nil {
+		return upload, err
+	}
+	return upload, copyErr
+}
+
+// Place 将已完成上传的数据重命名为 target 所在目录下的隐藏临时文件，返回临时文件路径
+// target 必须是已经过根目录检查的路径；暂存目录位于根目录下，与 target 在同一文件系统中，重命名是原子的
+func (s *Store) Place(id, target string) (string, error) {
+	dir := filepath.Dir(target)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create parent directory: %v", err)
+	}
+	tmpPath := filepath.Join(dir, "."+filepath.Base(target)+".upload-"+id)
+	if err := os.Rename(s.dataPath(id), tmpPath); err != nil {
+		return "", err
+	}
+	return tmpPath, nil
+}
+
+// Unplace 撤销 Place，将数据移回暂存目录，使上传可以重新完成
+func (s *Store) Unplace(id, tmpPath string) error {
+	return os.Rename(tmpPath, s.dataPath(id))
+}
+
+// Remove 删除上传的数据和状态文件
+func (s *Store) Remove(id string) error {
+	if !validID(id) {
+		return ErrNotFound
+	}
+	if err := os.Remove(s.infoPath(id)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Remove(s.dataPath(id)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	s.mu.Lock()
+	delete(s.locks, id)
+	s.mu.Unlock()
+	return nil
+}
+
+// Cleanup 删除已过期的未完成上传，返回删除的数量
+func (s *Store) Cleanup() (int, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	removed := 0
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".info")
+		if !ok {
+			continue
+		}
+
+		unlock := s.Lock(id)
+		upload, err := s.Get(id)
+		if err == ErrNotFound {
+			// 只剩状态文件或数据文件时直接清理
+			err = s.Remove(id)
+			removed++
+		} else if err == nil && now.After(upload.ExpiresAt) {
+			err = s.Remove(id)
+			removed++
+		}
+		unlock()
+
+		if err != nil {
+			logger.Error("Cleanup upload %s error: %v", id, err)
+		}
+	}
+	return removed, nil
+}
+
+// StartCleanup 按固定间隔在后台清理过期上传，关闭 stop 通道时停止
+func (s *Store) StartCleanup(interval time.Duration, stop <-chan struct{}) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if removed, err := s.Cleanup(); err != nil {
+					logger.Error("Cleanup uploads error: %v", err)
+				} else if removed > 0 {
+					logger.Info("Cleaned up %d expired uploads", removed)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// save 原子地写入上传状态文件
+func (s *Store) save(upload *Upload) error {
+	content, err := json.Marshal(upload)
+	if err != nil {
+		return err
+	}
+
+	tmpPath := s.infoPath(upload.ID) + ".tmp"
+	if err := os.WriteFile(tmpPath, content, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, s.infoPath(upload.ID))
+}
+
+func (s *Store) dataPath(id string) string {
+	return filepath.Join(s.dir, id+".bin")
+}
+
+func (s *Store) infoPath(id string) string {
+	return filepath.Join(s.dir, id+".info")
+}
+
+// newID 生成随机上传ID
+func newID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// validID 检查上传ID格式，防止通过ID访问暂存目录之外的文件
+func validID(id string) bool {
+	if len(id) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}